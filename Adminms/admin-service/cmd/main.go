@@ -4,43 +4,55 @@ package main
 import (
 	"log"
 	"net"
-	"os"
+	"net/http"
+	"time"
 
+	"github.com/BouchamiAhmed/TBD/shared"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
+	"admin-service/internal/config"
 	"admin-service/internal/database"
+	"admin-service/internal/gateway"
 	"admin-service/internal/k8s"
 	"admin-service/internal/server"
 	pb "admin-service/pkg/pb"
 )
 
+// dbReconnectInterval is how often the service retries the database when it
+// was unavailable at startup
+const dbReconnectInterval = 15 * time.Second
+
 func main() {
 	log.Println("🚀 Starting Admin gRPC Service...")
 
-	// Initialize Database connection
-	var dbClient *database.DBClient
-	// Get PostgreSQL connection details from environment or use cluster defaults
-	dbHost := os.Getenv("POSTGRES_HOST")
-	if dbHost == "" {
-		dbHost = "10.9.21.201" // Updated PostgreSQL cluster IP
-		log.Printf("Using default PostgreSQL host: %s", dbHost)
+	// Load configuration and feature flags from the environment
+	cfg := config.Load()
+	log.Printf("🚩 Feature flags: %+v", cfg.Features)
+	if !server.ValidAuthMode(cfg.AuthMode) {
+		log.Fatalf("❌ Invalid AUTH_MODE %q (expected %q or %q)", cfg.AuthMode, server.AuthModeMock, server.AuthModeReal)
 	}
-
-	dbUsername := os.Getenv("DB_USERNAME")
-	if dbUsername == "" {
-		dbUsername = "postgres" // Default
+	if err := cfg.ValidateNamespaceMetadata(); err != nil {
+		log.Fatalf("❌ Invalid namespace metadata configuration: %v", err)
 	}
-
-	dbPassword := os.Getenv("DB_PASSWORD")
-	if dbPassword == "" {
-		dbPassword = "postgres" // Default
+	if !shared.ValidTraefikMatcherVersion(cfg.TraefikMatcherVersion) {
+		log.Printf("⚠️  Unknown TRAEFIK_MATCHER_VERSION %q, using %s", cfg.TraefikMatcherVersion, shared.TraefikMatcherV2)
+		cfg.TraefikMatcherVersion = shared.TraefikMatcherV2
+	}
+	if err := (shared.PgAdminRouting{Mode: cfg.PgAdminRouting, HostDomain: cfg.PgAdminHostDomain}).Validate(); err != nil {
+		log.Printf("⚠️  %v", err)
 	}
 
+	// Initialize Database connection
+	var dbClient *database.DBClient
+	dbHost := cfg.PostgresHost
+	dbUsername := cfg.DBUsername
+	dbPassword := cfg.DBPassword
+
 	log.Printf("Attempting to connect to database at: %s", dbHost)
 
 	var err error
-	dbClient, err = database.NewDBClient(dbHost, dbUsername, dbPassword)
+	dbClient, err = database.NewDBClient(dbHost, dbUsername, dbPassword, cfg.DBPasswordFile, cfg.DBPool, cfg.BcryptCost)
 	if err != nil {
 		log.Printf("⚠️  Warning: Could not connect to database: %v", err)
 		log.Println("Authentication will not be available")
@@ -48,37 +60,57 @@ func main() {
 	} else {
 		log.Println("✅ Successfully connected to database")
 
-		// Initialize tables
-		if err := dbClient.CreateTablesIfNotExist(); err != nil {
-			log.Printf("⚠️  Warning: Could not initialize database tables: %v", err)
+		// Bring the shared schema up to date before anything queries it
+		if err := dbClient.Migrate(); err != nil {
+			log.Printf("⚠️  Warning: Could not migrate database schema: %v", err)
 		}
 	}
 
 	// Initialize Kubernetes service
-	k8sService, err := k8s.NewK8sService()
+	k8sService, err := k8s.NewK8sService(cfg)
 	if err != nil {
 		log.Printf("⚠️  Warning: Could not connect to Kubernetes: %v", err)
 		log.Println("Database creation will not be available")
 		k8sService = nil
 	} else {
 		log.Println("✅ Successfully connected to Kubernetes cluster")
+
+		// Serve namespace listings from a watch-backed cache instead of polling the API server
+		if err := k8sService.StartCache(make(chan struct{})); err != nil {
+			log.Printf("⚠️  Warning: Could not start resource cache: %v", err)
+			log.Println("Namespace listings will query the Kubernetes API directly")
+		} else {
+			log.Println("✅ Resource cache synced")
+		}
 	}
 
-	// Create gRPC server
-	grpcServer := grpc.NewServer()
+	// Create admin server with both services
+	adminServer := server.NewAdminServer(k8sService, dbClient, cfg)
 
-	// Create and register admin server with both services
-	adminServer := server.NewAdminServer(k8sService, dbClient)
+	// Create gRPC server, logging each call (payloads only when enabled, with
+	// secrets redacted) and requiring a session token for all but Login and Register
+	interceptor := server.ChainInterceptors(server.LoggingInterceptor(cfg.LogPayloads), adminServer.AuthInterceptor())
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(interceptor))
 	pb.RegisterAdminServiceServer(grpcServer, adminServer)
+	switch {
+	case cfg.AuthMode == server.AuthModeMock:
+		log.Println("🚨🚨🚨 AUTH_MODE=mock: Login and Register accept ANY credentials and issue fake tokens. This is INSECURE - never run it in production 🚨🚨🚨")
+	case dbClient == nil:
+		log.Println("🔐 Authentication mode: real; every call fails as unavailable until the database connects")
+	default:
+		log.Println("🔐 Authentication mode: real (users are checked against the database)")
+	}
+
+	// Keep retrying the database in the background so auth self-heals once it comes up
+	if dbClient == nil {
+		go reconnectDatabase(cfg, adminServer)
+	}
 
 	// Enable reflection for development (so we can test with grpcui)
 	reflection.Register(grpcServer)
 
 	// Start listening
-	port := os.Getenv("GRPC_PORT")
-	if port == "" {
-		port = "50051"
-	}
+	port := cfg.GRPCPort
 
 	lis, err := net.Listen("tcp", ":"+port)
 	if err != nil {
@@ -88,9 +120,14 @@ func main() {
 	log.Printf("✅ Admin gRPC Service running on :%s", port)
 	log.Printf("🔧 You can test it with: grpcui -plaintext localhost:%s", port)
 
+	// Expose the same methods as HTTP/JSON for browsers when enabled
+	if cfg.GatewayPort != "" && cfg.GatewayPort != "off" {
+		go serveGateway(cfg, adminServer, interceptor)
+	}
+
 	// Graceful shutdown handling
 	defer func() {
-		if dbClient != nil {
+		if dbClient := adminServer.DBClient(); dbClient != nil {
 			dbClient.Close()
 		}
 	}()
@@ -99,3 +136,42 @@ func main() {
 		log.Fatalf("Failed to serve: %v", err)
 	}
 }
+
+// serveGateway serves the HTTP/JSON gateway on GATEWAY_PORT
+func serveGateway(cfg *config.Config, adminServer *server.AdminServer, interceptor grpc.UnaryServerInterceptor) {
+	httpServer := &http.Server{
+		Addr:              ":" + cfg.GatewayPort,
+		Handler:           gateway.New(adminServer, interceptor, cfg.GatewayCORSOrigins),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Printf("✅ HTTP/JSON gateway running on :%s", cfg.GatewayPort)
+	if err := httpServer.ListenAndServe(); err != nil {
+		log.Printf("⚠️  HTTP/JSON gateway stopped: %v", err)
+	}
+}
+
+// reconnectDatabase periodically retries the database connection until it
+// succeeds, then migrates the schema and hands the client to the server
+func reconnectDatabase(cfg *config.Config, adminServer *server.AdminServer) {
+	ticker := time.NewTicker(dbReconnectInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		log.Printf("🔄 Retrying database connection to %s...", cfg.PostgresHost)
+
+		dbClient, err := database.NewDBClient(cfg.PostgresHost, cfg.DBUsername, cfg.DBPassword, cfg.DBPasswordFile, cfg.DBPool, cfg.BcryptCost)
+		if err != nil {
+			log.Printf("⚠️  Database still unavailable: %v", err)
+			continue
+		}
+
+		if err := dbClient.Migrate(); err != nil {
+			log.Printf("⚠️  Warning: Could not migrate database schema: %v", err)
+		}
+
+		adminServer.SetDBClient(dbClient)
+		log.Println("✅ Reconnected to database, authentication is now available")
+		return
+	}
+}
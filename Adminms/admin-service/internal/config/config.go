@@ -0,0 +1,193 @@
+// internal/config/config.go - Environment-driven configuration for admin service
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Features holds the feature flags read from the environment
+type Features struct {
+	AdminDashboards bool // ENABLE_ADMIN_DASHBOARDS (pgAdmin and phpMyAdmin)
+}
+
+// DBPool holds the connection pool settings for the control database
+type DBPool struct {
+	MaxOpenConns    int           // DB_MAX_OPEN_CONNS
+	MaxIdleConns    int           // DB_MAX_IDLE_CONNS
+	ConnMaxLifetime time.Duration // DB_CONN_MAX_LIFETIME
+	ConnMaxIdleTime time.Duration // DB_CONN_MAX_IDLE_TIME
+}
+
+// Config holds the admin service configuration, read once at startup
+type Config struct {
+	PostgresHost          string            // POSTGRES_HOST
+	DBUsername            string            // DB_USERNAME
+	DBPassword            string            // DB_PASSWORD
+	DBPasswordFile        string            // DB_PASSWORD_FILE (takes precedence over DB_PASSWORD)
+	GRPCPort              string            // GRPC_PORT
+	GatewayPort           string            // GATEWAY_PORT (HTTP/JSON gateway; off unless set, "off" also disables it)
+	GatewayCORSOrigins    []string          // GATEWAY_CORS_ORIGINS (no origins unless set; "*" allows any)
+	Kubeconfig            string            // KUBECONFIG
+	KubernetesServiceHost string            // KUBERNETES_SERVICE_HOST
+	NamespacePrefix       string            // NAMESPACE_PREFIX (e.g. "tenant-a-")
+	NamespaceLabels       map[string]string // NAMESPACE_LABELS
+	NamespaceAnnotations  map[string]string // NAMESPACE_ANNOTATIONS
+	PublicHost            string            // PUBLIC_HOST (the host users reach the cluster's ingress on)
+	TraefikMatcherVersion string            // TRAEFIK_MATCHER_VERSION ("v2" or "v3")
+	TraefikNamespace      string            // TRAEFIK_NAMESPACE (IngressRoutes and Middlewares go here; the database's namespace when empty)
+	PgAdminRouting        string            // PGADMIN_ROUTING ("path-prefix" or "host")
+	PgAdminHostDomain     string            // PGADMIN_HOST_DOMAIN
+	PgAdminEmailDomain    string            // PGADMIN_EMAIL_DOMAIN
+	BcryptCost            int               // BCRYPT_COST (clamped to bcrypt's MinCost..MaxCost)
+	LogPayloads           bool              // GRPC_LOG_PAYLOADS (sensitive fields are redacted)
+	AuthMode              string            // AUTH_MODE ("mock" or "real"; unset means real)
+	TokenTTL              time.Duration     // TOKEN_TTL (how long a session issued at login lasts)
+	AdminUsernames        []string          // ADMIN_USERNAMES (users who may list every namespace)
+	DBPool                DBPool
+	Features              Features
+}
+
+// Load reads the configuration from environment variables, applying defaults
+func Load() *Config {
+	return &Config{
+		PostgresHost:          getEnv("POSTGRES_HOST", "10.9.21.201"),
+		DBUsername:            getEnv("DB_USERNAME", "postgres"),
+		DBPassword:            getEnv("DB_PASSWORD", "postgres"),
+		DBPasswordFile:        os.Getenv("DB_PASSWORD_FILE"),
+		GRPCPort:              getEnv("GRPC_PORT", "50051"),
+		GatewayPort:           os.Getenv("GATEWAY_PORT"),
+		GatewayCORSOrigins:    getEnvList("GATEWAY_CORS_ORIGINS", nil),
+		Kubeconfig:            os.Getenv("KUBECONFIG"),
+		KubernetesServiceHost: os.Getenv("KUBERNETES_SERVICE_HOST"),
+		NamespacePrefix:       strings.ToLower(os.Getenv("NAMESPACE_PREFIX")),
+		NamespaceLabels:       getEnvMap("NAMESPACE_LABELS"),
+		NamespaceAnnotations:  getEnvMap("NAMESPACE_ANNOTATIONS"),
+		PublicHost:            getEnv("PUBLIC_HOST", "10.9.21.201"),
+		TraefikMatcherVersion: getEnv("TRAEFIK_MATCHER_VERSION", "v2"),
+		TraefikNamespace:      os.Getenv("TRAEFIK_NAMESPACE"),
+		PgAdminRouting:        getEnv("PGADMIN_ROUTING", "path-prefix"),
+		PgAdminHostDomain:     os.Getenv("PGADMIN_HOST_DOMAIN"),
+		PgAdminEmailDomain:    getEnv("PGADMIN_EMAIL_DOMAIN", "example.com"),
+		BcryptCost:            getBcryptCost("BCRYPT_COST"),
+		LogPayloads:           getEnvBool("GRPC_LOG_PAYLOADS", false),
+		AuthMode:              strings.ToLower(strings.TrimSpace(os.Getenv("AUTH_MODE"))),
+		TokenTTL:              getEnvDuration("TOKEN_TTL", 24*time.Hour),
+		AdminUsernames:        getEnvList("ADMIN_USERNAMES", nil),
+		DBPool: DBPool{
+			MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 5),
+			ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+			ConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", time.Minute),
+		},
+		Features: Features{
+			AdminDashboards: getEnvBool("ENABLE_ADMIN_DASHBOARDS", true),
+		},
+	}
+}
+
+// ValidateNamespaceMetadata checks NAMESPACE_LABELS and NAMESPACE_ANNOTATIONS,
+// so a bad entry stops startup instead of failing every namespace creation
+func (c *Config) ValidateNamespaceMetadata() error {
+	for key, value := range c.NamespaceLabels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid NAMESPACE_LABELS key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("invalid NAMESPACE_LABELS value %q for %q: %s", value, key, strings.Join(errs, "; "))
+		}
+	}
+	for key := range c.NamespaceAnnotations {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid NAMESPACE_ANNOTATIONS key %q: %s", key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// getEnv returns the value of an environment variable or a default
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvBool parses a boolean environment variable, falling back to a default
+// when it is unset or invalid
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvInt parses an integer environment variable, falling back to a default
+// when it is unset or invalid
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvDuration parses a duration environment variable (e.g. "30s", "5m"),
+// falling back to a default when it is unset or invalid
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvMap parses a comma-separated list of key=value pairs
+// (e.g. "team=data,cost-center=42"), skipping malformed entries
+func getEnvMap(key string) map[string]string {
+	result := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			continue
+		}
+		result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return result
+}
+
+// getEnvList parses a comma-separated list, skipping empty entries and
+// falling back to a default when it is unset or empty
+func getEnvList(key string, fallback []string) []string {
+	var result []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	if len(result) == 0 {
+		return fallback
+	}
+	return result
+}
+
+// getBcryptCost reads the bcrypt cost, clamped to the range bcrypt accepts.
+// Changing it does not re-hash existing passwords; each is re-hashed at the
+// new cost the next time its user logs in.
+func getBcryptCost(key string) int {
+	cost := getEnvInt(key, bcrypt.DefaultCost)
+	if cost < bcrypt.MinCost {
+		return bcrypt.MinCost
+	}
+	if cost > bcrypt.MaxCost {
+		return bcrypt.MaxCost
+	}
+	return cost
+}
@@ -2,13 +2,20 @@
 package database
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/BouchamiAhmed/TBD/shared"
 	_ "github.com/lib/pq" // PostgreSQL driver
 	"golang.org/x/crypto/bcrypt"
+
+	"admin-service/internal/config"
 )
 
 // Database connection parameters
@@ -19,20 +26,32 @@ const (
 
 // DBClient represents a PostgreSQL database client
 type DBClient struct {
-	db *sql.DB
+	db         *sql.DB
+	bcryptCost int // cost used when hashing passwords
 }
 
-// NewDBClient creates a new database client with configurable host
-func NewDBClient(host, username, password string) (*DBClient, error) {
+// NewDBClient creates a new database client with configurable host. The
+// connection password is read from passwordFile when one is given, otherwise
+// password is used. User passwords are hashed with bcryptCost.
+func NewDBClient(host, username, password, passwordFile string, pool config.DBPool, bcryptCost int) (*DBClient, error) {
 	fmt.Println("╔════════════════════════════════════════════════════════════╗")
 	fmt.Println("║                Admin Service Database Connection           ║")
 	fmt.Println("╚════════════════════════════════════════════════════════════╝")
 
 	fmt.Printf("⏳ Attempting to connect to PostgreSQL on %s:%d...\n", host, port)
 
-	// Connection string
-	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		host, port, username, password, dbname)
+	password, err := shared.ResolvePassword(password, passwordFile)
+	if err != nil {
+		fmt.Println("❌ Could not read the database password")
+		return nil, err
+	}
+
+	// Connection string (values are quoted so special characters in credentials are safe)
+	psqlInfo, err := shared.PostgresDSN(host, port, username, password, dbname)
+	if err != nil {
+		fmt.Println("❌ Invalid database connection parameters")
+		return nil, err
+	}
 
 	// Open doesn't actually connect, it just validates the args
 	fmt.Println("🔄 Initializing database driver...")
@@ -44,9 +63,10 @@ func NewDBClient(host, username, password string) (*DBClient, error) {
 
 	// Set connection pool settings
 	fmt.Println("🔄 Configuring connection pool...")
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 
 	// Verify connection works
 	fmt.Println("🔄 Testing connection to PostgreSQL...")
@@ -57,7 +77,7 @@ func NewDBClient(host, username, password string) (*DBClient, error) {
 
 	fmt.Println("✅ Successfully connected to PostgreSQL database!")
 	log.Println("Successfully connected to PostgreSQL database")
-	return &DBClient{db: db}, nil
+	return &DBClient{db: db, bcryptCost: bcryptCost}, nil
 }
 
 // Close closes the database connection
@@ -66,65 +86,13 @@ func (c *DBClient) Close() error {
 	return c.db.Close()
 }
 
-// CreateTablesIfNotExist creates necessary tables if they don't exist
-func (c *DBClient) CreateTablesIfNotExist() error {
-	fmt.Println("╔════════════════════════════════════════════════════════════╗")
-	fmt.Println("║                Table Initialization                        ║")
-	fmt.Println("╚════════════════════════════════════════════════════════════╝")
-
-	fmt.Println("🔄 Creating users table if it doesn't exist...")
-
-	// Create users table with authentication fields
-	usersQuery := `
-	CREATE TABLE IF NOT EXISTS users (
-		id SERIAL PRIMARY KEY,
-		username VARCHAR(100) UNIQUE NOT NULL,
-		email VARCHAR(255) UNIQUE NOT NULL,
-		password_hash VARCHAR(255) NOT NULL,
-		first_name VARCHAR(100) NOT NULL,
-		last_name VARCHAR(100) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	)`
-
-	_, err := c.db.Exec(usersQuery)
-	if err != nil {
-		fmt.Println("❌ Failed to create users table")
-		return fmt.Errorf("error creating users table: %w", err)
-	}
-
-	fmt.Println("🔄 Creating databases table if it doesn't exist...")
-
-	// Create databases table to track created databases
-	databasesQuery := `
-	CREATE TABLE IF NOT EXISTS databases (
-		id SERIAL PRIMARY KEY,
-		name VARCHAR(100) NOT NULL,
-		type VARCHAR(50) NOT NULL,
-		host VARCHAR(255) NOT NULL,
-		port VARCHAR(10) NOT NULL,
-		username VARCHAR(100) NOT NULL,
-		namespace VARCHAR(100) NOT NULL,
-		user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
-		admin_url VARCHAR(500),
-		admin_type VARCHAR(50),
-		status VARCHAR(50) DEFAULT 'creating',
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	)`
-
-	_, err = c.db.Exec(databasesQuery)
-	if err != nil {
-		fmt.Println("❌ Failed to create databases table")
-		return fmt.Errorf("error creating databases table: %w", err)
-	}
-
-	fmt.Println("✅ Database tables initialized successfully!")
-	log.Println("Database tables initialized")
-	return nil
+// Migrate brings the shared control database schema up to date with the
+// migrations the API server runs too, so both services agree on every table
+func (c *DBClient) Migrate() error {
+	return shared.Migrate(c.db)
 }
 
-// User represents a user in the database
+// User represents a user in the auth_users table shared with the API server
 type User struct {
 	ID           int       `json:"id"`
 	Username     string    `json:"username"`
@@ -153,18 +121,31 @@ type Database struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+// Database statuses. A deploy moves through provisioning (objects created),
+// pulling (image pull in progress or backing off) and starting (pod running,
+// not ready) to running, or ends in failed; creating is the legacy initial status.
+// The API server keeps recorded statuses in step with the database pods.
+const (
+	StatusCreating     = "creating"
+	StatusProvisioning = "provisioning"
+	StatusPulling      = "pulling"
+	StatusStarting     = "starting"
+	StatusRunning      = "running"
+	StatusFailed       = "failed"
+)
+
 // CreateUser adds a new user to the database
 func (c *DBClient) CreateUser(username, email, password, firstName, lastName string) (*User, error) {
 	fmt.Printf("🔄 Creating new user: %s (%s)...\n", username, email)
 
 	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), c.bcryptCost)
 	if err != nil {
 		return nil, fmt.Errorf("error hashing password: %w", err)
 	}
 
 	query := `
-	INSERT INTO users (username, email, password_hash, first_name, last_name)
+	INSERT INTO auth_users (username, email, password_hash, first_name, last_name)
 	VALUES ($1, $2, $3, $4, $5)
 	RETURNING id, username, email, password_hash, first_name, last_name, created_at, updated_at`
 
@@ -195,7 +176,7 @@ func (c *DBClient) AuthenticateUser(username, password string) (*User, error) {
 
 	query := `
 	SELECT id, username, email, password_hash, first_name, last_name, created_at, updated_at
-	FROM users
+	FROM auth_users
 	WHERE username = $1`
 
 	var user User
@@ -220,23 +201,97 @@ func (c *DBClient) AuthenticateUser(username, password string) (*User, error) {
 	}
 
 	// Check password
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
-	if err != nil {
+	ok, legacy := checkPassword(user.PasswordHash, password)
+	if !ok {
 		fmt.Printf("❌ Invalid password for user: %s\n", username)
 		return nil, fmt.Errorf("invalid credentials")
 	}
 
+	// Legacy hashes and passwords hashed under a previous BCRYPT_COST are upgraded on login
+	if cost, err := bcrypt.Cost([]byte(user.PasswordHash)); legacy || (err == nil && cost != c.bcryptCost) {
+		c.rehashPassword(&user, password)
+	}
+
 	fmt.Printf("✅ User authenticated successfully: %s\n", username)
 	return &user, nil
 }
 
+// checkPassword reports whether password matches a stored hash. The API
+// server stored unsalted SHA-256 hex digests before it adopted bcrypt; for
+// those, legacy is true so the caller can rehash them.
+func checkPassword(hash, password string) (ok, legacy bool) {
+	if strings.HasPrefix(hash, "$2") {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil, false
+	}
+
+	sum := sha256.Sum256([]byte(password))
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(hex.EncodeToString(sum[:]))) == 1, true
+}
+
+// rehashPassword stores the password hashed at the configured cost. Failures
+// are logged only, since the login itself has already succeeded.
+func (c *DBClient) rehashPassword(user *User, password string) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), c.bcryptCost)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to re-hash password for user %s: %v\n", user.Username, err)
+		return
+	}
+
+	query := `UPDATE auth_users SET password_hash = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	if _, err := c.db.Exec(query, string(hashedPassword), user.ID); err != nil {
+		fmt.Printf("⚠️  Failed to store re-hashed password for user %s: %v\n", user.Username, err)
+		return
+	}
+
+	user.PasswordHash = string(hashedPassword)
+	fmt.Printf("🔐 Re-hashed password for user %s at cost %d\n", user.Username, c.bcryptCost)
+}
+
+// hashToken returns the SHA-256 hex digest under which the sessions table,
+// shared with the API server, stores a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateSession records a token issued to userID, valid for ttl
+func (c *DBClient) CreateSession(token string, userID int, ttl time.Duration) error {
+	query := `
+	INSERT INTO sessions (user_id, token_hash, issued_at, expires_at, last_seen_at)
+	VALUES ($1, $2, $3, $4, $3)`
+
+	issuedAt := time.Now()
+	if _, err := c.db.Exec(query, userID, hashToken(token), issuedAt, issuedAt.Add(ttl)); err != nil {
+		return fmt.Errorf("error creating session: %w", err)
+	}
+	return nil
+}
+
+// SessionUserID returns the ID of the user an unexpired session was issued
+// to for token, or 0 when there is none
+func (c *DBClient) SessionUserID(token string) (int, error) {
+	query := `
+	SELECT user_id FROM sessions
+	WHERE token_hash = $1 AND expires_at > CURRENT_TIMESTAMP`
+
+	var userID int
+	err := c.db.QueryRow(query, hashToken(token)).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error checking session: %w", err)
+	}
+	return userID, nil
+}
+
 // GetUserByID retrieves a specific user by ID
 func (c *DBClient) GetUserByID(id int) (*User, error) {
 	fmt.Printf("🔄 Looking up user with ID: %d...\n", id)
 
 	query := `
 	SELECT id, username, email, password_hash, first_name, last_name, created_at, updated_at
-	FROM users
+	FROM auth_users
 	WHERE id = $1`
 
 	var user User
@@ -336,28 +391,15 @@ func (c *DBClient) GetUserDatabases(userID int) ([]Database, error) {
 	return databases, nil
 }
 
-// UpdateDatabaseStatus updates the status of a database
-func (c *DBClient) UpdateDatabaseStatus(name, namespace, status string) error {
-	fmt.Printf("🔄 Updating database status: %s -> %s...\n", name, status)
-
-	query := `
-	UPDATE databases 
-	SET status = $1, updated_at = CURRENT_TIMESTAMP
-	WHERE name = $2 AND namespace = $3`
-
-	result, err := c.db.Exec(query, status, name, namespace)
-	if err != nil {
-		fmt.Println("❌ Failed to update database status")
-		return fmt.Errorf("error updating database status: %w", err)
-	}
+// UserOwnsNamespace reports whether userID has a database recorded in namespace
+func (c *DBClient) UserOwnsNamespace(userID int, namespace string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM databases WHERE user_id = $1 AND namespace = $2)`
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("no database found with name %s in namespace %s", name, namespace)
+	var owns bool
+	if err := c.db.QueryRow(query, userID, namespace).Scan(&owns); err != nil {
+		return false, fmt.Errorf("error checking namespace owner: %w", err)
 	}
-
-	fmt.Printf("✅ Database status updated successfully\n")
-	return nil
+	return owns, nil
 }
 
 // DeleteDatabase removes a database record
@@ -0,0 +1,195 @@
+// internal/gateway/gateway.go - HTTP/JSON gateway in front of the gRPC service
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "admin-service/pkg/pb"
+)
+
+// serviceName is the fully-qualified gRPC service, used to build the method
+// names reported to the interceptor
+const serviceName = "/admin.v1.AdminService/"
+
+// maxBodyBytes bounds request bodies; longer bodies are truncated and fail to decode
+const maxBodyBytes = 1 << 20
+
+// New returns an HTTP handler exposing the AdminService methods as JSON:
+//
+//	POST   /v1/auth/login                               Login
+//	POST   /v1/auth/register                            Register
+//	POST   /v1/databases                                CreateDatabase
+//	GET    /v1/namespaces                               GetAllNamespaces
+//	GET    /v1/namespaces/{namespace}/databases         GetUserDatabases
+//	DELETE /v1/namespaces/{namespace}/databases/{name}  DeleteDatabase
+//
+// Calls go straight to srv through the same interceptor as gRPC calls, so
+// they are logged and authenticated identically; the Authorization header is
+// passed on as "authorization" metadata. allowedOrigins configures CORS
+// ("*" allows any, none by default).
+func New(srv pb.AdminServiceServer, interceptor grpc.UnaryServerInterceptor, allowedOrigins []string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle("POST /v1/auth/login", unary(interceptor, "Login", srv.Login, decodeBody[pb.LoginRequest]))
+	mux.Handle("POST /v1/auth/register", unary(interceptor, "Register", srv.Register, decodeBody[pb.RegisterRequest]))
+	mux.Handle("POST /v1/databases", unary(interceptor, "CreateDatabase", srv.CreateDatabase, decodeBody[pb.CreateDatabaseRequest]))
+	mux.Handle("GET /v1/namespaces", unary(interceptor, "GetAllNamespaces", srv.GetAllNamespaces,
+		func(*http.Request) (*pb.GetAllNamespacesRequest, error) {
+			return &pb.GetAllNamespacesRequest{}, nil
+		}))
+	mux.Handle("GET /v1/namespaces/{namespace}/databases", unary(interceptor, "GetUserDatabases", srv.GetUserDatabases,
+		func(r *http.Request) (*pb.GetUserDatabasesRequest, error) {
+			return &pb.GetUserDatabasesRequest{Namespace: r.PathValue("namespace")}, nil
+		}))
+	mux.Handle("DELETE /v1/namespaces/{namespace}/databases/{name}", unary(interceptor, "DeleteDatabase", srv.DeleteDatabase,
+		func(r *http.Request) (*pb.DeleteDatabaseRequest, error) {
+			return &pb.DeleteDatabaseRequest{Namespace: r.PathValue("namespace"), Name: r.PathValue("name")}, nil
+		}))
+
+	return withCORS(mux, allowedOrigins)
+}
+
+// unary adapts one gRPC method to an HTTP handler: bind builds the request
+// message, the call runs through the interceptor, and the response or error
+// is written as JSON
+func unary[Req, Resp any](
+	interceptor grpc.UnaryServerInterceptor,
+	method string,
+	call func(context.Context, *Req) (*Resp, error),
+	bind func(*http.Request) (*Req, error),
+) http.Handler {
+	info := &grpc.UnaryServerInfo{FullMethod: serviceName + method}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return call(ctx, req.(*Req))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := bind(r)
+		if err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, "invalid request body: "+err.Error()))
+			return
+		}
+
+		ctx := r.Context()
+		if authorization := r.Header.Get("Authorization"); authorization != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", authorization))
+		}
+
+		resp, err := interceptor(ctx, req, info, handler)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeMessage(w, http.StatusOK, resp)
+	})
+}
+
+// decodeBody decodes a JSON request body into a new message, using the
+// protobuf JSON mapping for generated messages (camelCase or original field
+// names, unknown fields ignored)
+func decodeBody[Req any](r *http.Request) (*Req, error) {
+	req := new(Req)
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	if msg, ok := any(req).(proto.Message); ok {
+		return req, protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, msg)
+	}
+	return req, json.Unmarshal(data, req)
+}
+
+// writeMessage writes a response message as JSON, using the protobuf JSON
+// mapping (camelCase names, RFC 3339 timestamps) for generated messages
+func writeMessage(w http.ResponseWriter, code int, resp interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if msg, ok := resp.(proto.Message); ok {
+		data, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(msg)
+		if err != nil {
+			writeError(w, status.Error(codes.Internal, "failed to encode response"))
+			return
+		}
+		w.WriteHeader(code)
+		w.Write(data)
+		return
+	}
+
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Error encoding gateway response: %v", err)
+	}
+}
+
+// writeError writes a gRPC error as {"error", "code"} with the matching HTTP status
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(st.Code()))
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": st.Message(),
+		"code":  st.Code().String(),
+	})
+}
+
+// httpStatus maps a gRPC status code to the closest HTTP status
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// withCORS lets browsers on the allowed origins call the gateway, answering
+// preflight requests directly
+func withCORS(next http.Handler, allowedOrigins []string) http.Handler {
+	allowAny := slices.Contains(allowedOrigins, "*")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && (allowAny || slices.Contains(allowedOrigins, origin)) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Vary", "Origin")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		}
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
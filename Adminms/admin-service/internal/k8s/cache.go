@@ -0,0 +1,86 @@
+// internal/k8s/cache.go - Watch-backed cache for namespace and database listings
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// managedBySelector matches every resource created by the platform
+	managedBySelector = "app.kubernetes.io/managed-by=db-saas"
+	// cacheResyncPeriod is how often the informers replay their full state
+	cacheResyncPeriod = 10 * time.Minute
+	// cacheSyncTimeout bounds how long startup waits for the initial list
+	cacheSyncTimeout = 30 * time.Second
+)
+
+// resourceCache holds listers for managed namespaces and deployments
+type resourceCache struct {
+	namespaces  corelisters.NamespaceLister
+	deployments appslisters.DeploymentLister
+}
+
+// StartCache starts shared informers for db-saas namespaces and deployments and
+// waits for their initial sync. Until it succeeds, listings query the API server directly.
+func (k *K8sService) StartCache(stopCh <-chan struct{}) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(k.clientset, cacheResyncPeriod,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = managedBySelector
+		}))
+
+	namespaceInformer := factory.Core().V1().Namespaces()
+	deploymentInformer := factory.Apps().V1().Deployments()
+	c := &resourceCache{
+		namespaces:  namespaceInformer.Lister(),
+		deployments: deploymentInformer.Lister(),
+	}
+
+	factory.Start(stopCh)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheSyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(ctx.Done(),
+		namespaceInformer.Informer().HasSynced,
+		deploymentInformer.Informer().HasSynced,
+	) {
+		return fmt.Errorf("timed out waiting for informer caches to sync")
+	}
+
+	k.cache = c
+	return nil
+}
+
+// getAllNamespacesFromCache builds the namespace listing from the informer cache
+func (k *K8sService) getAllNamespacesFromCache() ([]*NamespaceInfo, error) {
+	namespaces, err := k.cache.namespaces.List(k.managedNamespaceSelector())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	databaseSelector := labels.SelectorFromSet(labels.Set{"app.kubernetes.io/component": "database"})
+
+	result := make([]*NamespaceInfo, 0, len(namespaces))
+	for _, ns := range namespaces {
+		deployments, err := k.cache.deployments.Deployments(ns.Name).List(databaseSelector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deployments in %s: %w", ns.Name, err)
+		}
+
+		result = append(result, &NamespaceInfo{
+			Name:          ns.Name,
+			CreatedAt:     ns.CreationTimestamp.Time,
+			DatabaseCount: int32(len(deployments)),
+			Status:        namespaceStatus(ns),
+		})
+	}
+	return result, nil
+}
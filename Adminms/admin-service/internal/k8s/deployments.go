@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/BouchamiAhmed/TBD/shared"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -25,7 +26,7 @@ func (k *K8sService) createPostgreSQLDeployment(req *DatabaseRequest, namespace
 				"app":                          req.Name,
 				"app.kubernetes.io/component":  "database",
 				"app.kubernetes.io/managed-by": "db-saas",
-				"db-saas/type":                 "postgresql",
+				"db-saas/type":                 shared.DatabaseTypePostgreSQL,
 				"db-saas/user-id":              strconv.Itoa(req.UserID),
 			},
 		},
@@ -51,7 +52,7 @@ func (k *K8sService) createPostgreSQLDeployment(req *DatabaseRequest, namespace
 								{ContainerPort: 5432},
 							},
 							Env: []corev1.EnvVar{
-								{Name: "POSTGRES_DB", Value: req.Name},
+								{Name: "POSTGRES_DB", Value: req.LogicalDatabaseName()},
 								{Name: "POSTGRES_USER", Value: req.Username},
 								{Name: "POSTGRES_PASSWORD", Value: req.Password},
 							},
@@ -100,7 +101,7 @@ func (k *K8sService) createPostgreSQLService(req *DatabaseRequest) *corev1.Servi
 
 func (k *K8sService) createPgAdminDeployment(req *DatabaseRequest, namespace string) *appsv1.Deployment {
 	replicas := int32(1)
-	return &appsv1.Deployment{
+	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      req.Name + "-pgadmin",
 			Namespace: namespace,
@@ -134,11 +135,10 @@ func (k *K8sService) createPgAdminDeployment(req *DatabaseRequest, namespace str
 								{ContainerPort: 80},
 							},
 							Env: []corev1.EnvVar{
-								{Name: "PGADMIN_DEFAULT_EMAIL", Value: fmt.Sprintf("admin%s@gmail.com", req.Name)},
+								{Name: "PGADMIN_DEFAULT_EMAIL", Value: k.pgAdminEmail(req)},
 								{Name: "PGADMIN_DEFAULT_PASSWORD", Value: req.Password},
 								{Name: "PGADMIN_CONFIG_SERVER_MODE", Value: "False"},
 								{Name: "PGADMIN_CONFIG_MASTER_PASSWORD_REQUIRED", Value: "False"},
-								// Removed SCRIPT_NAME - let it work at root path after StripPrefix
 							},
 							Resources: corev1.ResourceRequirements{
 								Requests: corev1.ResourceList{
@@ -156,6 +156,13 @@ func (k *K8sService) createPgAdminDeployment(req *DatabaseRequest, namespace str
 			},
 		},
 	}
+
+	// Tell pgAdmin its subdirectory when served under a path prefix
+	if k.pgAdminRouting().Strategy() == shared.PgAdminRoutingPathPrefix {
+		container := &deployment.Spec.Template.Spec.Containers[0]
+		container.Env = append(container.Env, corev1.EnvVar{Name: "SCRIPT_NAME", Value: shared.PgAdminPathPrefix(namespace, req.Name)})
+	}
+	return deployment
 }
 
 func (k *K8sService) createPgAdminService(req *DatabaseRequest) *corev1.Service {
@@ -196,7 +203,7 @@ func (k *K8sService) createMySQLDeployment(req *DatabaseRequest, namespace strin
 				"app":                          req.Name,
 				"app.kubernetes.io/component":  "database",
 				"app.kubernetes.io/managed-by": "db-saas",
-				"db-saas/type":                 "mysql",
+				"db-saas/type":                 shared.DatabaseTypeMySQL,
 				"db-saas/user-id":              strconv.Itoa(req.UserID),
 			},
 		},
@@ -223,7 +230,7 @@ func (k *K8sService) createMySQLDeployment(req *DatabaseRequest, namespace strin
 							},
 							Env: []corev1.EnvVar{
 								{Name: "MYSQL_ROOT_PASSWORD", Value: req.Password},
-								{Name: "MYSQL_DATABASE", Value: req.Name},
+								{Name: "MYSQL_DATABASE", Value: req.LogicalDatabaseName()},
 								{Name: "MYSQL_USER", Value: req.Username},
 								{Name: "MYSQL_PASSWORD", Value: req.Password},
 							},
@@ -363,17 +370,19 @@ func (k *K8sService) createTraefikResources(ctx context.Context, req *DatabaseRe
 		return fmt.Errorf("dynamic client not available")
 	}
 
+	if adminType == "pgadmin" {
+		return k.createPgAdminIngressRoute(ctx, req, namespace)
+	}
+
 	pathPrefix := fmt.Sprintf("/%s/%s-%s", namespace, req.Name, adminType)
+	stripPrefixName := k.traefikObjectName(namespace, fmt.Sprintf("%s-%s-stripprefix", req.Name, adminType))
 
 	// Create StripPrefix middleware
 	stripMiddleware := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"apiVersion": "traefik.io/v1alpha1",
 			"kind":       "Middleware",
-			"metadata": map[string]interface{}{
-				"name":      fmt.Sprintf("%s-%s-stripprefix", req.Name, adminType),
-				"namespace": namespace,
-			},
+			"metadata":   k.traefikMetadata(req, namespace, stripPrefixName),
 			"spec": map[string]interface{}{
 				"stripPrefix": map[string]interface{}{
 					"prefixes": []interface{}{pathPrefix},
@@ -388,7 +397,7 @@ func (k *K8sService) createTraefikResources(ctx context.Context, req *DatabaseRe
 		Resource: "middlewares",
 	}
 
-	_, err := k.dynamicClient.Resource(middlewareGVR).Namespace(namespace).Create(ctx, stripMiddleware, metav1.CreateOptions{})
+	_, err := k.dynamicClient.Resource(middlewareGVR).Namespace(k.traefikNamespace(namespace)).Create(ctx, stripMiddleware, metav1.CreateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to create middleware: %w", err)
 	}
@@ -398,25 +407,23 @@ func (k *K8sService) createTraefikResources(ctx context.Context, req *DatabaseRe
 		Object: map[string]interface{}{
 			"apiVersion": "traefik.io/v1alpha1",
 			"kind":       "IngressRoute",
-			"metadata": map[string]interface{}{
-				"name":      fmt.Sprintf("%s-%s-ingress", req.Name, adminType),
-				"namespace": namespace,
-			},
+			"metadata":   k.traefikMetadata(req, namespace, k.traefikObjectName(namespace, fmt.Sprintf("%s-%s-ingress", req.Name, adminType))),
 			"spec": map[string]interface{}{
 				"entryPoints": []interface{}{"web"},
 				"routes": []interface{}{
 					map[string]interface{}{
-						"match": fmt.Sprintf(`Host("10.9.21.201") && PathPrefix("%s")`, pathPrefix),
+						"match": k.traefikHostPathRule(k.cfg.PublicHost, pathPrefix),
 						"kind":  "Rule",
 						"middlewares": []interface{}{
 							map[string]interface{}{
-								"name": fmt.Sprintf("%s-%s-stripprefix", req.Name, adminType),
+								"name": stripPrefixName,
 							},
 						},
 						"services": []interface{}{
 							map[string]interface{}{
-								"name": fmt.Sprintf("%s-%s", req.Name, adminType),
-								"port": 80,
+								"name":      fmt.Sprintf("%s-%s", req.Name, adminType),
+								"namespace": namespace,
+								"port":      80,
 							},
 						},
 					},
@@ -431,10 +438,49 @@ func (k *K8sService) createTraefikResources(ctx context.Context, req *DatabaseRe
 		Resource: "ingressroutes",
 	}
 
-	_, err = k.dynamicClient.Resource(ingressGVR).Namespace(namespace).Create(ctx, ingressRoute, metav1.CreateOptions{})
+	_, err = k.dynamicClient.Resource(ingressGVR).Namespace(k.traefikNamespace(namespace)).Create(ctx, ingressRoute, metav1.CreateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to create ingress route: %w", err)
 	}
 
 	return nil
 }
+
+// createPgAdminIngressRoute routes to pgAdmin according to the configured
+// strategy; neither strategy needs a middleware (see pgadmin_routing.go)
+func (k *K8sService) createPgAdminIngressRoute(ctx context.Context, req *DatabaseRequest, namespace string) error {
+	ingressRoute := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "traefik.io/v1alpha1",
+			"kind":       "IngressRoute",
+			"metadata":   k.traefikMetadata(req, namespace, k.traefikObjectName(namespace, fmt.Sprintf("%s-pgadmin-ingress", req.Name))),
+			"spec": map[string]interface{}{
+				"entryPoints": []interface{}{"web"},
+				"routes": []interface{}{
+					map[string]interface{}{
+						"match": k.pgAdminMatchRule(namespace, req.Name),
+						"kind":  "Rule",
+						"services": []interface{}{
+							map[string]interface{}{
+								"name":      fmt.Sprintf("%s-pgadmin", req.Name),
+								"namespace": namespace,
+								"port":      80,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	ingressGVR := schema.GroupVersionResource{
+		Group:    "traefik.io",
+		Version:  "v1alpha1",
+		Resource: "ingressroutes",
+	}
+
+	if _, err := k.dynamicClient.Resource(ingressGVR).Namespace(k.traefikNamespace(namespace)).Create(ctx, ingressRoute, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create ingress route: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+// internal/k8s/instance.go - Namespace isolation between db-saas instances
+package k8s
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// instanceLabel records on each namespace the NAMESPACE_PREFIX of the db-saas
+// instance that created it, so instances sharing a cluster only list their own
+const instanceLabel = "db-saas/instance"
+
+// instanceName returns the NAMESPACE_PREFIX as a label value, or "" when unset
+func (k *K8sService) instanceName() string {
+	return strings.Trim(k.cfg.NamespacePrefix, "-.")
+}
+
+// withInstanceLabel adds the instance label to a namespace's labels when a
+// NAMESPACE_PREFIX is configured
+func (k *K8sService) withInstanceLabel(namespaceLabels map[string]string) map[string]string {
+	if instance := k.instanceName(); instance != "" {
+		namespaceLabels[instanceLabel] = instance
+	}
+	return namespaceLabels
+}
+
+// managedNamespaceSelector matches the managed namespaces of this instance:
+// those labeled with its instance name or, without a NAMESPACE_PREFIX, those
+// carrying no instance label, so prefixed instances' namespaces are left alone
+func (k *K8sService) managedNamespaceSelector() labels.Selector {
+	selector := labels.SelectorFromSet(labels.Set{"app.kubernetes.io/managed-by": "db-saas"})
+
+	var instance *labels.Requirement
+	if name := k.instanceName(); name != "" {
+		instance, _ = labels.NewRequirement(instanceLabel, selection.Equals, []string{name})
+	} else {
+		instance, _ = labels.NewRequirement(instanceLabel, selection.DoesNotExist, nil)
+	}
+	return selector.Add(*instance)
+}
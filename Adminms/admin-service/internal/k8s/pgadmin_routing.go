@@ -0,0 +1,26 @@
+// internal/k8s/pgadmin_routing.go - pgAdmin routing strategy shared by the builders
+package k8s
+
+import "github.com/BouchamiAhmed/TBD/shared"
+
+// pgAdminRouting returns the configured pgAdmin routing; see shared.PgAdminRouting
+// for the strategies. Misconfigurations are reported once at startup.
+func (k *K8sService) pgAdminRouting() shared.PgAdminRouting {
+	return shared.PgAdminRouting{Mode: k.cfg.PgAdminRouting, HostDomain: k.cfg.PgAdminHostDomain}
+}
+
+// pgAdminMatchRule returns the Traefik match rule for a pgAdmin IngressRoute
+func (k *K8sService) pgAdminMatchRule(namespace, dbName string) string {
+	return k.pgAdminRouting().MatchRule(k.cfg.TraefikMatcherVersion, k.cfg.PublicHost, namespace, dbName)
+}
+
+// pgAdminURL returns the URL users open to reach pgAdmin
+func (k *K8sService) pgAdminURL(namespace, dbName string) string {
+	return k.pgAdminRouting().URL("http", k.cfg.PublicHost, namespace, dbName)
+}
+
+// pgAdminEmail returns the pgAdmin login email: the user's own email when
+// known, otherwise the database username at PGADMIN_EMAIL_DOMAIN
+func (k *K8sService) pgAdminEmail(req *DatabaseRequest) string {
+	return shared.PgAdminEmail(req.Email, req.Username, k.cfg.PgAdminEmailDomain)
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time" // Add this import  // Add this import
 
+	"github.com/BouchamiAhmed/TBD/shared"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -16,40 +18,27 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+
+	"admin-service/internal/config"
 )
 
 // K8sService handles all Kubernetes operations
 type K8sService struct {
 	clientset     *kubernetes.Clientset
 	dynamicClient dynamic.Interface
+	cfg           *config.Config
+	cache         *resourceCache // set once StartCache has synced
 }
 
-// DatabaseRequest matches your existing structure
-type DatabaseRequest struct {
-	Name     string
-	Username string
-	Password string
-	Type     string // "mysql" or "postgres"
-	UserID   int
-	UserName string
-}
-
-// DatabaseResponse matches your existing structure
-type DatabaseResponse struct {
-	Name      string
-	Host      string
-	Port      string
-	Username  string
-	Type      string
-	Status    string
-	Message   string
-	Namespace string
-	AdminURL  string
-	AdminType string
-}
+// DatabaseRequest and DatabaseResponse are shared with the API server so the
+// two services cannot drift apart
+type (
+	DatabaseRequest  = shared.DatabaseRequest
+	DatabaseResponse = shared.DatabaseResponse
+)
 
 // NewK8sService creates a new Kubernetes service client
-func NewK8sService() (*K8sService, error) {
+func NewK8sService(cfg *config.Config) (*K8sService, error) {
 	var config *rest.Config
 	var err error
 
@@ -60,10 +49,10 @@ func NewK8sService() (*K8sService, error) {
 		fmt.Println("🔄 Falling back to local development config...")
 
 		// Only fall back to kubeconfig for local development
-		if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		if cfg.KubernetesServiceHost == "" {
 			kubeconfig := "kubeconfig.yaml"
 			if _, err := os.Stat(kubeconfig); os.IsNotExist(err) {
-				kubeconfig = os.Getenv("KUBECONFIG")
+				kubeconfig = cfg.Kubeconfig
 				if kubeconfig == "" {
 					// Try default location
 					homeDir, herr := os.UserHomeDir()
@@ -104,6 +93,7 @@ func NewK8sService() (*K8sService, error) {
 	return &K8sService{
 		clientset:     clientset,
 		dynamicClient: dynamicClient,
+		cfg:           cfg,
 	}, nil
 }
 
@@ -119,9 +109,19 @@ type NamespaceInfo struct {
 func (k *K8sService) GetAllNamespaces(ctx context.Context) ([]*NamespaceInfo, error) {
 	fmt.Printf("🔍 Getting all db-saas namespaces\n")
 
+	// Serve from the informer cache when it is available
+	if k.cache != nil {
+		result, err := k.getAllNamespacesFromCache()
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("✅ Found %d total db-saas namespaces (cached)\n", len(result))
+		return result, nil
+	}
+
 	// Get all namespaces managed by db-saas
 	namespaces, err := k.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
-		LabelSelector: "app.kubernetes.io/managed-by=db-saas",
+		LabelSelector: k.managedNamespaceSelector().String(),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list namespaces: %w", err)
@@ -138,17 +138,11 @@ func (k *K8sService) GetAllNamespaces(ctx context.Context) ([]*NamespaceInfo, er
 			dbCount = len(deployments.Items)
 		}
 
-		// Determine namespace status
-		status := "Active"
-		if ns.Status.Phase != corev1.NamespaceActive {
-			status = string(ns.Status.Phase)
-		}
-
 		nsInfo := &NamespaceInfo{
 			Name:          ns.Name,
 			CreatedAt:     ns.CreationTimestamp.Time,
 			DatabaseCount: int32(dbCount),
-			Status:        status,
+			Status:        namespaceStatus(&ns),
 		}
 
 		result = append(result, nsInfo)
@@ -158,13 +152,34 @@ func (k *K8sService) GetAllNamespaces(ctx context.Context) ([]*NamespaceInfo, er
 	return result, nil
 }
 
-// GetUserNamespace returns the namespace name for a given user (same as your existing logic)
-func (k *K8sService) GetUserNamespace(userID int, username string) string {
-	namespaceName := fmt.Sprintf("%d%s", userID, username)
-	if len(namespaceName) > 63 {
-		namespaceName = namespaceName[:63]
+// namespaceStatus reports a namespace's phase, defaulting to "Active"
+func namespaceStatus(ns *corev1.Namespace) string {
+	if ns.Status.Phase != corev1.NamespaceActive && ns.Status.Phase != "" {
+		return string(ns.Status.Phase)
 	}
-	return namespaceName
+	return "Active"
+}
+
+// GetUserNamespace returns the namespace name for a given user (same as your existing logic),
+// prefixed with NAMESPACE_PREFIX. A namespace an earlier release created under
+// the truncated legacy name keeps being used.
+func (k *K8sService) GetUserNamespace(userID int, username string) string {
+	name := fmt.Sprintf("%s%d%s", k.cfg.NamespacePrefix, userID, username)
+	return shared.ResolveNamespaceName(name, func(legacy string) (bool, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		ns, err := k.clientset.CoreV1().Namespaces().Get(ctx, legacy, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		// Namespaces this service created carry no user label
+		owner, labeled := ns.Labels["db-saas/user-id"]
+		return !labeled || owner == strconv.Itoa(userID), nil
+	})
 }
 
 // CreateDatabase deploys a database using your existing logic
@@ -179,13 +194,24 @@ func (k *K8sService) CreateDatabase(ctx context.Context, req *DatabaseRequest) (
 	}
 
 	// Deploy based on database type
-	if req.Type == "mysql" {
+	if req.Type == shared.DatabaseTypeMySQL {
 		return k.deployMySQL(ctx, req, userNamespace)
 	} else {
 		return k.deployPostgreSQL(ctx, req, userNamespace)
 	}
 }
 
+// withExtraMetadata merges operator-configured labels or annotations into the
+// managed set; managed keys always win so ownership labels cannot be overridden
+func withExtraMetadata(managed, extra map[string]string) map[string]string {
+	for key, value := range extra {
+		if _, exists := managed[key]; !exists {
+			managed[key] = value
+		}
+	}
+	return managed
+}
+
 // ensureNamespace creates namespace if it doesn't exist
 func (k *K8sService) ensureNamespace(ctx context.Context, namespace string) error {
 	_, err := k.clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
@@ -194,10 +220,11 @@ func (k *K8sService) ensureNamespace(ctx context.Context, namespace string) erro
 			ns := &corev1.Namespace{
 				ObjectMeta: metav1.ObjectMeta{
 					Name: namespace,
-					Labels: map[string]string{
+					Labels: withExtraMetadata(k.withInstanceLabel(map[string]string{
 						"app.kubernetes.io/managed-by": "db-saas",
 						"db-saas/user-namespace":       "true",
-					},
+					}), k.cfg.NamespaceLabels),
+					Annotations: withExtraMetadata(map[string]string{}, k.cfg.NamespaceAnnotations),
 				},
 			}
 			_, err = k.clientset.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
@@ -212,7 +239,8 @@ func (k *K8sService) ensureNamespace(ctx context.Context, namespace string) erro
 	return nil
 }
 
-// deployPostgreSQL deploys PostgreSQL database with pgAdmin
+// deployPostgreSQL deploys PostgreSQL database with pgAdmin, unless
+// ENABLE_ADMIN_DASHBOARDS is off
 func (k *K8sService) deployPostgreSQL(ctx context.Context, req *DatabaseRequest, namespace string) (*DatabaseResponse, error) {
 	// Create PostgreSQL deployment
 	postgresDeployment := k.createPostgreSQLDeployment(req, namespace)
@@ -230,6 +258,19 @@ func (k *K8sService) deployPostgreSQL(ctx context.Context, req *DatabaseRequest,
 	}
 	fmt.Printf("✅ Created PostgreSQL service: %s\n", req.Name)
 
+	if !k.cfg.Features.AdminDashboards {
+		return &DatabaseResponse{
+			Name:      req.Name,
+			Host:      fmt.Sprintf("%s.%s.svc.cluster.local", req.Name, namespace),
+			Port:      "5432",
+			Username:  req.Username,
+			Type:      req.Type,
+			Status:    "provisioning",
+			Message:   fmt.Sprintf("PostgreSQL database deployment initiated in namespace '%s'", namespace),
+			Namespace: namespace,
+		}, nil
+	}
+
 	// Create pgAdmin deployment
 	pgAdminDeployment := k.createPgAdminDeployment(req, namespace)
 	_, err = k.clientset.AppsV1().Deployments(namespace).Create(ctx, pgAdminDeployment, metav1.CreateOptions{})
@@ -253,7 +294,7 @@ func (k *K8sService) deployPostgreSQL(ctx context.Context, req *DatabaseRequest,
 
 	// Build response
 	host := fmt.Sprintf("%s.%s.svc.cluster.local", req.Name, namespace)
-	adminURL := fmt.Sprintf("http://10.9.21.201/%s/%s-pgadmin", namespace, req.Name)
+	adminURL := k.pgAdminURL(namespace, req.Name)
 
 	return &DatabaseResponse{
 		Name:      req.Name,
@@ -261,7 +302,7 @@ func (k *K8sService) deployPostgreSQL(ctx context.Context, req *DatabaseRequest,
 		Port:      "5432",
 		Username:  req.Username,
 		Type:      req.Type,
-		Status:    "creating",
+		Status:    "provisioning",
 		Message:   fmt.Sprintf("PostgreSQL database and pgAdmin dashboard deployment initiated in namespace '%s'", namespace),
 		Namespace: namespace,
 		AdminURL:  adminURL,
@@ -269,7 +310,8 @@ func (k *K8sService) deployPostgreSQL(ctx context.Context, req *DatabaseRequest,
 	}, nil
 }
 
-// deployMySQL deploys MySQL database with phpMyAdmin
+// deployMySQL deploys MySQL database with phpMyAdmin, unless
+// ENABLE_ADMIN_DASHBOARDS is off
 func (k *K8sService) deployMySQL(ctx context.Context, req *DatabaseRequest, namespace string) (*DatabaseResponse, error) {
 	// Create MySQL deployment
 	mysqlDeployment := k.createMySQLDeployment(req, namespace)
@@ -287,6 +329,19 @@ func (k *K8sService) deployMySQL(ctx context.Context, req *DatabaseRequest, name
 	}
 	fmt.Printf("✅ Created MySQL service: %s\n", req.Name)
 
+	if !k.cfg.Features.AdminDashboards {
+		return &DatabaseResponse{
+			Name:      req.Name,
+			Host:      fmt.Sprintf("%s.%s.svc.cluster.local", req.Name, namespace),
+			Port:      "3306",
+			Username:  req.Username,
+			Type:      req.Type,
+			Status:    "provisioning",
+			Message:   fmt.Sprintf("MySQL database deployment initiated in namespace '%s'", namespace),
+			Namespace: namespace,
+		}, nil
+	}
+
 	// Create phpMyAdmin deployment
 	phpMyAdminDeployment := k.createPhpMyAdminDeployment(req, namespace)
 	_, err = k.clientset.AppsV1().Deployments(namespace).Create(ctx, phpMyAdminDeployment, metav1.CreateOptions{})
@@ -310,7 +365,7 @@ func (k *K8sService) deployMySQL(ctx context.Context, req *DatabaseRequest, name
 
 	// Build response
 	host := fmt.Sprintf("%s.%s.svc.cluster.local", req.Name, namespace)
-	adminURL := fmt.Sprintf("http://10.9.21.201/%s/%s-phpmyadmin", namespace, req.Name)
+	adminURL := fmt.Sprintf("http://%s/%s/%s-phpmyadmin", k.cfg.PublicHost, namespace, req.Name)
 
 	return &DatabaseResponse{
 		Name:      req.Name,
@@ -318,7 +373,7 @@ func (k *K8sService) deployMySQL(ctx context.Context, req *DatabaseRequest, name
 		Port:      "3306",
 		Username:  req.Username,
 		Type:      req.Type,
-		Status:    "creating",
+		Status:    "provisioning",
 		Message:   fmt.Sprintf("MySQL database and phpMyAdmin dashboard deployment initiated in namespace '%s'", namespace),
 		Namespace: namespace,
 		AdminURL:  adminURL,
@@ -0,0 +1,49 @@
+// internal/k8s/traefik_rules.go - Traefik match rules and object placement
+package k8s
+
+import (
+	"strconv"
+
+	"github.com/BouchamiAhmed/TBD/shared"
+)
+
+// traefikHostPathRule returns a rule matching a host and path prefix in the
+// configured matcher syntax; unknown versions are reported once at startup
+func (k *K8sService) traefikHostPathRule(host, pathPrefix string) string {
+	return shared.TraefikHostPathRule(k.cfg.TraefikMatcherVersion, host, pathPrefix)
+}
+
+// traefikNamespace returns the namespace holding the IngressRoutes and
+// Middlewares of a database in namespace: TRAEFIK_NAMESPACE when set, otherwise
+// the database's own namespace. Routing to a service in another namespace
+// requires Traefik's kubernetesCRD provider to run with allowCrossNamespace.
+func (k *K8sService) traefikNamespace(namespace string) string {
+	if k.cfg.TraefikNamespace == "" {
+		return namespace
+	}
+	return k.cfg.TraefikNamespace
+}
+
+// traefikObjectName qualifies a Traefik object name with the database
+// namespace when objects from several namespaces share TRAEFIK_NAMESPACE
+func (k *K8sService) traefikObjectName(namespace, name string) string {
+	if k.traefikNamespace(namespace) == namespace {
+		return name
+	}
+	return namespace + "-" + name
+}
+
+// traefikMetadata returns the metadata of a Traefik object for a database in
+// namespace, labelled like TBDback's so either service can clean it up
+func (k *K8sService) traefikMetadata(req *DatabaseRequest, namespace, name string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":      name,
+		"namespace": k.traefikNamespace(namespace),
+		"labels": map[string]interface{}{
+			"app.kubernetes.io/managed-by": "db-saas",
+			"db-saas/database":             req.Name,
+			"db-saas/user-id":              strconv.Itoa(req.UserID),
+			shared.TraefikNamespaceLabel:   namespace,
+		},
+	}
+}
@@ -0,0 +1,307 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"admin-service/internal/database"
+	pb "admin-service/pkg/pb"
+)
+
+// Authentication modes selected by AUTH_MODE
+const (
+	AuthModeMock = "mock" // canned users and tokens, for local testing only
+	AuthModeReal = "real" // users are stored and checked in the database
+)
+
+// ValidAuthMode reports whether mode is a known AUTH_MODE; empty means real
+func ValidAuthMode(mode string) bool {
+	return mode == "" || mode == AuthModeMock || mode == AuthModeReal
+}
+
+// authMode returns the effective authentication mode. Mock authentication is
+// only ever used when AUTH_MODE=mock is set explicitly; otherwise calls fail
+// as unavailable while the database is down.
+func (s *AdminServer) authMode() string {
+	if s.configuredAuthMode == AuthModeMock {
+		return AuthModeMock
+	}
+	return AuthModeReal
+}
+
+// publicMethods can be called without a token
+var publicMethods = map[string]bool{
+	"/admin.v1.AdminService/Login":    true,
+	"/admin.v1.AdminService/Register": true,
+}
+
+// AuthInterceptor rejects calls to every method but Login and Register that
+// do not carry a bearer token with a live session in the "authorization"
+// metadata (the gateway copies it from the Authorization header). Sessions
+// are issued at login by this service or the API server. In mock mode any
+// token is accepted.
+func (s *AdminServer) AuthInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		token := bearerToken(ctx)
+		if token == "" {
+			return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+
+		if s.authMode() == AuthModeMock {
+			warnMockAuth(info.FullMethod, "")
+			return handler(ctx, req)
+		}
+
+		dbClient := s.DBClient()
+		if dbClient == nil {
+			return nil, status.Error(codes.Unavailable, "authentication unavailable: database not connected")
+		}
+		userID, err := dbClient.SessionUserID(token)
+		if err != nil {
+			log.Printf("❌ Session check failed for %s: %v", info.FullMethod, err)
+			return nil, status.Error(codes.Unavailable, "authentication unavailable")
+		}
+		if userID == 0 {
+			return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+		}
+		return handler(context.WithValue(ctx, sessionUserKey{}, userID), req)
+	}
+}
+
+// sessionUserKey stores the ID of the user whose session authenticated a call
+type sessionUserKey struct{}
+
+// sessionUserID returns the ID of the user AuthInterceptor authenticated, or 0
+func sessionUserID(ctx context.Context) int {
+	userID, _ := ctx.Value(sessionUserKey{}).(int)
+	return userID
+}
+
+// authorizeUser returns the user a call acts for: the session's user when
+// requested is 0, and a PermissionDenied error when requested is another
+// user. In mock mode there is no session and requested is trusted.
+func (s *AdminServer) authorizeUser(ctx context.Context, requested int32) (int32, error) {
+	if s.authMode() == AuthModeMock {
+		return requested, nil
+	}
+	userID := sessionUserID(ctx)
+	if userID == 0 {
+		return 0, status.Error(codes.Unauthenticated, "no authenticated user")
+	}
+	if requested != 0 && int(requested) != userID {
+		return 0, status.Error(codes.PermissionDenied, "cannot act for another user")
+	}
+	return int32(userID), nil
+}
+
+// authorizeNamespace rejects calls on a namespace that is not the session
+// user's: neither the namespace their databases are deployed to nor one
+// holding a database recorded for them
+func (s *AdminServer) authorizeNamespace(ctx context.Context, namespace string) error {
+	if s.authMode() == AuthModeMock {
+		return nil
+	}
+	userID := sessionUserID(ctx)
+	if userID == 0 {
+		return status.Error(codes.Unauthenticated, "no authenticated user")
+	}
+	if namespace == "" {
+		return status.Error(codes.InvalidArgument, "namespace required")
+	}
+
+	if s.k8sService != nil {
+		user, err := s.lookupUser(userID)
+		if err != nil {
+			return err
+		}
+		if namespace == s.k8sService.GetUserNamespace(userID, user.Username) {
+			return nil
+		}
+	}
+	dbClient := s.DBClient()
+	if dbClient == nil {
+		return status.Error(codes.Unavailable, "authorization unavailable: database not connected")
+	}
+	owns, err := dbClient.UserOwnsNamespace(userID, namespace)
+	if err != nil {
+		log.Printf("❌ Namespace check failed for user %d: %v", userID, err)
+		return status.Error(codes.Unavailable, "authorization unavailable")
+	}
+	if !owns {
+		return status.Error(codes.PermissionDenied, "cannot access another user's namespace")
+	}
+	return nil
+}
+
+// requireAdmin rejects calls from users not listed in ADMIN_USERNAMES, like
+// the API server's admin-only routes. Mock mode trusts every caller.
+func (s *AdminServer) requireAdmin(ctx context.Context) error {
+	if s.authMode() == AuthModeMock {
+		return nil
+	}
+	userID := sessionUserID(ctx)
+	if userID == 0 {
+		return status.Error(codes.Unauthenticated, "no authenticated user")
+	}
+
+	user, err := s.lookupUser(userID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(s.adminUsernames, user.Username) {
+		return status.Error(codes.PermissionDenied, "admin access required")
+	}
+	return nil
+}
+
+// lookupUser returns the stored user with userID, from the auth_users table
+// shared with the API server. Mock mode, where users need not be stored,
+// falls back to a stand-in named "user<id>".
+func (s *AdminServer) lookupUser(userID int) (*database.User, error) {
+	dbClient := s.DBClient()
+	if dbClient != nil {
+		user, err := dbClient.GetUserByID(userID)
+		if err != nil {
+			log.Printf("❌ Could not look up user %d: %v", userID, err)
+			return nil, status.Error(codes.Unavailable, "user lookup unavailable")
+		}
+		if user != nil {
+			return user, nil
+		}
+	}
+
+	switch {
+	case s.authMode() == AuthModeMock:
+		return &database.User{ID: userID, Username: fmt.Sprintf("user%d", userID)}, nil
+	case dbClient == nil:
+		return nil, status.Error(codes.Unavailable, "user lookup unavailable: database not connected")
+	default:
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("user %d not found", userID))
+	}
+}
+
+// bearerToken returns the token of an "authorization: Bearer <token>" entry
+// in the incoming metadata, or ""
+func bearerToken(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+// ChainInterceptors runs interceptors in order around a call, the first
+// outermost, so the gateway can apply the same chain as the gRPC server
+func ChainInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+// warnMockAuth logs loudly that a request was served by mock authentication
+func warnMockAuth(method, username string) {
+	log.Printf("🚨 INSECURE: %s for %q served by MOCK authentication (AUTH_MODE=mock or no database) - never use this in production", method, username)
+}
+
+// newSessionToken returns a random opaque token for a real login
+func newSessionToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// issueSession returns a new token for userID, recorded in the sessions table
+// so AuthInterceptor accepts it on the calls that follow
+func (s *AdminServer) issueSession(dbClient Store, userID int) (string, error) {
+	token, err := newSessionToken()
+	if err != nil {
+		return "", err
+	}
+	if err := dbClient.CreateSession(token, userID, s.tokenTTL); err != nil {
+		log.Printf("❌ Failed to record session for user %d: %v", userID, err)
+		return "", status.Error(codes.Unavailable, "authentication unavailable")
+	}
+	return token, nil
+}
+
+// userToProto converts a stored user to its protobuf form
+func userToProto(user *database.User) *pb.User {
+	return &pb.User{
+		Id:        int32(user.ID),
+		Username:  user.Username,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		CreatedAt: timestamppb.New(user.CreatedAt),
+	}
+}
+
+// loginWithDatabase checks the credentials against the auth_users table
+func (s *AdminServer) loginWithDatabase(req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	dbClient := s.DBClient()
+	if dbClient == nil {
+		return nil, status.Error(codes.Unavailable, "authentication unavailable: database not connected")
+	}
+
+	user, err := dbClient.AuthenticateUser(req.Username, req.Password)
+	if err != nil {
+		log.Printf("❌ Login failed for user %s: %v", req.Username, err)
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	token, err := s.issueSession(dbClient, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ Login successful for user: %s", req.Username)
+	return &pb.LoginResponse{User: userToProto(user), Token: token}, nil
+}
+
+// registerWithDatabase stores a new user in the auth_users table
+func (s *AdminServer) registerWithDatabase(req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
+	dbClient := s.DBClient()
+	if dbClient == nil {
+		return nil, status.Error(codes.Unavailable, "registration unavailable: database not connected")
+	}
+
+	user, err := dbClient.CreateUser(req.Username, req.Email, req.Password, req.FirstName, req.LastName)
+	if err != nil {
+		log.Printf("❌ Registration failed for user %s: %v", req.Username, err)
+		return nil, fmt.Errorf("registration failed: %w", err)
+	}
+
+	token, err := s.issueSession(dbClient, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ Registration successful for user: %s", req.Username)
+	return &pb.RegisterResponse{User: userToProto(user), Token: token}, nil
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"admin-service/internal/config"
+	"admin-service/internal/database"
+	pb "admin-service/pkg/pb"
+)
+
+func TestAuthInterceptor(t *testing.T) {
+	srv := NewAdminServer(nil, nil, &config.Config{AuthMode: AuthModeReal, TokenTTL: time.Hour})
+	interceptor := srv.AuthInterceptor()
+	withToken := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer abc"))
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		want   codes.Code
+	}{
+		{"login is public", context.Background(), "/admin.v1.AdminService/Login", codes.OK},
+		{"register is public", context.Background(), "/admin.v1.AdminService/Register", codes.OK},
+		{"create needs a token", context.Background(), "/admin.v1.AdminService/CreateDatabase", codes.Unauthenticated},
+		{"namespaces need a token", context.Background(), "/admin.v1.AdminService/GetAllNamespaces", codes.Unauthenticated},
+		{"no database to check against", withToken, "/admin.v1.AdminService/DeleteDatabase", codes.Unavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				return "ok", nil
+			}
+
+			_, err := interceptor(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("code = %s, want %s", got, tt.want)
+			}
+			if called != (tt.want == codes.OK) {
+				t.Errorf("handler called = %v, want %v", called, tt.want == codes.OK)
+			}
+		})
+	}
+}
+
+func TestChainInterceptorsRunsInOrder(t *testing.T) {
+	var order []string
+	record := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			order = append(order, name)
+			return handler(ctx, req)
+		}
+	}
+
+	chain := ChainInterceptors(record("first"), record("second"))
+	_, err := chain(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		order = append(order, "handler")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("chain returned error: %v", err)
+	}
+	if len(order) != 3 || order[0] != "first" || order[1] != "second" || order[2] != "handler" {
+		t.Errorf("call order = %v, want [first second handler]", order)
+	}
+}
+
+func TestAuthorizeUser(t *testing.T) {
+	srv := NewAdminServer(nil, nil, &config.Config{AuthMode: AuthModeReal, TokenTTL: time.Hour})
+	session := context.WithValue(context.Background(), sessionUserKey{}, 7)
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		requested int32
+		want      int32
+		code      codes.Code
+	}{
+		{"defaults to the session user", session, 0, 7, codes.OK},
+		{"own user id", session, 7, 7, codes.OK},
+		{"another user's id", session, 8, 0, codes.PermissionDenied},
+		{"no session", context.Background(), 7, 0, codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := srv.authorizeUser(tt.ctx, tt.requested)
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("code = %s, want %s", code, tt.code)
+			}
+			if got != tt.want {
+				t.Errorf("user = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeNamespace(t *testing.T) {
+	srv := NewAdminServer(nil, nil, &config.Config{AuthMode: AuthModeReal, TokenTTL: time.Hour})
+	session := context.WithValue(context.Background(), sessionUserKey{}, 7)
+
+	if code := status.Code(srv.authorizeNamespace(context.Background(), "ns")); code != codes.Unauthenticated {
+		t.Errorf("no session: code = %s, want %s", code, codes.Unauthenticated)
+	}
+	if code := status.Code(srv.authorizeNamespace(session, "")); code != codes.InvalidArgument {
+		t.Errorf("empty namespace: code = %s, want %s", code, codes.InvalidArgument)
+	}
+	if code := status.Code(srv.authorizeNamespace(session, "ns")); code != codes.Unavailable {
+		t.Errorf("no database: code = %s, want %s", code, codes.Unavailable)
+	}
+	if err := NewAdminServer(nil, nil, &config.Config{AuthMode: AuthModeMock, TokenTTL: time.Hour}).authorizeNamespace(context.Background(), "ns"); err != nil {
+		t.Errorf("mock mode: got %v, want nil", err)
+	}
+}
+
+// fakeStore keeps users and sessions in memory
+type fakeStore struct {
+	users    map[string]*database.User // by username
+	password map[string]string         // by username
+	sessions map[string]int            // token to user ID
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{users: map[string]*database.User{}, password: map[string]string{}, sessions: map[string]int{}}
+}
+
+func (f *fakeStore) AuthenticateUser(username, password string) (*database.User, error) {
+	if user, ok := f.users[username]; ok && f.password[username] == password {
+		return user, nil
+	}
+	return nil, fmt.Errorf("invalid credentials")
+}
+
+func (f *fakeStore) CreateUser(username, email, password, firstName, lastName string) (*database.User, error) {
+	user := &database.User{ID: len(f.users) + 1, Username: username, Email: email, FirstName: firstName, LastName: lastName}
+	f.users[username] = user
+	f.password[username] = password
+	return user, nil
+}
+
+func (f *fakeStore) CreateSession(token string, userID int, ttl time.Duration) error {
+	f.sessions[token] = userID
+	return nil
+}
+
+func (f *fakeStore) SessionUserID(token string) (int, error) {
+	return f.sessions[token], nil
+}
+
+func (f *fakeStore) GetUserByID(id int) (*database.User, error) {
+	for _, user := range f.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeStore) CreateDatabase(name, dbType, host, port, username, namespace string, userID int, adminURL, adminType string) (*database.Database, error) {
+	return &database.Database{Name: name, Namespace: namespace, UserID: userID}, nil
+}
+
+func (f *fakeStore) UserOwnsNamespace(userID int, namespace string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeStore) Close() error {
+	return nil
+}
+
+func TestLoginIssuesATokenTheInterceptorAccepts(t *testing.T) {
+	srv := NewAdminServer(nil, nil, &config.Config{AuthMode: "", TokenTTL: time.Hour})
+	store := newFakeStore()
+	srv.dbClient = store
+	if _, err := store.CreateUser("alice", "alice@example.com", "secret-password", "Alice", "Liddell"); err != nil {
+		t.Fatalf("CreateUser returned error: %v", err)
+	}
+
+	login, err := srv.Login(context.Background(), &pb.LoginRequest{Username: "alice", Password: "secret-password"})
+	if err != nil {
+		t.Fatalf("Login returned error: %v", err)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+login.Token))
+	var gotUser int
+	_, err = srv.AuthInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/admin.v1.AdminService/GetUserDatabases"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			gotUser = sessionUserID(ctx)
+			return "ok", nil
+		})
+	if err != nil {
+		t.Fatalf("authenticated call rejected: %v", err)
+	}
+	if gotUser != int(login.User.Id) {
+		t.Errorf("session user = %d, want %d", gotUser, login.User.Id)
+	}
+}
+
+func TestUnsetAuthModeFailsClosedWithoutDatabase(t *testing.T) {
+	srv := NewAdminServer(nil, nil, &config.Config{AuthMode: "", TokenTTL: time.Hour})
+
+	_, err := srv.Login(context.Background(), &pb.LoginRequest{Username: "alice", Password: "anything"})
+	if code := status.Code(err); code != codes.Unavailable {
+		t.Errorf("Login code = %s, want %s", code, codes.Unavailable)
+	}
+	if _, err := srv.authorizeUser(context.Background(), 7); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("authorizeUser trusted the requested user without a session: %v", err)
+	}
+}
+
+func TestGetAllNamespacesRequiresAnAdmin(t *testing.T) {
+	srv := NewAdminServer(nil, nil, &config.Config{AuthMode: AuthModeReal, TokenTTL: time.Hour, AdminUsernames: []string{"root"}})
+	store := newFakeStore()
+	srv.dbClient = store
+	alice, _ := store.CreateUser("alice", "alice@example.com", "secret-password", "Alice", "Liddell")
+	root, _ := store.CreateUser("root", "root@example.com", "secret-password", "Root", "User")
+
+	asAlice := context.WithValue(context.Background(), sessionUserKey{}, alice.ID)
+	if _, err := srv.GetAllNamespaces(asAlice, &pb.GetAllNamespacesRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("non-admin: code = %s, want %s", status.Code(err), codes.PermissionDenied)
+	}
+
+	asRoot := context.WithValue(context.Background(), sessionUserKey{}, root.ID)
+	if _, err := srv.GetAllNamespaces(asRoot, &pb.GetAllNamespacesRequest{}); err != nil {
+		t.Errorf("admin: got %v, want the namespace listing", err)
+	}
+}
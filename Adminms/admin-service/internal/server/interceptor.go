@@ -0,0 +1,106 @@
+// internal/server/interceptor.go - Request logging with secret redaction
+package server
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// redactedValue replaces the value of sensitive fields in logged payloads
+const redactedValue = "[REDACTED]"
+
+// sensitiveFieldMarkers flag fields whose values must never be logged
+// (e.g. CreateDatabaseRequest.password, LoginRequest.password, LoginResponse.token)
+var sensitiveFieldMarkers = []string{"password", "token", "secret"}
+
+// LoggingInterceptor logs every unary call's method, status and duration.
+// When logPayloads is set, requests and responses are logged too, with
+// sensitive fields redacted.
+func LoggingInterceptor(logPayloads bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		if logPayloads {
+			log.Printf("➡️  %s request: %s", info.FullMethod, redactedPayload(req))
+		}
+
+		resp, err := handler(ctx, req)
+
+		log.Printf("method=%s code=%s duration=%s", info.FullMethod, status.Code(err), time.Since(start))
+		if logPayloads && err == nil {
+			log.Printf("⬅️  %s response: %s", info.FullMethod, redactedPayload(resp))
+		}
+		return resp, err
+	}
+}
+
+// redactedPayload renders a message as JSON with sensitive fields masked.
+// Anything that is not a protobuf message is never rendered, since its
+// fields cannot be inspected.
+func redactedPayload(payload interface{}) string {
+	msg, ok := payload.(proto.Message)
+	if !ok {
+		return "<unloggable payload>"
+	}
+
+	clone := proto.Clone(msg)
+	redactMessage(clone.ProtoReflect())
+
+	rendered, err := protojson.Marshal(clone)
+	if err != nil {
+		return "<unloggable payload>"
+	}
+	return string(rendered)
+}
+
+// redactMessage masks sensitive string fields in place, recursing into
+// nested messages, lists and maps of messages
+func redactMessage(m protoreflect.Message) {
+	var sensitive []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case isSensitiveField(fd):
+			sensitive = append(sensitive, fd)
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				redactMessage(list.Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				redactMessage(mv.Message())
+				return true
+			})
+		case fd.Message() != nil && !fd.IsMap():
+			redactMessage(v.Message())
+		}
+		return true
+	})
+
+	// Mutate after iterating: strings are masked, anything else is dropped
+	for _, fd := range sensitive {
+		if fd.Kind() == protoreflect.StringKind && !fd.IsList() && !fd.IsMap() {
+			m.Set(fd, protoreflect.ValueOfString(redactedValue))
+		} else {
+			m.Clear(fd)
+		}
+	}
+}
+
+// isSensitiveField reports whether a field's name marks it as secret
+func isSensitiveField(fd protoreflect.FieldDescriptor) bool {
+	name := strings.ToLower(string(fd.Name()))
+	for _, marker := range sensitiveFieldMarkers {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// createDatabaseRequest builds a message shaped like admin.v1.CreateDatabaseRequest
+// without depending on the generated code
+func createDatabaseRequest(t *testing.T, password string) proto.Message {
+	t.Helper()
+
+	field := func(name string, number int32, kind descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     kind.Enum(),
+		}
+	}
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("interceptor_test.proto"),
+		Package: proto.String("admin.v1.test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("CreateDatabaseRequest"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				field("username", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				field("password", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				field("type", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("building descriptor: %v", err)
+	}
+
+	desc := file.Messages().ByName("CreateDatabaseRequest")
+	msg := dynamicpb.NewMessage(desc)
+	set := func(name string, value string) {
+		msg.Set(desc.Fields().ByName(protoreflect.Name(name)), protoreflect.ValueOfString(value))
+	}
+	set("name", "orders")
+	set("username", "app")
+	set("password", password)
+	set("type", "postgresql")
+	return msg
+}
+
+func TestLoggingInterceptorNeverLogsPasswords(t *testing.T) {
+	var logs bytes.Buffer
+	saved := log.Writer()
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(saved) })
+
+	const password = "hunter2-very-secret"
+	req := createDatabaseRequest(t, password)
+	info := &grpc.UnaryServerInfo{FullMethod: "/admin.v1.AdminService/CreateDatabase"}
+
+	// The handler echoes the request back, as a response carrying credentials would
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	}
+
+	if _, err := LoggingInterceptor(true)(context.Background(), req, info, handler); err != nil {
+		t.Fatalf("interceptor returned error: %v", err)
+	}
+
+	output := logs.String()
+	if !strings.Contains(output, "CreateDatabase") {
+		t.Fatalf("expected the create call to be logged, got:\n%s", output)
+	}
+	if strings.Contains(output, password) {
+		t.Errorf("password appeared in the log output:\n%s", output)
+	}
+	if !strings.Contains(output, redactedValue) {
+		t.Errorf("expected the password to be logged as %s, got:\n%s", redactedValue, output)
+	}
+
+	// The original request must be left intact for the handler
+	if got := req.ProtoReflect().Get(req.ProtoReflect().Descriptor().Fields().ByName("password")).String(); got != password {
+		t.Errorf("request password was modified to %q", got)
+	}
+}
@@ -5,25 +5,68 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
+	"github.com/BouchamiAhmed/TBD/shared"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"admin-service/internal/config"
 	"admin-service/internal/database" // Add this line
 	"admin-service/internal/k8s"
 	pb "admin-service/pkg/pb"
 )
 
+// Store is the control database the server keeps users, sessions and
+// database records in; *database.DBClient implements it
+type Store interface {
+	AuthenticateUser(username, password string) (*database.User, error)
+	CreateUser(username, email, password, firstName, lastName string) (*database.User, error)
+	CreateSession(token string, userID int, ttl time.Duration) error
+	SessionUserID(token string) (int, error)
+	GetUserByID(id int) (*database.User, error)
+	CreateDatabase(name, dbType, host, port, username, namespace string, userID int, adminURL, adminType string) (*database.Database, error)
+	UserOwnsNamespace(userID int, namespace string) (bool, error)
+	Close() error
+}
+
 type AdminServer struct {
 	pb.UnimplementedAdminServiceServer
 	k8sService *k8s.K8sService
-	dbClient   *database.DBClient // Add this line
+	dbMu       sync.RWMutex // guards dbClient, which may be swapped in after startup
+	dbClient   Store
+
+	configuredAuthMode string        // AUTH_MODE; empty means real
+	tokenTTL           time.Duration // TOKEN_TTL, how long a login's session lasts
+	adminUsernames     []string      // ADMIN_USERNAMES
 }
 
-func NewAdminServer(k8sService *k8s.K8sService, dbClient *database.DBClient) *AdminServer {
-	return &AdminServer{
-		k8sService: k8sService,
-		dbClient:   dbClient, // Add this line
+func NewAdminServer(k8sService *k8s.K8sService, dbClient *database.DBClient, cfg *config.Config) *AdminServer {
+	s := &AdminServer{
+		k8sService:         k8sService,
+		configuredAuthMode: cfg.AuthMode,
+		tokenTTL:           cfg.TokenTTL,
+		adminUsernames:     cfg.AdminUsernames,
+	}
+	if dbClient != nil {
+		s.dbClient = dbClient
+	}
+	return s
+}
+
+// DBClient returns the current database client, or nil if not connected
+func (s *AdminServer) DBClient() Store {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+	return s.dbClient
+}
+
+// SetDBClient swaps in a database client once a connection is established
+func (s *AdminServer) SetDBClient(dbClient *database.DBClient) {
+	s.dbMu.Lock()
+	defer s.dbMu.Unlock()
+	if dbClient != nil {
+		s.dbClient = dbClient
 	}
 }
 
@@ -35,6 +78,11 @@ func (s *AdminServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.Logi
 		return nil, fmt.Errorf("username and password required")
 	}
 
+	if s.authMode() == AuthModeReal {
+		return s.loginWithDatabase(req)
+	}
+	warnMockAuth("Login", req.Username)
+
 	// Mock user data
 	user := &pb.User{
 		Id:        1,
@@ -64,6 +112,11 @@ func (s *AdminServer) Register(ctx context.Context, req *pb.RegisterRequest) (*p
 		return nil, fmt.Errorf("username, email and password required")
 	}
 
+	if s.authMode() == AuthModeReal {
+		return s.registerWithDatabase(req)
+	}
+	warnMockAuth("Register", req.Username)
+
 	// Mock user creation
 	user := &pb.User{
 		Id:        2, // Mock ID
@@ -88,25 +141,41 @@ func (s *AdminServer) Register(ctx context.Context, req *pb.RegisterRequest) (*p
 func (s *AdminServer) CreateDatabase(ctx context.Context, req *pb.CreateDatabaseRequest) (*pb.CreateDatabaseResponse, error) {
 	log.Printf("📞 CreateDatabase request: %s (%s) for user %d", req.Name, req.Type, req.UserId)
 
+	userID, err := s.authorizeUser(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	req.UserId = userID
+
 	if req.Name == "" || req.Type == "" {
 		return nil, fmt.Errorf("database name and type required")
 	}
 
+	// Only canonical types go past here (labels, branching, response)
+	dbType, ok := shared.NormalizeDatabaseType(req.Type)
+	if !ok {
+		return nil, fmt.Errorf("unsupported database type '%s' (supported: %s, %s)", req.Type, shared.DatabaseTypePostgreSQL, shared.DatabaseTypeMySQL)
+	}
+
 	if s.k8sService == nil {
 		return nil, fmt.Errorf("kubernetes service not available")
 	}
 
-	// Mock username from user ID (in real implementation, you'd look this up from database)
-	mockUsername := fmt.Sprintf("user%d", req.UserId)
+	// The namespace is named after the user's username, as the API server names it
+	user, err := s.lookupUser(int(req.UserId))
+	if err != nil {
+		return nil, err
+	}
 
-	// Convert to internal request format
+	// Convert to internal request format; the user's email is the pgAdmin login
 	k8sReq := &k8s.DatabaseRequest{
 		Name:     req.Name,
 		Username: req.Username,
 		Password: req.Password,
-		Type:     req.Type,
+		Type:     dbType,
 		UserID:   int(req.UserId),
-		UserName: mockUsername,
+		UserName: user.Username,
+		Email:    user.Email,
 	}
 
 	// Create database in Kubernetes
@@ -118,6 +187,18 @@ func (s *AdminServer) CreateDatabase(ctx context.Context, req *pb.CreateDatabase
 
 	log.Printf("✅ Database creation initiated: %s", req.Name)
 
+	// Record the database; the deploy already happened, so a failure here is
+	// logged rather than returned
+	if dbClient := s.DBClient(); dbClient != nil {
+		_, err := dbClient.CreateDatabase(dbResp.Name, dbResp.Type, dbResp.Host, dbResp.Port, dbResp.Username,
+			dbResp.Namespace, int(req.UserId), dbResp.AdminURL, dbResp.AdminType)
+		if err != nil {
+			log.Printf("⚠️  Failed to record database %s: %v", dbResp.Name, err)
+		}
+	} else {
+		log.Printf("⚠️  Database not connected, database %s is not recorded", dbResp.Name)
+	}
+
 	// Convert response to protobuf format
 	return &pb.CreateDatabaseResponse{
 		Name:      dbResp.Name,
@@ -137,11 +218,15 @@ func (s *AdminServer) CreateDatabase(ctx context.Context, req *pb.CreateDatabase
 func (s *AdminServer) GetUserDatabases(ctx context.Context, req *pb.GetUserDatabasesRequest) (*pb.GetUserDatabasesResponse, error) {
 	log.Printf("📞 GetUserDatabases request for namespace: %s", req.Namespace)
 
+	if err := s.authorizeNamespace(ctx, req.Namespace); err != nil {
+		return nil, err
+	}
+
 	// Mock database list
 	databases := []*pb.Database{
 		{
 			Name:      "postgres-quick-123",
-			Type:      "postgresql",
+			Type:      shared.DatabaseTypePostgreSQL,
 			Status:    "running",
 			Namespace: req.Namespace,
 			UserId:    "1",
@@ -151,7 +236,7 @@ func (s *AdminServer) GetUserDatabases(ctx context.Context, req *pb.GetUserDatab
 		},
 		{
 			Name:      "mysql-quick-456",
-			Type:      "mysql",
+			Type:      shared.DatabaseTypeMySQL,
 			Status:    "running",
 			Namespace: req.Namespace,
 			UserId:    "1",
@@ -175,7 +260,12 @@ func (s *AdminServer) GetUserDatabases(ctx context.Context, req *pb.GetUserDatab
 func (s *AdminServer) DeleteDatabase(ctx context.Context, req *pb.DeleteDatabaseRequest) (*pb.DeleteDatabaseResponse, error) {
 	log.Printf("📞 DeleteDatabase request: %s from namespace: %s", req.Name, req.Namespace)
 
-	// Mock deletion (always succeeds for now)
+	if err := s.authorizeNamespace(ctx, req.Namespace); err != nil {
+		return nil, err
+	}
+
+	// Mock deletion (always succeeds for now). The database keeps running, so
+	// its record stays until the Kubernetes delete is implemented and succeeds.
 	log.Printf("✅ Database deletion successful: %s", req.Name)
 
 	return &pb.DeleteDatabaseResponse{
@@ -190,6 +280,10 @@ func (s *AdminServer) DeleteDatabase(ctx context.Context, req *pb.DeleteDatabase
 func (s *AdminServer) GetAllNamespaces(ctx context.Context, req *pb.GetAllNamespacesRequest) (*pb.GetAllNamespacesResponse, error) {
 	log.Printf("📞 GetAllNamespaces request")
 
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
 	if s.k8sService == nil {
 		return &pb.GetAllNamespacesResponse{
 			Success: false,
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/BouchamiAhmed/TBD/config"
+)
+
+// redactedValue replaces a secret in the effective configuration and in
+// exported manifests
+const redactedValue = "[redacted]"
+
+// EffectiveConfig is the resolved server configuration reported to admins.
+// Secrets are never included; Secrets only tells whether each one is set.
+type EffectiveConfig struct {
+	Config                 config.Config                  `json:"config"`
+	Secrets                map[string]bool                `json:"secrets"`
+	DatabaseTypes          []string                       `json:"databaseTypes"`
+	DefaultResourceProfile string                         `json:"defaultResourceProfile"`
+	ResourceProfiles       map[string]DatabaseResources   `json:"resourceProfiles"`
+	Environments           map[string]environmentDefaults `json:"environments"`
+}
+
+// effectiveConfig returns the configuration the server is running with.
+// Secret fields are already hidden from JSON by their tags; the webhook URL
+// is redacted too, since chat webhooks embed their token in it.
+func effectiveConfig() EffectiveConfig {
+	resolved := *appConfig
+	if resolved.WebhookURL != "" {
+		resolved.WebhookURL = redactedValue
+	}
+
+	databaseTypes := make([]string, 0, len(defaultPorts))
+	for dbType := range defaultPorts {
+		databaseTypes = append(databaseTypes, dbType)
+	}
+	sort.Strings(databaseTypes)
+
+	return EffectiveConfig{
+		Config: resolved,
+		Secrets: map[string]bool{
+			"dbPassword":    appConfig.DBPassword != "" || appConfig.DBPasswordFile != "",
+			"jwtSecret":     appConfig.JWTSecret != "",
+			"webhookSecret": appConfig.WebhookSecret != "",
+			"webhookUrl":    appConfig.WebhookURL != "",
+		},
+		DatabaseTypes:          databaseTypes,
+		DefaultResourceProfile: defaultResourceProfile,
+		ResourceProfiles:       resourceProfiles,
+		Environments:           environmentPolicies,
+	}
+}
+
+// handleEffectiveConfig serves the effective configuration
+func handleEffectiveConfig(w http.ResponseWriter, r *http.Request) {
+	respondSuccess(w, http.StatusOK, effectiveConfig())
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// adminURL returns the URL of a database's admin dashboard. When
+// ADMIN_URL_TEMPLATE is set it is rendered with the {name}, {namespace},
+// {admintype} and {host} placeholders (e.g. "https://{name}.{host}");
+// otherwise the URL matches the IngressRoutes created by this server.
+// The template only changes the advertised URL: Traefik routing must be
+// configured to match it.
+func adminURL(namespace, dbName, dbType string) string {
+	adminType := adminDashboardName(dbType)
+
+	if appConfig != nil && appConfig.AdminURLTemplate != "" {
+		return strings.NewReplacer(
+			"{name}", dbName,
+			"{namespace}", namespace,
+			"{admintype}", adminType,
+			"{host}", appConfig.PublicHost,
+		).Replace(appConfig.AdminURLTemplate)
+	}
+
+	if dbType == DatabaseTypePostgreSQL {
+		return pgAdminURL(namespace, dbName)
+	}
+	return fmt.Sprintf("%s://%s/%s/%s-%s", adminURLScheme(), publicHost(), namespace, dbName, adminType)
+}
+
+// adminDashboardsEnabled reports whether databases are deployed with a
+// pgAdmin or phpMyAdmin dashboard (ENABLE_ADMIN_DASHBOARDS)
+func adminDashboardsEnabled() bool {
+	return appConfig == nil || appConfig.Features.AdminDashboards
+}
+
+// adminDashboardName returns the lowercase admin dashboard name for a database type
+func adminDashboardName(dbType string) string {
+	if dbType == DatabaseTypeMySQL {
+		return "phpmyadmin"
+	}
+	return "pgadmin"
+}
+
+// publicHost returns the host users reach the cluster's ingress on
+func publicHost() string {
+	if appConfig == nil || appConfig.PublicHost == "" {
+		return "10.9.21.201"
+	}
+	return appConfig.PublicHost
+}
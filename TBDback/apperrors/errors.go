@@ -0,0 +1,61 @@
+// Package apperrors defines the typed errors shared by the database,
+// Kubernetes and HTTP layers, so handlers can choose a status code with
+// errors.Is instead of matching error text.
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel error kinds; wrap them with %w or create them with New
+var (
+	ErrAlreadyExists = errors.New("already exists")
+	ErrNotFound      = errors.New("not found")
+	ErrInvalidInput  = errors.New("invalid input")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrLimitExceeded = errors.New("limit exceeded")
+	ErrConflict      = errors.New("conflict")
+)
+
+// Error is an error of one of the sentinel kinds carrying its own message
+// and optional machine-readable details (e.g. the violated constraint)
+type Error struct {
+	Kind    error
+	Message string
+	Details map[string]string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap lets errors.Is match the error against its kind
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// New returns an error of the given kind with a formatted message
+func New(kind error, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// HTTPStatus maps an error to the status code a handler should respond with,
+// defaulting to 500 for errors of no known kind
+func HTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrAlreadyExists), errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrLimitExceeded):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,11 +1,19 @@
 package main
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"database/sql"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 // AuthUser represents a user with authentication information
@@ -34,39 +42,38 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// UpdateProfileRequest holds the profile fields a user may change; omitted
+// fields are left unchanged
+type UpdateProfileRequest struct {
+	FirstName *string `json:"firstName,omitempty"`
+	LastName  *string `json:"lastName,omitempty"`
+}
+
 // LoginResponse is sent back after successful login
 type LoginResponse struct {
 	User  AuthUser `json:"user"`
 	Token string   `json:"token"`
 }
 
-// Create auth-related tables
-func (c *DBClient) CreateAuthTablesIfNotExist() error {
-	// Create auth_users table if it doesn't exist
-	query := `
-	CREATE TABLE IF NOT EXISTS auth_users (
-		id SERIAL PRIMARY KEY,
-		username VARCHAR(50) NOT NULL UNIQUE,
-		email VARCHAR(100) NOT NULL UNIQUE,
-		first_name VARCHAR(100) NOT NULL,
-		last_name VARCHAR(100) NOT NULL,
-		password_hash VARCHAR(64) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	)`
-
-	_, err := c.db.Exec(query)
+// HashPassword creates a bcrypt hash of the password with the configured cost
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), appConfig.BcryptCost)
 	if err != nil {
-		return fmt.Errorf("error creating auth_users table: %w", err)
+		return "", fmt.Errorf("error hashing password: %w", err)
 	}
-
-	fmt.Println("✅ Authentication tables initialized successfully!")
-	return nil
+	return string(hash), nil
 }
 
-// HashPassword creates a SHA-256 hash of the password
-func HashPassword(password string) string {
-	hash := sha256.Sum256([]byte(password))
-	return hex.EncodeToString(hash[:])
+// checkPassword reports whether password matches a stored hash. Hashes
+// written before bcrypt was adopted are unsalted SHA-256 hex digests; for
+// those, legacy is true so the caller can rehash them.
+func checkPassword(hash, password string) (ok, legacy bool) {
+	if strings.HasPrefix(hash, "$2") {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil, false
+	}
+
+	sum := sha256.Sum256([]byte(password))
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(hex.EncodeToString(sum[:]))) == 1, true
 }
 
 // RegisterUser adds a new user to the database
@@ -74,7 +81,10 @@ func (c *DBClient) RegisterUser(req RegisterRequest) (*AuthUser, error) {
 	fmt.Printf("🔄 Registering new user: %s (%s)\n", req.Username, req.Email)
 
 	// Hash the password
-	passwordHash := HashPassword(req.Password)
+	passwordHash, err := HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
 
 	query := `
 	INSERT INTO auth_users (username, email, first_name, last_name, password_hash)
@@ -82,7 +92,7 @@ func (c *DBClient) RegisterUser(req RegisterRequest) (*AuthUser, error) {
 	RETURNING id, username, email, first_name, last_name, created_at`
 
 	var user AuthUser
-	err := c.db.QueryRow(
+	err = c.db.QueryRow(
 		query,
 		req.Username,
 		req.Email,
@@ -100,6 +110,15 @@ func (c *DBClient) RegisterUser(req RegisterRequest) (*AuthUser, error) {
 
 	if err != nil {
 		fmt.Println("❌ Failed to register user")
+		if conflict := asUniqueViolation(err, "User already exists"); conflict != nil {
+			switch conflict.Details["constraint"] {
+			case "auth_users_username_key":
+				conflict.Message = "Username already exists"
+			case "auth_users_email_key":
+				conflict.Message = "Email already exists"
+			}
+			return nil, conflict
+		}
 		return nil, fmt.Errorf("error registering user: %w", err)
 	}
 
@@ -111,22 +130,21 @@ func (c *DBClient) RegisterUser(req RegisterRequest) (*AuthUser, error) {
 func (c *DBClient) AuthenticateUser(req LoginRequest) (*AuthUser, error) {
 	fmt.Printf("🔄 Authenticating user: %s\n", req.Username)
 
-	// Hash the password for comparison
-	passwordHash := HashPassword(req.Password)
-
 	query := `
-	SELECT id, username, email, first_name, last_name, created_at
+	SELECT id, username, email, first_name, last_name, created_at, password_hash
 	FROM auth_users
-	WHERE username = $1 AND password_hash = $2`
+	WHERE username = $1`
 
 	var user AuthUser
-	err := c.db.QueryRow(query, req.Username, passwordHash).Scan(
+	var passwordHash string
+	err := c.db.QueryRow(query, req.Username).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.FirstName,
 		&user.LastName,
 		&user.CreatedAt,
+		&passwordHash,
 	)
 
 	if err != nil {
@@ -138,14 +156,186 @@ func (c *DBClient) AuthenticateUser(req LoginRequest) (*AuthUser, error) {
 		return nil, fmt.Errorf("error during authentication: %w", err)
 	}
 
+	ok, legacy := checkPassword(passwordHash, req.Password)
+	if !ok {
+		fmt.Println("❌ Authentication failed: Invalid credentials")
+		return nil, nil // Invalid credentials
+	}
+
+	// Upgrade a legacy SHA-256 hash now that the plaintext is known; a
+	// failure here does not fail the login
+	if legacy {
+		if err := c.rehashPassword(user.ID, req.Password); err != nil {
+			fmt.Printf("⚠️  Failed to upgrade password hash for user %s: %v\n", user.Username, err)
+		} else {
+			fmt.Printf("🔐 Upgraded password hash for user %s to bcrypt\n", user.Username)
+		}
+	}
+
 	fmt.Printf("✅ User authenticated successfully: %s (ID: %d)\n", user.Username, user.ID)
 	return &user, nil
 }
 
-// GenerateToken creates a simple token (in a real app, use JWT)
-func GenerateToken(userID int) string {
-	// In a real application, use JWT with proper signing
-	// This is a simplified version for demonstration
-	token := fmt.Sprintf("user_%d_%d", userID, time.Now().Unix())
-	return hex.EncodeToString([]byte(token))
+// rehashPassword replaces a user's stored password hash with a bcrypt hash
+func (c *DBClient) rehashPassword(id int, password string) error {
+	passwordHash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.Exec(`UPDATE auth_users SET password_hash = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`, id, passwordHash)
+	if err != nil {
+		return fmt.Errorf("error updating password hash: %w", err)
+	}
+	return nil
+}
+
+// UpdateUserProfile updates a user's first and/or last name, returning the
+// updated user or nil if no such user exists
+func (c *DBClient) UpdateUserProfile(id int, req UpdateProfileRequest) (*AuthUser, error) {
+	fmt.Printf("🔄 Updating profile of user ID: %d\n", id)
+
+	// COALESCE keeps the current value for fields that were not supplied
+	query := `
+	UPDATE auth_users
+	SET first_name = COALESCE($2, first_name),
+		last_name = COALESCE($3, last_name),
+		updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	RETURNING id, username, email, first_name, last_name, created_at`
+
+	var user AuthUser
+	err := c.db.QueryRow(query, id, req.FirstName, req.LastName).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.FirstName,
+		&user.LastName,
+		&user.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		fmt.Println("❌ Failed to update user profile")
+		return nil, fmt.Errorf("error updating user profile: %w", err)
+	}
+
+	fmt.Printf("✅ Profile updated for user: %s\n", user.Username)
+	return &user, nil
+}
+
+// GetUsername returns a user's username, or "" if no such user exists
+func (c *DBClient) GetUsername(id int) (string, error) {
+	var username string
+	err := c.db.QueryRow(`SELECT username FROM auth_users WHERE id = $1`, id).Scan(&username)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error getting username: %w", err)
+	}
+	return username, nil
+}
+
+// GetUserEmail returns a user's email, or "" if no such user exists
+func (c *DBClient) GetUserEmail(id int) (string, error) {
+	var email string
+	err := c.db.QueryRow(`SELECT email FROM auth_users WHERE id = $1`, id).Scan(&email)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error getting user email: %w", err)
+	}
+	return email, nil
+}
+
+// TokenClaims holds the identity carried by a signed token
+type TokenClaims struct {
+	ID        string `json:"jti"`
+	UserID    int    `json:"sub"`
+	Username  string `json:"username"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// tokenHeader is the fixed JWT header for HS256-signed tokens
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// tokenSecret signs and verifies tokens. It comes from JWT_SECRET, or is
+// generated at startup (tokens then do not survive a restart).
+var tokenSecret []byte
+
+// initTokenSecret sets the signing secret from configuration
+func initTokenSecret(secret string) {
+	if secret != "" {
+		tokenSecret = []byte(secret)
+		return
+	}
+
+	tokenSecret = make([]byte, 32)
+	if _, err := rand.Read(tokenSecret); err != nil {
+		panic(fmt.Sprintf("failed to generate token secret: %v", err))
+	}
+	fmt.Println("⚠️  JWT_SECRET not set, using a random secret (tokens will not survive a restart)")
+}
+
+// GenerateToken creates an HS256-signed JWT identifying the user. Each token
+// carries a random ID, so two tokens issued in the same second still differ.
+func GenerateToken(userID int, username string) string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(fmt.Sprintf("failed to generate token ID: %v", err))
+	}
+
+	now := time.Now()
+	claims := TokenClaims{
+		ID:        hex.EncodeToString(id),
+		UserID:    userID,
+		Username:  username,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(appConfig.TokenTTL).Unix(),
+	}
+
+	payload, _ := json.Marshal(claims)
+	signingInput := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + signToken(signingInput)
+}
+
+// ParseToken verifies a token's signature and expiry and returns its claims
+func ParseToken(token string) (*TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenHeader {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	signingInput := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(signToken(signingInput))) {
+		return nil, fmt.Errorf("invalid token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token payload: %w", err)
+	}
+
+	var claims TokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("malformed token payload: %w", err)
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, fmt.Errorf("token expired")
+	}
+
+	return &claims, nil
+}
+
+// signToken returns the base64url HMAC-SHA256 signature of the signing input
+func signToken(signingInput string) string {
+	mac := hmac.New(sha256.New, tokenSecret)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
 }
@@ -2,26 +2,24 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/BouchamiAhmed/TBD/apperrors"
 	"github.com/gorilla/mux"
 )
 
 // RegisterAuthHandlers adds the authentication routes to the router
 func RegisterAuthHandlers(r *mux.Router, dbClient *DBClient) {
-	// Create auth tables
-	if err := dbClient.CreateAuthTablesIfNotExist(); err != nil {
-		fmt.Printf("Error initializing auth tables: %v\n", err)
-	}
-
 	// Register user
 	r.HandleFunc("/api/auth/register", func(w http.ResponseWriter, r *http.Request) {
 		// Parse request body
 		var registerRequest RegisterRequest
 		if err := json.NewDecoder(r.Body).Decode(&registerRequest); err != nil {
-			fmt.Println("Error parsing registration request:", err)
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			logf(r.Context(), "Error parsing registration request: %v\n", err)
+			respondError(w, http.StatusBadRequest, "Invalid request body")
 			return
 		}
 
@@ -29,45 +27,44 @@ func RegisterAuthHandlers(r *mux.Router, dbClient *DBClient) {
 		if registerRequest.Username == "" || registerRequest.Password == "" ||
 			registerRequest.Email == "" || registerRequest.FirstName == "" ||
 			registerRequest.LastName == "" {
-			http.Error(w, "All fields are required", http.StatusBadRequest)
+			respondError(w, http.StatusBadRequest, "All fields are required")
 			return
 		}
 
 		// Register the user
 		user, err := dbClient.RegisterUser(registerRequest)
 		if err != nil {
-			// Check for duplicate username/email
-			if err.Error() == "error registering user: pq: duplicate key value violates unique constraint \"auth_users_username_key\"" {
-				http.Error(w, "Username already exists", http.StatusConflict)
-				return
-			}
-			if err.Error() == "error registering user: pq: duplicate key value violates unique constraint \"auth_users_email_key\"" {
-				http.Error(w, "Email already exists", http.StatusConflict)
+			// Duplicate username/email
+			if errors.Is(err, apperrors.ErrAlreadyExists) {
+				respondError(w, http.StatusConflict, err.Error())
 				return
 			}
 
-			fmt.Printf("Error registering user: %v\n", err)
-			http.Error(w, "Failed to register user", http.StatusInternalServerError)
+			logf(r.Context(), "Error registering user: %v\n", err)
+			respondError(w, http.StatusInternalServerError, "Failed to register user")
 			return
 		}
 
 		// Create Kubernetes namespace for the new user
-		fmt.Printf("🔄 Creating Kubernetes namespace for user %s (ID: %d)\n", user.Username, user.ID)
+		logf(r.Context(), "🔄 Creating Kubernetes namespace for user %s (ID: %d)\n", user.Username, user.ID)
 		if err := CreateNamespaceForUser(user.ID, user.Username); err != nil {
-			fmt.Printf("⚠️  Warning: Failed to create namespace for user %s: %v\n", user.Username, err)
+			logf(r.Context(), "⚠️  Warning: Failed to create namespace for user %s: %v\n", user.Username, err)
 			// Note: We don't fail the registration if namespace creation fails
 			// The user can still be registered, but they won't have their own namespace
 		} else {
-			fmt.Printf("✅ Namespace created successfully for user %s\n", user.Username)
+			logf(r.Context(), "✅ Namespace created successfully for user %s\n", user.Username)
 		}
 
 		// Generate token for the new user
-		token := GenerateToken(user.ID)
+		token, err := issueToken(dbClient, user)
+		if err != nil {
+			logf(r.Context(), "Error creating session: %v\n", err)
+			respondError(w, http.StatusInternalServerError, "Failed to create session")
+			return
+		}
 
 		// Send success response
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(LoginResponse{
+		respondSuccess(w, http.StatusCreated, LoginResponse{
 			User:  *user,
 			Token: token,
 		})
@@ -78,41 +75,95 @@ func RegisterAuthHandlers(r *mux.Router, dbClient *DBClient) {
 		// Parse request body
 		var loginRequest LoginRequest
 		if err := json.NewDecoder(r.Body).Decode(&loginRequest); err != nil {
-			fmt.Println("Error parsing login request:", err)
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			logf(r.Context(), "Error parsing login request: %v\n", err)
+			respondError(w, http.StatusBadRequest, "Invalid request body")
 			return
 		}
 
 		// Validate request
 		if loginRequest.Username == "" || loginRequest.Password == "" {
-			http.Error(w, "Username and password are required", http.StatusBadRequest)
+			respondError(w, http.StatusBadRequest, "Username and password are required")
 			return
 		}
 
 		// Authenticate the user
 		user, err := dbClient.AuthenticateUser(loginRequest)
 		if err != nil {
-			fmt.Printf("Error during authentication: %v\n", err)
-			http.Error(w, "Authentication error", http.StatusInternalServerError)
+			logf(r.Context(), "Error during authentication: %v\n", err)
+			respondError(w, http.StatusInternalServerError, "Authentication error")
 			return
 		}
 
 		if user == nil {
 			// Invalid credentials
-			http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+			logf(r.Context(), "🔒 Failed login for user '%s' from %s\n", loginRequest.Username, clientIP(r))
+			respondError(w, http.StatusUnauthorized, "Invalid username or password")
 			return
 		}
 
 		// Generate token
-		token := GenerateToken(user.ID)
+		token, err := issueToken(dbClient, user)
+		if err != nil {
+			logf(r.Context(), "Error creating session: %v\n", err)
+			respondError(w, http.StatusInternalServerError, "Failed to create session")
+			return
+		}
 
 		// Send success response
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(LoginResponse{
+		respondSuccess(w, http.StatusOK, LoginResponse{
 			User:  *user,
 			Token: token,
 		})
 	}).Methods("POST")
 
+	// List the authenticated user's active sessions
+	r.HandleFunc("/api/auth/sessions", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		claims := authFromContext(r.Context())
+
+		sessions, err := dbClient.ListActiveSessions(claims.UserID)
+		if err != nil {
+			logf(r.Context(), "Error listing sessions: %v\n", err)
+			respondError(w, http.StatusInternalServerError, "Failed to list sessions")
+			return
+		}
+
+		currentID := sessionIDFromContext(r.Context())
+		for i := range sessions {
+			sessions[i].Current = sessions[i].ID == currentID
+		}
+
+		respondSuccess(w, http.StatusOK, map[string]interface{}{
+			"sessions": sessions,
+			"count":    len(sessions),
+		})
+	})).Methods("GET")
+
+	// Revoke one of the authenticated user's sessions
+	r.HandleFunc("/api/auth/sessions/{id}", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		claims := authFromContext(r.Context())
+		revoked, err := dbClient.RevokeSession(id, claims.UserID)
+		if err != nil {
+			logf(r.Context(), "Error revoking session: %v\n", err)
+			respondError(w, http.StatusInternalServerError, "Failed to revoke session")
+			return
+		}
+		if !revoked {
+			respondError(w, http.StatusNotFound, "Session not found")
+			return
+		}
+
+		logf(r.Context(), "🔒 Revoked session %d for user %s\n", id, claims.Username)
+		respondSuccess(w, http.StatusOK, map[string]interface{}{
+			"message": fmt.Sprintf("Session %d revoked", id),
+			"id":      id,
+		})
+	})).Methods("DELETE")
+
 	fmt.Println("Authentication endpoints registered at /api/auth")
 }
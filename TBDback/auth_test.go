@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestGenerateTokenIsUniquePerCall(t *testing.T) {
+	useTestConfig(t)
+	saved := tokenSecret
+	t.Cleanup(func() { tokenSecret = saved })
+	initTokenSecret("test-secret")
+
+	first := GenerateToken(1, "alice")
+	second := GenerateToken(1, "alice")
+	if first == second {
+		t.Fatal("two tokens issued to the same user in the same second are identical")
+	}
+
+	claims, err := ParseToken(first)
+	if err != nil {
+		t.Fatalf("ParseToken returned error: %v", err)
+	}
+	if len(claims.ID) != 32 {
+		t.Errorf("jti = %q, want 32 hex characters", claims.ID)
+	}
+}
+
+func TestHashTokenIsSHA256(t *testing.T) {
+	sum := sha256.Sum256([]byte("a.b.c"))
+	if got, want := hashToken("a.b.c"), hex.EncodeToString(sum[:]); got != want {
+		t.Errorf("hashToken = %s, want %s", got, want)
+	}
+}
+
+func TestHashPasswordUsesBcrypt(t *testing.T) {
+	cfg := useTestConfig(t)
+	cfg.BcryptCost = bcrypt.MinCost
+
+	hash, err := HashPassword("s3cret-pass")
+	if err != nil {
+		t.Fatalf("HashPassword returned error: %v", err)
+	}
+	if cost, err := bcrypt.Cost([]byte(hash)); err != nil || cost != bcrypt.MinCost {
+		t.Errorf("bcrypt cost = %d (error %v), want %d", cost, err, bcrypt.MinCost)
+	}
+
+	if ok, legacy := checkPassword(hash, "s3cret-pass"); !ok || legacy {
+		t.Errorf("checkPassword(bcrypt, correct) = %v, legacy %v; want true, false", ok, legacy)
+	}
+	if ok, _ := checkPassword(hash, "wrong"); ok {
+		t.Error("checkPassword accepted a wrong password")
+	}
+}
+
+func TestCheckPasswordLegacySHA256(t *testing.T) {
+	sum := sha256.Sum256([]byte("old-password"))
+	legacyHash := hex.EncodeToString(sum[:])
+
+	if ok, legacy := checkPassword(legacyHash, "old-password"); !ok || !legacy {
+		t.Errorf("checkPassword(sha256, correct) = %v, legacy %v; want true, true", ok, legacy)
+	}
+	if ok, _ := checkPassword(legacyHash, "wrong"); ok {
+		t.Error("checkPassword accepted a wrong password against a legacy hash")
+	}
+}
@@ -0,0 +1,493 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BouchamiAhmed/TBD/apperrors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Backups are written to a per-database PersistentVolumeClaim mounted here
+const (
+	backupVolumeName = "backups"
+	backupMountPath  = "/backups"
+)
+
+// defaultBackupRetention is how many backups are kept when a schedule names no retention
+const defaultBackupRetention = 7
+
+// maxBackupRetention bounds how many backups a schedule may keep
+const maxBackupRetention = 90
+
+// backupScheduleNone opts a database out of the backup schedule its environment would give it
+const backupScheduleNone = "none"
+
+// BackupScheduleRequest sets a database's backup schedule
+type BackupScheduleRequest struct {
+	Schedule  string `json:"schedule"`            // cron expression, e.g. "0 2 * * *"
+	Retention int    `json:"retention,omitempty"` // backups kept; defaults to defaultBackupRetention
+}
+
+// backupPVCName returns the name of the PersistentVolumeClaim holding a database's backups
+func backupPVCName(dbName string) string {
+	return dbName + "-backups"
+}
+
+// backupCronJobName returns the name of a database's backup CronJob
+func backupCronJobName(dbName string) string {
+	return dbName + "-backup"
+}
+
+// cronFieldRanges are the bounds of the five fields of a cron expression:
+// minute, hour, day of month, month and day of week (0 and 7 are Sunday)
+var cronFieldRanges = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// cronMacros are the shorthand schedules the CronJob controller accepts
+var cronMacros = map[string]bool{
+	"@yearly": true, "@annually": true, "@monthly": true, "@weekly": true,
+	"@daily": true, "@midnight": true, "@hourly": true,
+}
+
+// validateCronSchedule checks a standard five-field cron expression (numeric
+// values, with *, ranges, lists and steps) or one of the @ macros, so a bad
+// schedule is rejected up front rather than by the CronJob controller
+func validateCronSchedule(schedule string) error {
+	schedule = strings.TrimSpace(schedule)
+	if schedule == "" {
+		return apperrors.New(apperrors.ErrInvalidInput, "backup schedule is required")
+	}
+	if strings.HasPrefix(schedule, "@") {
+		if !cronMacros[schedule] {
+			return apperrors.New(apperrors.ErrInvalidInput, "unknown cron macro '%s'", schedule)
+		}
+		return nil
+	}
+
+	fields := strings.Fields(schedule)
+	if len(fields) != len(cronFieldRanges) {
+		return apperrors.New(apperrors.ErrInvalidInput, "cron expression '%s' must have %d fields, got %d", schedule, len(cronFieldRanges), len(fields))
+	}
+	for i, field := range fields {
+		bounds := cronFieldRanges[i]
+		for _, item := range strings.Split(field, ",") {
+			if err := validateCronItem(item, bounds.min, bounds.max); err != nil {
+				return apperrors.New(apperrors.ErrInvalidInput, "invalid %s '%s' in cron expression: %v", bounds.name, item, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateCronItem checks one list item of a cron field: *, a value, or a
+// range, each optionally followed by /step
+func validateCronItem(item string, min, max int) error {
+	base, step, hasStep := strings.Cut(item, "/")
+	if hasStep {
+		n, err := strconv.Atoi(step)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("step must be a positive integer")
+		}
+	}
+	if base == "*" {
+		return nil
+	}
+
+	low, high, isRange := strings.Cut(base, "-")
+	start, err := strconv.Atoi(low)
+	if err != nil || start < min || start > max {
+		return fmt.Errorf("value must be between %d and %d", min, max)
+	}
+	if !isRange {
+		return nil
+	}
+	end, err := strconv.Atoi(high)
+	if err != nil || end < min || end > max {
+		return fmt.Errorf("value must be between %d and %d", min, max)
+	}
+	if end < start {
+		return fmt.Errorf("range end is before its start")
+	}
+	return nil
+}
+
+// backupCommand returns the shell script dumping a database into the backup
+// volume and pruning all but the newest $RETENTION backups. Dumps are written
+// to a temporary file first, so a failed run never leaves a partial backup.
+// Credentials come from the environment copied from the database container.
+func backupCommand(dbType string) ([]string, error) {
+	var dump, extension string
+	switch dbType {
+	case DatabaseTypePostgreSQL:
+		dump = `PGPASSWORD="$POSTGRES_PASSWORD" pg_dump -h "$DB_HOST" -U "$POSTGRES_USER" -Fc -f "$file.tmp" "$POSTGRES_DB"`
+		extension = ".dump"
+	case DatabaseTypeMySQL:
+		dump = `MYSQL_PWD="$MYSQL_PASSWORD" mysqldump -h "$DB_HOST" -u "$MYSQL_USER" --single-transaction --no-tablespaces "$MYSQL_DATABASE" > "$file.tmp"`
+		extension = ".sql"
+	default:
+		return nil, apperrors.New(apperrors.ErrInvalidInput, "backups are not supported for database type %s", dbType)
+	}
+
+	script := `set -e
+file="` + backupMountPath + `/$(date -u +%Y%m%d-%H%M%S)` + extension + `"
+` + dump + `
+mv "$file.tmp" "$file"
+echo "Backup written to $file"
+ls -1t ` + backupMountPath + `/*` + extension + ` | tail -n +$((RETENTION + 1)) | while read -r old; do rm -f "$old"; echo "Pruned $old"; done
+`
+	return []string{"sh", "-c", script}, nil
+}
+
+// backupCredentialEnv lists, per database type, the database container's
+// environment variables the backup script needs
+var backupCredentialEnv = map[string][]string{
+	DatabaseTypePostgreSQL: {"POSTGRES_USER", "POSTGRES_PASSWORD", "POSTGRES_DB"},
+	DatabaseTypeMySQL:      {"MYSQL_USER", "MYSQL_PASSWORD", "MYSQL_DATABASE"},
+}
+
+// ensureBackupPVC creates the PersistentVolumeClaim holding a database's
+// backups unless it already exists
+func ensureBackupPVC(ctx context.Context, clientset kubernetes.Interface, deployment *appsv1.Deployment) error {
+	size, err := resource.ParseQuantity(appConfig.BackupStorageSize)
+	if err != nil {
+		return fmt.Errorf("invalid BACKUP_STORAGE_SIZE %q: %w", appConfig.BackupStorageSize, err)
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backupPVCName(deployment.Name),
+			Namespace: deployment.Namespace,
+			Labels: withDeploymentLabels(map[string]string{
+				"app":                         deployment.Name,
+				"app.kubernetes.io/component": "backup",
+			}, deployment),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+		},
+	}
+	if appConfig.PVCStorageClass != "" {
+		pvc.Spec.StorageClassName = &appConfig.PVCStorageClass
+	}
+
+	_, err = clientset.CoreV1().PersistentVolumeClaims(deployment.Namespace).Create(ctx, pvc, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create backup PersistentVolumeClaim: %w", err)
+	}
+	logf(ctx, "✅ Created backup PersistentVolumeClaim: %s\n", pvc.Name)
+	return nil
+}
+
+// newBackupCronJob builds the CronJob backing up a database. It runs the
+// database's own image, so the dump client matches the server version, and
+// reuses the credentials from the database container.
+func newBackupCronJob(deployment *appsv1.Deployment, dbType, schedule string, retention int) (*batchv1.CronJob, error) {
+	command, err := backupCommand(dbType)
+	if err != nil {
+		return nil, err
+	}
+
+	database := deployment.Spec.Template.Spec.Containers[0]
+	env := []corev1.EnvVar{
+		{Name: "DB_HOST", Value: deployment.Name},
+		{Name: "RETENTION", Value: strconv.Itoa(retention)},
+	}
+	for _, name := range backupCredentialEnv[dbType] {
+		for _, envVar := range database.Env {
+			if envVar.Name == name {
+				env = append(env, envVar)
+				break
+			}
+		}
+	}
+
+	labels := withDeploymentLabels(map[string]string{
+		"app":                         deployment.Name,
+		"app.kubernetes.io/component": "backup",
+	}, deployment)
+	successfulJobs, failedJobs, backoffLimit := int32(3), int32(1), int32(2)
+
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backupCronJobName(deployment.Name),
+			Namespace: deployment.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                   schedule,
+			ConcurrencyPolicy:          batchv1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: &successfulJobs,
+			FailedJobsHistoryLimit:     &failedJobs,
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec: corev1.PodSpec{
+							RestartPolicy:    corev1.RestartPolicyOnFailure,
+							ImagePullSecrets: deployment.Spec.Template.Spec.ImagePullSecrets,
+							Containers: []corev1.Container{
+								{
+									Name:            "backup",
+									Image:           database.Image,
+									ImagePullPolicy: database.ImagePullPolicy,
+									Command:         command,
+									Env:             env,
+									VolumeMounts: []corev1.VolumeMount{
+										{Name: backupVolumeName, MountPath: backupMountPath},
+									},
+								},
+							},
+							Volumes: []corev1.Volume{
+								{
+									Name: backupVolumeName,
+									VolumeSource: corev1.VolumeSource{
+										PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: backupPVCName(deployment.Name)},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// applyBackupSchedule creates or updates a database's backup CronJob, and the
+// volume its backups go to. The schedule must have passed validateCronSchedule.
+func applyBackupSchedule(ctx context.Context, clientset kubernetes.Interface, deployment *appsv1.Deployment, dbType, schedule string, retention int) error {
+	if retention == 0 {
+		retention = defaultBackupRetention
+	}
+
+	cronJob, err := newBackupCronJob(deployment, dbType, schedule, retention)
+	if err != nil {
+		return err
+	}
+	if err := ensureBackupPVC(ctx, clientset, deployment); err != nil {
+		return err
+	}
+
+	cronJobs := clientset.BatchV1().CronJobs(deployment.Namespace)
+	existing, err := cronJobs.Get(ctx, cronJob.Name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		if _, err := cronJobs.Create(ctx, cronJob, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create backup CronJob: %w", err)
+		}
+		logf(ctx, "✅ Created backup CronJob %s (schedule %q, keeping %d)\n", cronJob.Name, schedule, retention)
+	case err != nil:
+		return fmt.Errorf("failed to get backup CronJob: %w", err)
+	default:
+		existing.Spec = cronJob.Spec
+		if _, err := cronJobs.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update backup CronJob: %w", err)
+		}
+		logf(ctx, "✅ Updated backup CronJob %s (schedule %q, keeping %d)\n", cronJob.Name, schedule, retention)
+	}
+	return nil
+}
+
+// setBackupSchedule applies a backup schedule to an existing database
+func setBackupSchedule(ctx context.Context, dbName, namespace, schedule string, retention int) error {
+	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, dbName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return apperrors.New(apperrors.ErrNotFound, "database '%s' not found in namespace '%s'", dbName, namespace)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get database deployment: %w", err)
+	}
+	return applyBackupSchedule(ctx, clientset, deployment, labeledDatabaseType(deployment.Labels), schedule, retention)
+}
+
+// createBackupCronJob schedules backups for a new database when its request
+// (or environment) asks for them
+func createBackupCronJob(ctx context.Context, clientset kubernetes.Interface, dbRequest DatabaseRequest, deployment *appsv1.Deployment) error {
+	if dbRequest.BackupSchedule == "" || dbRequest.BackupSchedule == backupScheduleNone {
+		return nil
+	}
+	return applyBackupSchedule(ctx, clientset, deployment, dbRequest.Type, dbRequest.BackupSchedule, defaultBackupRetention)
+}
+
+// deleteBackupCronJob removes a database's backup CronJob and its jobs if it
+// exists. The backup volume is kept so a deleted database can still be
+// restored; it goes away with the namespace.
+func deleteBackupCronJob(ctx context.Context, dbName, namespace string) {
+	propagation := metav1.DeletePropagationBackground
+	err := clientset.BatchV1().CronJobs(namespace).Delete(ctx, backupCronJobName(dbName), metav1.DeleteOptions{PropagationPolicy: &propagation})
+	switch {
+	case err == nil:
+		logf(ctx, "✅ Deleted backup CronJob\n")
+	case !errors.IsNotFound(err):
+		logf(ctx, "Warning: Failed to delete backup CronJob: %v\n", err)
+	}
+}
+
+// backupListImage runs the short Job listing a database's backups
+const backupListImage = "busybox:1.36"
+
+// backupListTimeout bounds how long listing backups waits for its Job
+const backupListTimeout = time.Minute
+
+// BackupInfo describes one backup artifact on a database's backup volume
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// listBackups returns the backups on a database's backup volume, newest
+// first. A volume can only be read from a pod, so a short Job lists it and
+// its log is parsed; the Job is deleted afterwards. A database that never had
+// a backup schedule has no volume and no backups.
+func listBackups(ctx context.Context, dbName, namespace string) ([]BackupInfo, error) {
+	_, err := clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, backupPVCName(dbName), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return []BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup PersistentVolumeClaim: %w", err)
+	}
+
+	backoffLimit, ttl := int32(0), int32(300)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: backupCronJobName(dbName) + "-list-",
+			Namespace:    namespace,
+			Labels: map[string]string{
+				"app":                          dbName,
+				"app.kubernetes.io/component":  "backup",
+				"app.kubernetes.io/managed-by": "db-saas",
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttl,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:  "list",
+							Image: backupListImage,
+							// One "name size mtime" line per backup
+							Command: []string{"sh", "-c", `for f in ` + backupMountPath + `/*.dump ` + backupMountPath + `/*.sql; do [ -f "$f" ] && stat -c '%n %s %Y' "$f"; done; true`},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: backupVolumeName, MountPath: backupMountPath, ReadOnly: true},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: backupVolumeName,
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: backupPVCName(dbName), ReadOnly: true},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	jobs := clientset.BatchV1().Jobs(namespace)
+	created, err := jobs.Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup listing Job: %w", err)
+	}
+	defer func() {
+		propagation := metav1.DeletePropagationBackground
+		err := jobs.Delete(context.WithoutCancel(ctx), created.Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !errors.IsNotFound(err) {
+			logf(ctx, "Warning: Failed to delete backup listing Job %s: %v\n", created.Name, err)
+		}
+	}()
+
+	err = wait.PollUntilContextTimeout(ctx, time.Second, backupListTimeout, true, func(ctx context.Context) (bool, error) {
+		current, err := jobs.Get(ctx, created.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if current.Status.Failed > 0 {
+			return false, fmt.Errorf("backup listing Job failed")
+		}
+		return current.Status.Succeeded > 0, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "job-name=" + created.Name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find backup listing pod: %w", err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, fmt.Errorf("backup listing Job %s has no pod", created.Name)
+	}
+	output, err := clientset.CoreV1().Pods(namespace).GetLogs(pods.Items[0].Name, &corev1.PodLogOptions{}).Do(ctx).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup listing: %w", err)
+	}
+
+	return parseBackupListing(string(output)), nil
+}
+
+// parseBackupListing parses the "path size mtime" lines written by the
+// listing Job, newest first. Malformed lines are skipped.
+func parseBackupListing(output string) []BackupInfo {
+	backups := []BackupInfo{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		modified, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{
+			Name:      path.Base(fields[0]),
+			Size:      size,
+			CreatedAt: time.Unix(modified, 0).UTC(),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// trustedProxies are the networks (e.g. Traefik's pod CIDR) whose
+// X-Forwarded-For and X-Real-IP headers are believed; set by loadTrustedProxies
+var trustedProxies []*net.IPNet
+
+// loadTrustedProxies parses the TRUSTED_PROXIES entries, which may be CIDRs
+// or single IPs, skipping invalid ones with a warning
+func loadTrustedProxies(entries []string) {
+	trustedProxies = nil
+	for _, entry := range entries {
+		cidr := entry
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Printf("Warning: Ignoring invalid TRUSTED_PROXIES entry %q: %v", entry, err)
+			continue
+		}
+		trustedProxies = append(trustedProxies, network)
+	}
+	if len(trustedProxies) > 0 {
+		log.Printf("🛡️  Trusting X-Forwarded-For from %d proxy network(s)", len(trustedProxies))
+	}
+}
+
+// isTrustedProxy reports whether ip belongs to a trusted proxy network
+func isTrustedProxy(ip net.IP) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the IP of the client that made the request. Forwarding
+// headers are only honored when the direct peer is a trusted proxy, so
+// clients cannot spoof their address. X-Forwarded-For is walked from the
+// right, skipping trusted hops, and the first untrusted address wins;
+// X-Real-IP is used when X-Forwarded-For is absent.
+func clientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	remoteIP := net.ParseIP(remote)
+	if remoteIP == nil || !isTrustedProxy(remoteIP) {
+		return remote
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		client := remote
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			client = ip.String()
+			if !isTrustedProxy(ip) {
+				break
+			}
+		}
+		return client
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return remote
+}
@@ -0,0 +1,283 @@
+package config
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Features holds the feature flags read from the environment
+type Features struct {
+	TLS                 bool `json:"tls"`                 // ENABLE_TLS (on by default once TRAEFIK_TLS_SECRET or TRAEFIK_CERT_RESOLVER is set)
+	PVC                 bool `json:"pvc"`                 // ENABLE_PVC
+	Metrics             bool `json:"metrics"`             // ENABLE_METRICS (allows enableMetrics on create requests)
+	NetworkPolicy       bool `json:"networkPolicy"`       // ENABLE_NETWORK_POLICY
+	AdminDashboards     bool `json:"adminDashboards"`     // ENABLE_ADMIN_DASHBOARDS (pgAdmin and phpMyAdmin)
+	PodSecurity         bool `json:"podSecurity"`         // ENABLE_POD_SECURITY
+	AdminColocation     bool `json:"adminColocation"`     // ENABLE_ADMIN_COLOCATION
+	Pprof               bool `json:"pprof"`               // ENABLE_PPROF
+	PodDisruptionBudget bool `json:"podDisruptionBudget"` // ENABLE_POD_DISRUPTION_BUDGET
+}
+
+// DBPool holds the connection pool settings for the control database
+type DBPool struct {
+	MaxOpenConns    int           `json:"maxOpenConns"`    // DB_MAX_OPEN_CONNS
+	MaxIdleConns    int           `json:"maxIdleConns"`    // DB_MAX_IDLE_CONNS
+	ConnMaxLifetime time.Duration `json:"connMaxLifetime"` // DB_CONN_MAX_LIFETIME
+	ConnMaxIdleTime time.Duration `json:"connMaxIdleTime"` // DB_CONN_MAX_IDLE_TIME
+	PingInterval    time.Duration `json:"pingInterval"`    // DB_PING_INTERVAL (0 disables the keep-alive ping)
+}
+
+// Probes holds the readiness and liveness probe settings of database containers
+type Probes struct {
+	Type                  string        `json:"type"`                  // PROBE_TYPE ("exec", "tcp" or "off")
+	ReadinessInitialDelay time.Duration `json:"readinessInitialDelay"` // PROBE_READINESS_INITIAL_DELAY
+	LivenessInitialDelay  time.Duration `json:"livenessInitialDelay"`  // PROBE_LIVENESS_INITIAL_DELAY (raise for slow storage classes)
+	Period                time.Duration `json:"period"`                // PROBE_PERIOD
+	Timeout               time.Duration `json:"timeout"`               // PROBE_TIMEOUT
+	FailureThreshold      int           `json:"failureThreshold"`      // PROBE_FAILURE_THRESHOLD
+}
+
+// HTTPServer holds the HTTP server listen address and timeouts
+type HTTPServer struct {
+	Addr         string        `json:"addr"`         // HTTP_ADDR, or HTTP_HOST and HTTP_PORT
+	ReadTimeout  time.Duration `json:"readTimeout"`  // HTTP_READ_TIMEOUT
+	WriteTimeout time.Duration `json:"writeTimeout"` // HTTP_WRITE_TIMEOUT
+	IdleTimeout  time.Duration `json:"idleTimeout"`  // HTTP_IDLE_TIMEOUT
+	// RequestTimeout bounds every request's context, whatever the handler does
+	RequestTimeout time.Duration `json:"requestTimeout"` // HTTP_REQUEST_TIMEOUT (0 disables)
+	StreamingPaths []string      `json:"streamingPaths"` // HTTP_STREAMING_PATHS (path prefixes exempt from HTTP_REQUEST_TIMEOUT)
+}
+
+// Config holds the API server configuration, read once at startup
+type Config struct {
+	DBHost                  string            `json:"dbHost"`                  // DB_HOST
+	DBPassword              string            `json:"-"`                       // DB_PASSWORD
+	DBPasswordFile          string            `json:"dbPasswordFile"`          // DB_PASSWORD_FILE (takes precedence over DB_PASSWORD)
+	DBPort                  string            `json:"dbPort"`                  // DB_PORT (port reported for PostgreSQL databases)
+	Kubeconfig              string            `json:"kubeconfig"`              // KUBECONFIG
+	KubernetesServiceHost   string            `json:"kubernetesServiceHost"`   // KUBERNETES_SERVICE_HOST
+	JWTSecret               string            `json:"-"`                       // JWT_SECRET
+	TokenTTL                time.Duration     `json:"tokenTtl"`                // TOKEN_TTL
+	BcryptCost              int               `json:"bcryptCost"`              // BCRYPT_COST (work factor for password hashes, clamped to 4-31)
+	PublicHost              string            `json:"publicHost"`              // PUBLIC_HOST
+	CORSAllowedOrigins      []string          `json:"corsAllowedOrigins"`      // CORS_ALLOWED_ORIGINS (default "*")
+	AdminURLTemplate        string            `json:"adminUrlTemplate"`        // ADMIN_URL_TEMPLATE ({name}, {namespace}, {admintype}, {host})
+	TraefikMatcherVersion   string            `json:"traefikMatcherVersion"`   // TRAEFIK_MATCHER_VERSION ("v2" or "v3")
+	TraefikNamespace        string            `json:"traefikNamespace"`        // TRAEFIK_NAMESPACE (IngressRoutes and Middlewares go here; the database's namespace when empty)
+	TraefikTLSSecret        string            `json:"traefikTlsSecret"`        // TRAEFIK_TLS_SECRET (certificate Secret in the IngressRoutes' namespace, e.g. a wildcard cert)
+	TraefikCertResolver     string            `json:"traefikCertResolver"`     // TRAEFIK_CERT_RESOLVER (ACME resolver configured in Traefik)
+	TraefikTLSEntryPoint    string            `json:"traefikTlsEntryPoint"`    // TRAEFIK_TLS_ENTRYPOINT (used instead of "web" when ENABLE_TLS is on)
+	PgAdminRouting          string            `json:"pgAdminRouting"`          // PGADMIN_ROUTING ("path-prefix" or "host")
+	PgAdminHostDomain       string            `json:"pgAdminHostDomain"`       // PGADMIN_HOST_DOMAIN
+	PgAdminEmailDomain      string            `json:"pgAdminEmailDomain"`      // PGADMIN_EMAIL_DOMAIN
+	ReconcileInterval       time.Duration     `json:"reconcileInterval"`       // RECONCILE_INTERVAL (0 disables)
+	PhaseWatchInterval      time.Duration     `json:"phaseWatchInterval"`      // PHASE_WATCH_INTERVAL (phase transitions and record statuses; 0 disables)
+	WaitForDB               bool              `json:"waitForDb"`               // WAIT_FOR_DB (create admin dashboards once the database is ready)
+	WaitForDBTimeout        time.Duration     `json:"waitForDbTimeout"`        // WAIT_FOR_DB_TIMEOUT (keep below HTTP_WRITE_TIMEOUT)
+	WebhookURL              string            `json:"webhookUrl"`              // WEBHOOK_URL (status changes are POSTed here; needs PHASE_WATCH_INTERVAL)
+	WebhookSecret           string            `json:"-"`                       // WEBHOOK_SECRET (HMAC-SHA256 signing key)
+	RequireDeleteConfirm    bool              `json:"requireDeleteConfirm"`    // REQUIRE_DELETE_CONFIRM
+	IdempotencyKeyTTL       time.Duration     `json:"idempotencyKeyTtl"`       // IDEMPOTENCY_KEY_TTL (how long Idempotency-Key responses are replayed)
+	MaxDatabasesPerUser     int               `json:"maxDatabasesPerUser"`     // MAX_DATABASES_PER_USER (0 means unlimited)
+	AdminUsernames          []string          `json:"adminUsernames"`          // ADMIN_USERNAMES
+	DefaultDeployNamespace  string            `json:"defaultDeployNamespace"`  // DEFAULT_DEPLOY_NAMESPACE (YAML deploys without user info)
+	DeployNamespaces        []string          `json:"deployNamespaces"`        // DEPLOY_NAMESPACE_ALLOWLIST (unmanaged namespaces YAML may be deployed to)
+	ImagePullPolicy         string            `json:"imagePullPolicy"`         // IMAGE_PULL_POLICY
+	ImagePullSecret         string            `json:"imagePullSecret"`         // IMAGE_PULL_SECRET
+	NamespacePrefix         string            `json:"namespacePrefix"`         // NAMESPACE_PREFIX (e.g. "tenant-a-")
+	NamespaceLabels         map[string]string `json:"namespaceLabels"`         // NAMESPACE_LABELS
+	NamespaceAnnotations    map[string]string `json:"namespaceAnnotations"`    // NAMESPACE_ANNOTATIONS
+	PprofAddr               string            `json:"pprofAddr"`               // PPROF_ADDR
+	PVCStorageSize          string            `json:"pvcStorageSize"`          // PVC_STORAGE_SIZE (used with ENABLE_PVC)
+	PVCStorageClass         string            `json:"pvcStorageClass"`         // PVC_STORAGE_CLASS (cluster default when empty)
+	BackupStorageSize       string            `json:"backupStorageSize"`       // BACKUP_STORAGE_SIZE (volume scheduled backups are written to)
+	NetworkPolicyNamespaces []string          `json:"networkPolicyNamespaces"` // NETWORK_POLICY_ALLOWED_NAMESPACES (namespaces besides TRAEFIK_NAMESPACE allowed through ENABLE_NETWORK_POLICY, e.g. monitoring)
+	TrustedProxies          []string          `json:"trustedProxies"`          // TRUSTED_PROXIES (CIDRs or IPs whose X-Forwarded-For/X-Real-IP are honored)
+	DBPool                  DBPool            `json:"dbPool"`
+	HTTPServer              HTTPServer        `json:"httpServer"`
+	Probes                  Probes            `json:"probes"`
+	Features                Features          `json:"features"`
+}
+
+// Load reads the configuration from environment variables, applying defaults
+func Load() *Config {
+	return &Config{
+		DBHost:                  getEnv("DB_HOST", "10.9.21.201"),
+		DBPassword:              getEnv("DB_PASSWORD", "postgres"),
+		DBPasswordFile:          os.Getenv("DB_PASSWORD_FILE"),
+		DBPort:                  getEnv("DB_PORT", "5432"),
+		Kubeconfig:              os.Getenv("KUBECONFIG"),
+		KubernetesServiceHost:   os.Getenv("KUBERNETES_SERVICE_HOST"),
+		JWTSecret:               os.Getenv("JWT_SECRET"),
+		TokenTTL:                getEnvDuration("TOKEN_TTL", 24*time.Hour),
+		BcryptCost:              getBcryptCost("BCRYPT_COST"),
+		PublicHost:              getEnv("PUBLIC_HOST", "10.9.21.201"),
+		CORSAllowedOrigins:      getEnvListDefault("CORS_ALLOWED_ORIGINS", []string{"*"}),
+		AdminURLTemplate:        os.Getenv("ADMIN_URL_TEMPLATE"),
+		TraefikMatcherVersion:   getEnv("TRAEFIK_MATCHER_VERSION", "v2"),
+		TraefikNamespace:        os.Getenv("TRAEFIK_NAMESPACE"),
+		TraefikTLSSecret:        os.Getenv("TRAEFIK_TLS_SECRET"),
+		TraefikCertResolver:     os.Getenv("TRAEFIK_CERT_RESOLVER"),
+		TraefikTLSEntryPoint:    getEnv("TRAEFIK_TLS_ENTRYPOINT", "websecure"),
+		PgAdminRouting:          getEnv("PGADMIN_ROUTING", "path-prefix"),
+		PgAdminHostDomain:       os.Getenv("PGADMIN_HOST_DOMAIN"),
+		PgAdminEmailDomain:      getEnv("PGADMIN_EMAIL_DOMAIN", "example.com"),
+		ReconcileInterval:       getEnvDuration("RECONCILE_INTERVAL", 0),
+		PhaseWatchInterval:      getEnvDuration("PHASE_WATCH_INTERVAL", 30*time.Second),
+		WaitForDB:               getEnvBool("WAIT_FOR_DB", false),
+		WaitForDBTimeout:        getEnvDuration("WAIT_FOR_DB_TIMEOUT", 45*time.Second),
+		WebhookURL:              os.Getenv("WEBHOOK_URL"),
+		WebhookSecret:           os.Getenv("WEBHOOK_SECRET"),
+		RequireDeleteConfirm:    getEnvBool("REQUIRE_DELETE_CONFIRM", false),
+		IdempotencyKeyTTL:       getEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		MaxDatabasesPerUser:     getEnvInt("MAX_DATABASES_PER_USER", 0),
+		AdminUsernames:          getEnvList("ADMIN_USERNAMES"),
+		DefaultDeployNamespace:  getEnv("DEFAULT_DEPLOY_NAMESPACE", "default"),
+		DeployNamespaces:        getEnvList("DEPLOY_NAMESPACE_ALLOWLIST"),
+		ImagePullPolicy:         os.Getenv("IMAGE_PULL_POLICY"),
+		ImagePullSecret:         os.Getenv("IMAGE_PULL_SECRET"),
+		NamespacePrefix:         strings.ToLower(os.Getenv("NAMESPACE_PREFIX")),
+		NamespaceLabels:         getEnvMap("NAMESPACE_LABELS"),
+		NamespaceAnnotations:    getEnvMap("NAMESPACE_ANNOTATIONS"),
+		PprofAddr:               getEnv("PPROF_ADDR", "localhost:6060"),
+		PVCStorageSize:          getEnv("PVC_STORAGE_SIZE", "1Gi"),
+		PVCStorageClass:         os.Getenv("PVC_STORAGE_CLASS"),
+		BackupStorageSize:       getEnv("BACKUP_STORAGE_SIZE", "5Gi"),
+		NetworkPolicyNamespaces: getEnvList("NETWORK_POLICY_ALLOWED_NAMESPACES"),
+		TrustedProxies:          getEnvList("TRUSTED_PROXIES"),
+		DBPool: DBPool{
+			MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 5),
+			ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+			ConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", time.Minute),
+			PingInterval:    getEnvDuration("DB_PING_INTERVAL", 30*time.Second),
+		},
+		HTTPServer: HTTPServer{
+			Addr:           getListenAddr(),
+			ReadTimeout:    getEnvDuration("HTTP_READ_TIMEOUT", 15*time.Second),
+			WriteTimeout:   getEnvDuration("HTTP_WRITE_TIMEOUT", 60*time.Second),
+			IdleTimeout:    getEnvDuration("HTTP_IDLE_TIMEOUT", 120*time.Second),
+			RequestTimeout: getEnvDuration("HTTP_REQUEST_TIMEOUT", 60*time.Second),
+			StreamingPaths: getEnvList("HTTP_STREAMING_PATHS"),
+		},
+		Probes: Probes{
+			Type:                  strings.ToLower(getEnv("PROBE_TYPE", "exec")),
+			ReadinessInitialDelay: getEnvDuration("PROBE_READINESS_INITIAL_DELAY", 5*time.Second),
+			LivenessInitialDelay:  getEnvDuration("PROBE_LIVENESS_INITIAL_DELAY", 60*time.Second),
+			Period:                getEnvDuration("PROBE_PERIOD", 10*time.Second),
+			Timeout:               getEnvDuration("PROBE_TIMEOUT", 5*time.Second),
+			FailureThreshold:      getEnvInt("PROBE_FAILURE_THRESHOLD", 6),
+		},
+		Features: Features{
+			TLS:                 getEnvBool("ENABLE_TLS", os.Getenv("TRAEFIK_TLS_SECRET") != "" || os.Getenv("TRAEFIK_CERT_RESOLVER") != ""),
+			PVC:                 getEnvBool("ENABLE_PVC", false),
+			Metrics:             getEnvBool("ENABLE_METRICS", true),
+			NetworkPolicy:       getEnvBool("ENABLE_NETWORK_POLICY", false),
+			AdminDashboards:     getEnvBool("ENABLE_ADMIN_DASHBOARDS", true),
+			PodSecurity:         getEnvBool("ENABLE_POD_SECURITY", false),
+			AdminColocation:     getEnvBool("ENABLE_ADMIN_COLOCATION", true),
+			Pprof:               getEnvBool("ENABLE_PPROF", false),
+			PodDisruptionBudget: getEnvBool("ENABLE_POD_DISRUPTION_BUDGET", false),
+		},
+	}
+}
+
+// getEnv returns the value of an environment variable or a default
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvBool parses a boolean environment variable, falling back to a default
+// when it is unset or invalid
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvDuration parses a duration environment variable (e.g. "30s", "5m"),
+// falling back to a default when it is unset or invalid
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvInt parses an integer environment variable, falling back to a default
+// when it is unset or invalid
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvMap parses a comma-separated list of key=value pairs
+// (e.g. "team=data,cost-center=42"), skipping malformed entries
+func getEnvMap(key string) map[string]string {
+	result := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			continue
+		}
+		result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return result
+}
+
+// getEnvList parses a comma-separated list, skipping empty entries
+func getEnvList(key string) []string {
+	var result []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// getEnvListDefault parses a comma-separated list, falling back to a default
+// when it is unset or empty
+func getEnvListDefault(key string, fallback []string) []string {
+	if result := getEnvList(key); len(result) > 0 {
+		return result
+	}
+	return fallback
+}
+
+// getListenAddr returns HTTP_ADDR (e.g. "0.0.0.0:8080") if set, otherwise
+// HTTP_HOST and HTTP_PORT joined, defaulting to all interfaces on port 8080
+func getListenAddr() string {
+	if addr := os.Getenv("HTTP_ADDR"); addr != "" {
+		return addr
+	}
+	return net.JoinHostPort(os.Getenv("HTTP_HOST"), getEnv("HTTP_PORT", "8080"))
+}
+
+// getBcryptCost reads the bcrypt cost, clamped to the range bcrypt accepts
+// like the admin service does. Changing it does not re-hash existing
+// passwords; each is re-hashed at the new cost the next time its user logs in.
+func getBcryptCost(key string) int {
+	cost := getEnvInt(key, bcrypt.DefaultCost)
+	if cost < bcrypt.MinCost {
+		return bcrypt.MinCost
+	}
+	if cost > bcrypt.MaxCost {
+		return bcrypt.MaxCost
+	}
+	return cost
+}
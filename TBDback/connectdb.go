@@ -1,38 +1,54 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
-	_ "github.com/lib/pq" // PostgreSQL driver
+	"github.com/BouchamiAhmed/TBD/apperrors"
+	"github.com/BouchamiAhmed/TBD/config"
+	"github.com/BouchamiAhmed/TBD/shared"
+	"github.com/lib/pq" // PostgreSQL driver
 )
 
 // Database connection parameters
 const (
-	port     = 5432
-	user     = "postgres"
-	password = "postgres"
-	dbname   = "testdb"
+	port   = 5432
+	user   = "postgres"
+	dbname = "testdb"
 )
 
 // DBClient represents a PostgreSQL database client
 type DBClient struct {
-	db *sql.DB
+	db   *sql.DB
+	pool config.DBPool
 }
 
-// NewDBClient creates a new database client with configurable host
-func NewDBClient(host string) (*DBClient, error) {
+// NewDBClient creates a new database client with configurable host. The
+// password is read from passwordFile when one is given, otherwise password is used.
+func NewDBClient(host, password, passwordFile string, pool config.DBPool) (*DBClient, error) {
 	fmt.Println("╔════════════════════════════════════════════════════════════╗")
 	fmt.Println("║                K3s Database Connection                     ║")
 	fmt.Println("╚════════════════════════════════════════════════════════════╝")
 
 	fmt.Printf("⏳ Attempting to connect to PostgreSQL on %s:%d...\n", host, port)
 
-	// Connection string
-	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
+	password, err := shared.ResolvePassword(password, passwordFile)
+	if err != nil {
+		fmt.Println("❌ Could not read the database password")
+		return nil, err
+	}
+
+	// Connection string (values are quoted so special characters in credentials are safe)
+	psqlInfo, err := shared.PostgresDSN(host, port, user, password, dbname)
+	if err != nil {
+		fmt.Println("❌ Invalid database connection parameters")
+		return nil, err
+	}
 
 	// Open doesn't actually connect, it just validates the args
 	fmt.Println("🔄 Initializing database driver...")
@@ -44,9 +60,10 @@ func NewDBClient(host string) (*DBClient, error) {
 
 	// Set connection pool settings
 	fmt.Println("🔄 Configuring connection pool...")
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 
 	// Verify connection works
 	fmt.Println("🔄 Testing connection to PostgreSQL...")
@@ -57,7 +74,7 @@ func NewDBClient(host string) (*DBClient, error) {
 
 	fmt.Println("✅ Successfully connected to PostgreSQL database!")
 	log.Println("Successfully connected to PostgreSQL database")
-	return &DBClient{db: db}, nil
+	return &DBClient{db: db, pool: pool}, nil
 }
 
 // Close closes the database connection
@@ -66,32 +83,50 @@ func (c *DBClient) Close() error {
 	return c.db.Close()
 }
 
-// CreateTablesIfNotExist creates necessary tables if they don't exist
-func (c *DBClient) CreateTablesIfNotExist() error {
-	fmt.Println("╔════════════════════════════════════════════════════════════╗")
-	fmt.Println("║                Table Initialization                        ║")
-	fmt.Println("╚════════════════════════════════════════════════════════════╝")
-
-	fmt.Println("🔄 Creating users table if it doesn't exist...")
+// advisoryLockRetryInterval is how often AcquireAdvisoryLock retries a lock
+// held by another session
+const advisoryLockRetryInterval = 250 * time.Millisecond
 
-	// Create users table if it doesn't exist
-	query := `
-	CREATE TABLE IF NOT EXISTS users (
-		id SERIAL PRIMARY KEY,
-		last_name VARCHAR(100) NOT NULL,
-		first_name VARCHAR(100) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	)`
-
-	_, err := c.db.Exec(query)
+// AcquireAdvisoryLock takes the Postgres session-level advisory lock for key,
+// retrying pg_try_advisory_lock until it is held or ctx is done, and returns
+// the function that releases it. The lock is bound to a dedicated connection
+// that is returned to the pool on release, or discarded if the unlock fails.
+func (c *DBClient) AcquireAdvisoryLock(ctx context.Context, key int64) (func(), error) {
+	conn, err := c.db.Conn(ctx)
 	if err != nil {
-		fmt.Println("❌ Failed to create users table")
-		return fmt.Errorf("error creating users table: %w", err)
+		return nil, fmt.Errorf("error getting connection for advisory lock: %w", err)
 	}
 
-	fmt.Println("✅ Database tables initialized successfully!")
-	log.Println("Database tables initialized")
-	return nil
+	for {
+		var acquired bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+			conn.Close()
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, fmt.Errorf("timed out acquiring advisory lock: %w", ctxErr)
+			}
+			return nil, fmt.Errorf("error acquiring advisory lock: %w", err)
+		}
+		if acquired {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return nil, fmt.Errorf("timed out acquiring advisory lock: %w", ctx.Err())
+		case <-time.After(advisoryLockRetryInterval):
+		}
+	}
+
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			// The connection may still hold the lock, so it must not go back to
+			// the pool: returning ErrBadConn from Raw makes database/sql discard it
+			fmt.Printf("⚠️  Warning: Failed to release advisory lock %d, discarding its connection: %v\n", key, err)
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}, nil
 }
 
 // User represents a user in the database
@@ -102,6 +137,25 @@ type User struct {
 	CreatedAt time.Time `json:"createdAt"`
 }
 
+// uniqueViolationCode is the PostgreSQL SQLSTATE of a unique constraint violation
+const uniqueViolationCode = "23505"
+
+// asUniqueViolation converts a unique constraint violation into an
+// ErrAlreadyExists error with the given message, whose "constraint" detail
+// names the violated constraint so callers can tell which field conflicted.
+// Any other error yields nil.
+func asUniqueViolation(err error, message string) *apperrors.Error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != uniqueViolationCode {
+		return nil
+	}
+	return &apperrors.Error{
+		Kind:    apperrors.ErrAlreadyExists,
+		Message: message,
+		Details: map[string]string{"constraint": pqErr.Constraint},
+	}
+}
+
 // CreateUser adds a new user to the database
 func (c *DBClient) CreateUser(lastName, firstName string) (*User, error) {
 	fmt.Printf("🔄 Creating new user: %s %s...\n", firstName, lastName)
@@ -121,6 +175,9 @@ func (c *DBClient) CreateUser(lastName, firstName string) (*User, error) {
 
 	if err != nil {
 		fmt.Println("❌ Failed to create user")
+		if conflict := asUniqueViolation(err, "User already exists"); conflict != nil {
+			return nil, conflict
+		}
 		return nil, fmt.Errorf("error creating user: %w", err)
 	}
 
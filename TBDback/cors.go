@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/cors"
+)
+
+// newCORS builds the CORS handler. Allowed methods are collected from the
+// registered routes, so new PATCH/PUT endpoints are never silently blocked
+// by browsers; it must be called after all routes are registered.
+func newCORS(r *mux.Router) *cors.Cors {
+	methods := routeMethods(r)
+	fmt.Printf("🌐 CORS allowed methods: %v\n", methods)
+
+	return cors.New(cors.Options{
+		AllowedOrigins:   appConfig.CORSAllowedOrigins,
+		AllowedMethods:   methods,
+		AllowedHeaders:   []string{"Content-Type", "Authorization", requestIDHeader, idempotencyKeyHeader},
+		ExposedHeaders:   []string{requestIDHeader, idempotencyReplayedHeader},
+		AllowCredentials: true,
+	})
+}
+
+// routeMethods returns the sorted set of HTTP methods used by the router's
+// routes, plus OPTIONS for preflight requests
+func routeMethods(r *mux.Router) []string {
+	seen := map[string]bool{http.MethodOptions: true}
+	_ = r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		// Routes without a method matcher return an error and are skipped
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			seen[method] = true
+		}
+		return nil
+	})
+
+	methods := make([]string, 0, len(seen))
+	for method := range seen {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
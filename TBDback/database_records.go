@@ -0,0 +1,234 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Database record statuses. Managed databases move through provisioning,
+// pulling and starting to running (or failed); creating is the legacy
+// pre-phase status.
+const (
+	DatabaseStatusCreating     = "creating"
+	DatabaseStatusProvisioning = "provisioning"
+	DatabaseStatusPulling      = "pulling"
+	DatabaseStatusStarting     = "starting"
+	DatabaseStatusRunning      = "running"
+	DatabaseStatusFailed       = "failed"
+	DatabaseStatusMissing      = "missing"
+	DatabaseStatusExternal     = "external"
+
+	// DatabaseStatusDeleteFailed marks a record whose delete only partly
+	// succeeded; its status message holds the error
+	DatabaseStatusDeleteFailed = "delete-failed"
+)
+
+// DatabaseRecord represents a database tracked in the databases table
+type DatabaseRecord struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Host      string `json:"host"`
+	Port      string `json:"port"`
+	Username  string `json:"username"`
+	Namespace string `json:"namespace"`
+	UserID    int    `json:"userId"`
+	AdminURL  string `json:"adminUrl"`
+	AdminType string `json:"adminType"`
+	Status    string `json:"status"`
+	// StatusMessage explains a failed status (e.g. why a delete failed)
+	StatusMessage string    `json:"statusMessage,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// databaseRecordColumns lists the columns scanned by scanDatabaseRecord
+const databaseRecordColumns = `id, name, type, host, port, username, namespace, user_id, admin_url, admin_type, status, status_message, created_at, updated_at`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanDatabaseRecord scans a row selected with databaseRecordColumns
+func scanDatabaseRecord(row rowScanner) (*DatabaseRecord, error) {
+	var record DatabaseRecord
+	err := row.Scan(
+		&record.ID,
+		&record.Name,
+		&record.Type,
+		&record.Host,
+		&record.Port,
+		&record.Username,
+		&record.Namespace,
+		&record.UserID,
+		&record.AdminURL,
+		&record.AdminType,
+		&record.Status,
+		&record.StatusMessage,
+		&record.CreatedAt,
+		&record.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// CreateDatabaseRecord inserts a database record
+func (c *DBClient) CreateDatabaseRecord(record DatabaseRecord) (*DatabaseRecord, error) {
+	query := `
+	INSERT INTO databases (name, type, host, port, username, namespace, user_id, admin_url, admin_type, status)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING ` + databaseRecordColumns
+
+	created, err := scanDatabaseRecord(c.db.QueryRow(
+		query,
+		record.Name,
+		record.Type,
+		record.Host,
+		record.Port,
+		record.Username,
+		record.Namespace,
+		record.UserID,
+		record.AdminURL,
+		record.AdminType,
+		record.Status,
+	))
+	if err != nil {
+		if conflict := asUniqueViolation(err, fmt.Sprintf("Database '%s' already exists in namespace '%s'", record.Name, record.Namespace)); conflict != nil {
+			return nil, conflict
+		}
+		return nil, fmt.Errorf("error recording database: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetDatabaseRecord retrieves a database record by name and namespace
+func (c *DBClient) GetDatabaseRecord(name, namespace string) (*DatabaseRecord, error) {
+	query := `SELECT ` + databaseRecordColumns + `
+	FROM databases
+	WHERE name = $1 AND namespace = $2`
+
+	record, err := scanDatabaseRecord(c.db.QueryRow(query, name, namespace))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Record not found
+		}
+		return nil, fmt.Errorf("error getting database record: %w", err)
+	}
+
+	return record, nil
+}
+
+// ListDatabaseRecords retrieves all database records in a namespace
+func (c *DBClient) ListDatabaseRecords(namespace string) ([]DatabaseRecord, error) {
+	query := `SELECT ` + databaseRecordColumns + `
+	FROM databases
+	WHERE namespace = $1
+	ORDER BY created_at DESC`
+
+	rows, err := c.db.Query(query, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("error querying database records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []DatabaseRecord
+	for rows.Next() {
+		record, err := scanDatabaseRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning database record: %w", err)
+		}
+		records = append(records, *record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating database records: %w", err)
+	}
+
+	return records, nil
+}
+
+// ListAllDatabaseRecords retrieves the database records of every namespace
+func (c *DBClient) ListAllDatabaseRecords() ([]DatabaseRecord, error) {
+	query := `SELECT ` + databaseRecordColumns + `
+	FROM databases
+	ORDER BY namespace, name`
+
+	rows, err := c.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying database records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []DatabaseRecord
+	for rows.Next() {
+		record, err := scanDatabaseRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning database record: %w", err)
+		}
+		records = append(records, *record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating database records: %w", err)
+	}
+
+	return records, nil
+}
+
+// UpdateDatabaseRecordStatus sets the status of a database record
+func (c *DBClient) UpdateDatabaseRecordStatus(name, namespace, status string) error {
+	query := `
+	UPDATE databases SET status = $1, status_message = '', updated_at = CURRENT_TIMESTAMP
+	WHERE name = $2 AND namespace = $3`
+
+	if _, err := c.db.Exec(query, status, name, namespace); err != nil {
+		return fmt.Errorf("error updating database record status: %w", err)
+	}
+	return nil
+}
+
+// UpdateDatabaseRecordPhase sets the status of a database record to an
+// observed phase, leaving records whose delete failed untouched
+func (c *DBClient) UpdateDatabaseRecordPhase(name, namespace, phase string) error {
+	query := `
+	UPDATE databases SET status = $1, status_message = '', updated_at = CURRENT_TIMESTAMP
+	WHERE name = $2 AND namespace = $3 AND status <> $1 AND status <> $4`
+
+	if _, err := c.db.Exec(query, phase, name, namespace, DatabaseStatusDeleteFailed); err != nil {
+		return fmt.Errorf("error updating database record phase: %w", err)
+	}
+	return nil
+}
+
+// MarkDatabaseRecordDeleteFailed flags a record whose delete only partly
+// succeeded, keeping the error so a retry or the reconciler can finish it
+func (c *DBClient) MarkDatabaseRecordDeleteFailed(name, namespace, message string) error {
+	query := `
+	UPDATE databases SET status = $1, status_message = $2, updated_at = CURRENT_TIMESTAMP
+	WHERE name = $3 AND namespace = $4`
+
+	if _, err := c.db.Exec(query, DatabaseStatusDeleteFailed, message, name, namespace); err != nil {
+		return fmt.Errorf("error marking database record delete-failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteDatabaseRecord removes a database record
+func (c *DBClient) DeleteDatabaseRecord(name, namespace string) error {
+	result, err := c.db.Exec(`DELETE FROM databases WHERE name = $1 AND namespace = $2`, name, namespace)
+	if err != nil {
+		return fmt.Errorf("error deleting database record: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("no database found with name %s in namespace %s", name, namespace)
+	}
+
+	return nil
+}
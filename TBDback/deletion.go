@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/BouchamiAhmed/TBD/apperrors"
+	"github.com/BouchamiAhmed/TBD/shared"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// deploymentDeleteTimeout bounds how long a delete waits for a deployment's
+// ReplicaSets and pods to be gone
+const deploymentDeleteTimeout = 2 * time.Minute
+
+// deploymentDeletePollInterval is how often a pending deployment delete is checked
+const deploymentDeletePollInterval = time.Second
+
+// deleteResponseMargin is the part of the request timeout kept for the
+// deletes that follow the waits and for writing the response
+const deleteResponseMargin = 10 * time.Second
+
+// deleteDeadlineKey holds the time by which a delete request stops waiting
+// for its deployments, however many it deletes
+const deleteDeadlineKey contextKey = "deleteDeadline"
+
+// deleteWaitTimeout returns how long a delete request may wait for its
+// deployments in total: deploymentDeleteTimeout, cut to finish within
+// HTTP_REQUEST_TIMEOUT and HTTP_WRITE_TIMEOUT
+func deleteWaitTimeout() time.Duration {
+	timeout := deploymentDeleteTimeout
+	if appConfig != nil {
+		for _, limit := range []time.Duration{appConfig.HTTPServer.RequestTimeout, appConfig.HTTPServer.WriteTimeout} {
+			if limit > 0 && limit-deleteResponseMargin < timeout {
+				timeout = limit - deleteResponseMargin
+			}
+		}
+	}
+	if timeout < deploymentDeletePollInterval {
+		timeout = deploymentDeletePollInterval
+	}
+	return timeout
+}
+
+// withDeleteDeadline starts the single wait budget of a delete request. The
+// handlers detach deletes from the request's cancellation, so the budget is
+// carried as a value rather than a context deadline.
+func withDeleteDeadline(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deleteDeadlineKey, time.Now().Add(deleteWaitTimeout()))
+}
+
+// deleteDeadline returns when waits for deployments must give up: the request's
+// deadline when set by withDeleteDeadline, otherwise deploymentDeleteTimeout from now
+func deleteDeadline(ctx context.Context) time.Time {
+	if deadline, ok := ctx.Value(deleteDeadlineKey).(time.Time); ok {
+		return deadline
+	}
+	return time.Now().Add(deploymentDeleteTimeout)
+}
+
+// parseGracePeriod reads the optional ?gracePeriodSeconds= query parameter,
+// returning nil when it is absent
+func parseGracePeriod(r *http.Request) (*int64, error) {
+	value := r.URL.Query().Get("gracePeriodSeconds")
+	if value == "" {
+		return nil, nil
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return nil, apperrors.New(apperrors.ErrInvalidInput, "gracePeriodSeconds must be a non-negative integer")
+	}
+	return &seconds, nil
+}
+
+// deleteConfirmed reports whether ?confirm= repeats every name to delete. It
+// is required when REQUIRE_DELETE_CONFIRM is on, and checked whenever given.
+func deleteConfirmed(r *http.Request, names ...string) bool {
+	confirm, hasConfirm := r.URL.Query()["confirm"]
+	if !appConfig.RequireDeleteConfirm && !hasConfirm {
+		return true
+	}
+
+	confirmed := make(map[string]bool, len(confirm))
+	for _, name := range confirm {
+		confirmed[name] = true
+	}
+	for _, name := range names {
+		if !confirmed[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// deleteDeploymentAndWait deletes a deployment with foreground propagation, so
+// the API server removes its ReplicaSets and pods before the deployment itself,
+// and returns once it is gone or the request's delete deadline passes. A grace
+// period, when given, is forwarded to the pods: the garbage collector would
+// otherwise terminate them with their default.
+func deleteDeploymentAndWait(ctx context.Context, namespace, name string, gracePeriod *int64) error {
+	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	propagation := metav1.DeletePropagationForeground
+	err = clientset.AppsV1().Deployments(namespace).Delete(ctx, name, metav1.DeleteOptions{
+		PropagationPolicy:  &propagation,
+		GracePeriodSeconds: gracePeriod,
+	})
+	if err != nil {
+		return err
+	}
+
+	if gracePeriod != nil && deployment.Spec.Selector != nil {
+		selector := labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels).String()
+		err := clientset.CoreV1().Pods(namespace).DeleteCollection(ctx,
+			metav1.DeleteOptions{GracePeriodSeconds: gracePeriod},
+			metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			logf(ctx, "Warning: Failed to apply grace period to pods of %s: %v\n", name, err)
+		}
+	}
+
+	waitCtx, cancel := context.WithDeadline(ctx, deleteDeadline(ctx))
+	defer cancel()
+
+	err = wait.PollUntilContextCancel(waitCtx, deploymentDeletePollInterval, true, func(ctx context.Context) (bool, error) {
+		_, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return fmt.Errorf("deployment %s is still terminating: %w", name, err)
+	}
+	return nil
+}
+
+// DatabaseDeletion reports which parts of a database delete succeeded
+type DatabaseDeletion struct {
+	KubernetesDeleted bool `json:"kubernetesDeleted"`
+	RecordDeleted     bool `json:"recordDeleted"`
+	Tracked           bool `json:"tracked"`            // whether the database had a record to delete
+	External          bool `json:"external,omitempty"` // only the record of an external database was removed
+}
+
+// errK8sUnavailable is returned by deleteDatabase when a database's resources
+// must be deleted but there is no Kubernetes client
+var errK8sUnavailable = fmt.Errorf("kubernetes unavailable")
+
+// deleteDatabase deletes one database from a namespace the caller has locked.
+// External databases are only tracked records, so just the record is removed
+// and the cluster is never touched; others go through deleteDatabaseAndRecord.
+func deleteDatabase(ctx context.Context, dbClient *DBClient, dbName, namespace string, gracePeriod *int64) (DatabaseDeletion, error) {
+	var record *DatabaseRecord
+	if dbClient != nil {
+		var err error
+		record, err = dbClient.GetDatabaseRecord(dbName, namespace)
+		if err != nil {
+			return DatabaseDeletion{}, fmt.Errorf("failed to look up database record: %w", err)
+		}
+		if record != nil && record.Status == DatabaseStatusExternal {
+			deletion := DatabaseDeletion{Tracked: true, External: true}
+			if err := dbClient.DeleteDatabaseRecord(dbName, namespace); err != nil {
+				return deletion, err
+			}
+			deletion.RecordDeleted = true
+			return deletion, nil
+		}
+	}
+
+	if clientset == nil || dynamicClient == nil {
+		return DatabaseDeletion{Tracked: record != nil}, errK8sUnavailable
+	}
+	return deleteDatabaseAndRecord(ctx, dbClient, record, dbName, namespace, gracePeriod)
+}
+
+// deleteDatabaseAndRecord deletes a database's Kubernetes resources and then
+// its record, if it has one, from a namespace the caller has locked. When
+// either step fails the record is kept and marked delete-failed with the
+// error, so the delete can be retried; a retry finds the resources already
+// gone and only removes the record.
+func deleteDatabaseAndRecord(ctx context.Context, dbClient *DBClient, record *DatabaseRecord, dbName, namespace string, gracePeriod *int64) (DatabaseDeletion, error) {
+	deletion := DatabaseDeletion{Tracked: record != nil}
+
+	err := deleteDatabaseDeploymentLocked(ctx, dbName, namespace, gracePeriod)
+	if err != nil && (record == nil || apperrors.HTTPStatus(err) != http.StatusNotFound) {
+		markDeleteFailed(ctx, dbClient, record, err)
+		return deletion, err
+	}
+	deletion.KubernetesDeleted = true
+
+	if record == nil {
+		return deletion, nil
+	}
+
+	if err := dbClient.DeleteDatabaseRecord(dbName, namespace); err != nil {
+		markDeleteFailed(ctx, dbClient, record, err)
+		return deletion, fmt.Errorf("resources deleted but the database record was not: %w", err)
+	}
+	deletion.RecordDeleted = true
+	return deletion, nil
+}
+
+// markDeleteFailed records a failed delete on the database's record, if any
+func markDeleteFailed(ctx context.Context, dbClient *DBClient, record *DatabaseRecord, cause error) {
+	if record == nil {
+		return
+	}
+	if err := dbClient.MarkDatabaseRecordDeleteFailed(record.Name, record.Namespace, cause.Error()); err != nil {
+		logf(ctx, "⚠️  Warning: Could not mark database record '%s' delete-failed: %v\n", record.Name, err)
+	}
+}
+
+// deleteLabeledDatabaseResources removes every object carrying a database's
+// label, so nothing is missed whatever the objects are named. The admin
+// dashboard goes before the database, whose deployment failing to delete is
+// the only fatal error. The backup volume is kept, as it is meant to outlive
+// the database.
+func deleteLabeledDatabaseResources(ctx context.Context, dbName, namespace string, gracePeriod *int64) error {
+	selector := databaseSelector(dbName)
+	listOptions := metav1.ListOptions{LabelSelector: selector}
+	logf(ctx, "🗑️ Deleting objects matching %s\n", selector)
+
+	// Traefik objects may live in a central namespace shared by every user
+	// namespace, so they are matched on their source namespace as well
+	if dynamicClient != nil {
+		traefikOptions := metav1.ListOptions{LabelSelector: selector + "," + shared.TraefikNamespaceLabel + "=" + namespace}
+		for _, gvr := range []schema.GroupVersionResource{ingressRoutesGVR, middlewaresGVR} {
+			if err := deleteLabeledCustomResources(ctx, gvr, traefikNamespace(namespace), traefikOptions); err != nil {
+				logf(ctx, "Warning: Failed to delete %s: %v\n", gvr.Resource, err)
+			}
+		}
+	}
+
+	// Older clusters cannot DeleteCollection services, so they go one by one
+	services, err := clientset.CoreV1().Services(namespace).List(ctx, listOptions)
+	if err != nil {
+		logf(ctx, "Warning: Failed to list services: %v\n", err)
+	} else {
+		for _, service := range services.Items {
+			if err := clientset.CoreV1().Services(namespace).Delete(ctx, service.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				logf(ctx, "Warning: Failed to delete service %s: %v\n", service.Name, err)
+				continue
+			}
+			logf(ctx, "✅ Deleted service %s\n", service.Name)
+		}
+	}
+
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, listOptions)
+	if err != nil {
+		return fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, deployment := range deployments.Items {
+		if deployment.Name == dbName {
+			continue
+		}
+		if err := deleteDeploymentAndWait(ctx, namespace, deployment.Name, gracePeriod); err != nil && !errors.IsNotFound(err) {
+			logf(ctx, "Warning: Failed to delete deployment %s: %v\n", deployment.Name, err)
+			continue
+		}
+		logf(ctx, "✅ Deleted deployment %s\n", deployment.Name)
+	}
+	if err := deleteDeploymentAndWait(ctx, namespace, dbName, gracePeriod); err != nil {
+		return fmt.Errorf("failed to delete database deployment: %w", err)
+	}
+	logf(ctx, "✅ Deleted database deployment %s\n", dbName)
+
+	// Remaining kinds support DeleteCollection; CronJobs take their Jobs along
+	background := metav1.DeletePropagationBackground
+	collections := []struct {
+		kind   string
+		delete func() error
+	}{
+		{"CronJobs", func() error {
+			return clientset.BatchV1().CronJobs(namespace).DeleteCollection(ctx, metav1.DeleteOptions{PropagationPolicy: &background}, listOptions)
+		}},
+		{"PodDisruptionBudgets", func() error {
+			return clientset.PolicyV1().PodDisruptionBudgets(namespace).DeleteCollection(ctx, metav1.DeleteOptions{}, listOptions)
+		}},
+		{"ConfigMaps", func() error {
+			return clientset.CoreV1().ConfigMaps(namespace).DeleteCollection(ctx, metav1.DeleteOptions{}, listOptions)
+		}},
+		{"Secrets", func() error {
+			return clientset.CoreV1().Secrets(namespace).DeleteCollection(ctx, metav1.DeleteOptions{}, listOptions)
+		}},
+		{"PersistentVolumeClaims", func() error {
+			return clientset.CoreV1().PersistentVolumeClaims(namespace).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{
+				LabelSelector: selector + ",app.kubernetes.io/component!=backup",
+			})
+		}},
+	}
+	for _, collection := range collections {
+		if err := collection.delete(); err != nil {
+			logf(ctx, "Warning: Failed to delete %s: %v\n", collection.kind, err)
+			continue
+		}
+		logf(ctx, "✅ Deleted %s\n", collection.kind)
+	}
+
+	return nil
+}
+
+// deleteLabeledCustomResources deletes the custom resources matching a
+// selector one by one, since DeleteCollection is not reliably served for CRDs
+func deleteLabeledCustomResources(ctx context.Context, gvr schema.GroupVersionResource, namespace string, options metav1.ListOptions) error {
+	list, err := dynamicClient.Resource(gvr).Namespace(namespace).List(ctx, options)
+	if err != nil {
+		return err
+	}
+	for _, item := range list.Items {
+		err := dynamicClient.Resource(gvr).Namespace(namespace).Delete(ctx, item.GetName(), metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete %s: %w", item.GetName(), err)
+		}
+		logf(ctx, "✅ Deleted %s %s\n", gvr.Resource, item.GetName())
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDeleteWaitTimeoutFitsRequestTimeouts(t *testing.T) {
+	cfg := useTestConfig(t)
+
+	cfg.HTTPServer.RequestTimeout = 60 * time.Second
+	cfg.HTTPServer.WriteTimeout = 30 * time.Second
+	if got, want := deleteWaitTimeout(), 30*time.Second-deleteResponseMargin; got != want {
+		t.Errorf("deleteWaitTimeout() = %s, want %s below the write timeout", got, want)
+	}
+
+	cfg.HTTPServer.RequestTimeout = 0
+	cfg.HTTPServer.WriteTimeout = 0
+	if got := deleteWaitTimeout(); got != deploymentDeleteTimeout {
+		t.Errorf("deleteWaitTimeout() without timeouts = %s, want %s", got, deploymentDeleteTimeout)
+	}
+}
+
+func TestDeleteDeadlineIsSharedByTheRequest(t *testing.T) {
+	cfg := useTestConfig(t)
+	cfg.HTTPServer.RequestTimeout = 20 * time.Second
+
+	ctx := withDeleteDeadline(context.Background())
+	first := deleteDeadline(ctx)
+	time.Sleep(10 * time.Millisecond)
+	if second := deleteDeadline(ctx); !second.Equal(first) {
+		t.Errorf("deleteDeadline moved from %s to %s within one request", first, second)
+	}
+	if remaining := time.Until(first); remaining > 20*time.Second-deleteResponseMargin {
+		t.Errorf("delete deadline is %s away, want at most %s", remaining, 20*time.Second-deleteResponseMargin)
+	}
+}
+
+func TestDeleteDatabasesBatchRunsUnderTheCallersLock(t *testing.T) {
+	cfg := useTestConfig(t)
+	cfg.WaitForDB = false
+	client := useFakeClientset(t, managedNamespace("7alice"))
+	useFakeDynamicClient(t)
+
+	ctx := context.Background()
+	names := []string{"orders", "billing", "audit"}
+	for _, name := range names {
+		dbRequest := DatabaseRequest{Name: name, Type: DatabaseTypePostgreSQL, Username: "app", Password: "secret-password", UserID: 7, UserName: "alice"}
+		if err := deployDatabase(ctx, client, dbRequest, "7alice"); err != nil {
+			t.Fatalf("deployDatabase(%s) returned error: %v", name, err)
+		}
+	}
+
+	unlock, err := lockNamespace(ctx, "7alice")
+	if err != nil {
+		t.Fatalf("lockNamespace returned error: %v", err)
+	}
+	defer unlock()
+
+	deadline, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	results := deleteDatabasesBatch(deadline, nil, names, "7alice", nil)
+	for _, name := range names {
+		if result := results[name]; !result.Success {
+			t.Errorf("batch delete of %s failed: %s", name, result.Error)
+		}
+	}
+
+	deployments, err := client.AppsV1().Deployments("7alice").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("listing deployments: %v", err)
+	}
+	if len(deployments.Items) != 0 {
+		t.Errorf("%d deployments left after batch delete, want 0", len(deployments.Items))
+	}
+}
+
+func TestNamespaceLocksAreRemovedWhenReleased(t *testing.T) {
+	ctx := context.Background()
+	unlock, err := lockNamespace(ctx, "7alice")
+	if err != nil {
+		t.Fatalf("lockNamespace returned error: %v", err)
+	}
+
+	waited := make(chan error, 1)
+	go func() {
+		unlockWaiter, err := lockNamespace(ctx, "7alice")
+		if err == nil {
+			unlockWaiter()
+		}
+		waited <- err
+	}()
+
+	unlock()
+	if err := <-waited; err != nil {
+		t.Fatalf("waiting lockNamespace returned error: %v", err)
+	}
+
+	namespaceLocksMu.Lock()
+	defer namespaceLocksMu.Unlock()
+	if _, ok := namespaceLocks["7alice"]; ok {
+		t.Error("namespace lock entry still present after every holder released it")
+	}
+}
+
+func TestDeleteConfirmed(t *testing.T) {
+	cfg := useTestConfig(t)
+
+	tests := []struct {
+		name    string
+		require bool
+		query   string
+		names   []string
+		want    bool
+	}{
+		{"not required", false, "", []string{"orders"}, true},
+		{"required but missing", true, "", []string{"orders"}, false},
+		{"single confirmed", true, "?confirm=orders", []string{"orders"}, true},
+		{"wrong name given", false, "?confirm=billing", []string{"orders"}, false},
+		{"every batch name confirmed", true, "?confirm=orders&confirm=billing", []string{"orders", "billing"}, true},
+		{"one batch name missing", true, "?confirm=orders", []string{"orders", "billing"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.RequireDeleteConfirm = tt.require
+			r := httptest.NewRequest(http.MethodPost, "/api/databases/7alice/batch-delete"+tt.query, nil)
+			if got := deleteConfirmed(r, tt.names...); got != tt.want {
+				t.Errorf("deleteConfirmed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,13 +6,18 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/BouchamiAhmed/TBD/apperrors"
+	"github.com/BouchamiAhmed/TBD/shared"
 	"github.com/gorilla/mux"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 	"k8s.io/client-go/dynamic"
@@ -31,33 +36,47 @@ type DeploymentRequest struct {
 
 // DeploymentResponse contains the result of a deployment operation
 type DeploymentResponse struct {
-	Success bool   `json:"success"`
 	Message string `json:"message"`
 	Name    string `json:"name,omitempty"`
 }
 
-// DatabaseRequest represents a request to create a database
-type DatabaseRequest struct {
+// DatabaseRequest and DatabaseResponse live in the shared module so the
+// admin service decodes exactly the same shape
+type (
+	DatabaseRequest  = shared.DatabaseRequest
+	DatabaseResponse = shared.DatabaseResponse
+)
+
+// ImportDatabaseRequest represents a request to track an existing external database
+type ImportDatabaseRequest struct {
 	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
 	Username string `json:"username"`
-	Password string `json:"password"`
-	Type     string `json:"type"`               // mysql or postgres
 	UserID   int    `json:"userId,omitempty"`   // User ID for namespace targeting
 	UserName string `json:"userName,omitempty"` // Username for namespace targeting
 }
 
-// DatabaseResponse contains the result of a database creation operation
-type DatabaseResponse struct {
-	Name      string `json:"name"`
-	Host      string `json:"host"`
-	Port      string `json:"port"`
-	Username  string `json:"username"`
-	Type      string `json:"type"`
-	Status    string `json:"status"`
-	Message   string `json:"message"`
-	Namespace string `json:"namespace,omitempty"` // Include namespace in response
-	AdminURL  string `json:"adminUrl,omitempty"`  // Admin dashboard URL
-	AdminType string `json:"adminType,omitempty"` // Type of admin dashboard (pgadmin/phpmyadmin)
+// BatchDeleteRequest represents a request to delete several databases in one namespace
+type BatchDeleteRequest struct {
+	Names []string `json:"names"`
+}
+
+// BatchDeleteResult contains the outcome of deleting a single database in a batch
+type BatchDeleteResult struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	DatabaseDeletion
+}
+
+// BatchCreateResult contains the outcome of creating a single database in a batch
+type BatchCreateResult struct {
+	Name     string            `json:"name"`
+	Success  bool              `json:"success"`
+	Error    string            `json:"error,omitempty"`
+	Errors   ValidationErrors  `json:"errors,omitempty"` // every invalid field, when validation failed
+	Database *DatabaseResponse `json:"database,omitempty"`
 }
 
 // NamespaceRequest represents a request to create a namespace for a user
@@ -68,7 +87,6 @@ type NamespaceRequest struct {
 
 // NamespaceResponse contains the result of a namespace creation operation
 type NamespaceResponse struct {
-	Success   bool   `json:"success"`
 	Message   string `json:"message"`
 	Namespace string `json:"namespace,omitempty"`
 }
@@ -83,13 +101,40 @@ type kubeClients struct {
 // global clients that will be initialized in RegisterDeploymentHandler
 var clients *kubeClients
 
-// GetUserNamespace returns the namespace name for a given user
+// GetUserNamespace returns the namespace name for a given user, prefixed with
+// NAMESPACE_PREFIX. Over-long names are hash-suffixed, except that a
+// namespace an earlier release created under the truncated name keeps being used.
 func GetUserNamespace(userID int, username string) string {
-	namespaceName := fmt.Sprintf("%d%s", userID, username)
-	if len(namespaceName) > 63 {
-		namespaceName = namespaceName[:63]
+	return shared.ResolveNamespaceName(userNamespaceBase(userID, username), legacyNamespaceOwner(userID))
+}
+
+// userNamespaceBase returns a user's namespace name before shortening
+func userNamespaceBase(userID int, username string) string {
+	return fmt.Sprintf("%s%d%s", appConfig.NamespacePrefix, userID, username)
+}
+
+// legacyNamespaceLookupTimeout bounds the check for a truncated legacy namespace
+const legacyNamespaceLookupTimeout = 5 * time.Second
+
+// legacyNamespaceOwner returns a check that a legacy namespace exists and
+// belongs to userID, or nil when Kubernetes is unavailable
+func legacyNamespaceOwner(userID int) func(string) (bool, error) {
+	if clientset == nil {
+		return nil
+	}
+	return func(name string) (bool, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), legacyNamespaceLookupTimeout)
+		defer cancel()
+
+		namespace, err := clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return namespace.Labels["db-saas/user-id"] == strconv.Itoa(userID), nil
 	}
-	return namespaceName
 }
 
 // CreateNamespaceForUser creates a namespace for a new user (used during registration)
@@ -114,28 +159,124 @@ func RegisterDeploymentHandler(r *mux.Router) {
 		fmt.Println("Successfully connected to Kubernetes cluster for deployments")
 	}
 
-	r.HandleFunc("/api/deploy", handleDeployYAML).Methods("POST")
-	r.HandleFunc("/api/namespace/create", handleCreateUserNamespace).Methods("POST")
+	r.HandleFunc("/api/deploy", requireAuth(handleDeployYAML)).Methods("POST")
+	r.HandleFunc("/api/namespace/create", requireAuth(handleCreateUserNamespace)).Methods("POST")
+	r.HandleFunc("/api/namespace/{name}", requireAuth(handleDeleteUserNamespace)).Methods("DELETE")
 	fmt.Println("Deployment endpoint registered at /api/deploy")
 	fmt.Println("Namespace creation endpoint registered at /api/namespace/create")
 }
 
-// handleCreateUserNamespace handles requests to create a namespace for a new user
+// handleDeleteUserNamespace deletes a db-saas managed namespace and everything
+// in it. Only the namespace's owner or an admin may delete it, and unless
+// ?force=true it must not contain any databases.
+func handleDeleteUserNamespace(w http.ResponseWriter, r *http.Request) {
+	namespaceName := mux.Vars(r)["name"]
+	force := r.URL.Query().Get("force") == "true"
+
+	if clients == nil || clients.clientset == nil {
+		respondK8sUnavailable(w)
+		return
+	}
+
+	unlock, err := lockNamespace(r.Context(), namespaceName)
+	if err != nil {
+		respondError(w, apperrors.HTTPStatus(err), err.Error())
+		return
+	}
+	defer unlock()
+
+	namespace, err := clients.clientset.CoreV1().Namespaces().Get(r.Context(), namespaceName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		respondError(w, http.StatusNotFound, "Namespace not found")
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error getting namespace: %v\n", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get namespace: "+err.Error())
+		return
+	}
+
+	if !managedNamespaceSelector().Matches(labels.Set(namespace.Labels)) {
+		respondError(w, http.StatusForbidden, "Namespace is not managed by this db-saas instance")
+		return
+	}
+
+	claims := authFromContext(r.Context())
+	if namespace.Labels["db-saas/user-id"] != fmt.Sprintf("%d", claims.UserID) && !isAdmin(claims) {
+		respondError(w, http.StatusForbidden, "Cannot delete another user's namespace")
+		return
+	}
+
+	if !force {
+		deployments, err := listDatabaseDeployments(r.Context(), namespaceName, nil)
+		if err != nil {
+			fmt.Printf("Error listing databases: %v\n", err)
+			respondError(w, http.StatusInternalServerError, "Failed to list databases: "+err.Error())
+			return
+		}
+		if len(deployments) > 0 {
+			remaining := make([]string, 0, len(deployments))
+			for _, deployment := range deployments {
+				remaining = append(remaining, deployment.Name)
+			}
+			writeJSON(w, http.StatusConflict, apiResponse{
+				Success: false,
+				Error:   fmt.Sprintf("Namespace '%s' still contains %d databases (use ?force=true to delete them)", namespaceName, len(remaining)),
+				Data:    map[string]interface{}{"databases": remaining},
+			})
+			return
+		}
+	}
+
+	fmt.Printf("🗑️ %s is deleting namespace '%s' (force=%t)\n", claims.Username, namespaceName, force)
+
+	// Routes in TRAEFIK_NAMESPACE outlive the namespace, so remove them first:
+	// a failure leaves the namespace in place and the request can be retried
+	if err := deleteNamespaceTraefikObjects(r.Context(), namespaceName); err != nil {
+		fmt.Printf("Error cleaning up Traefik objects for namespace '%s': %v\n", namespaceName, err)
+		respondError(w, http.StatusInternalServerError, "Failed to delete Traefik objects: "+err.Error())
+		return
+	}
+
+	if err := clients.clientset.CoreV1().Namespaces().Delete(r.Context(), namespaceName, metav1.DeleteOptions{}); err != nil {
+		fmt.Printf("Error deleting namespace: %v\n", err)
+		respondError(w, http.StatusInternalServerError, "Failed to delete namespace: "+err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, NamespaceResponse{
+		Message:   fmt.Sprintf("Namespace '%s' is being deleted", namespaceName),
+		Namespace: namespaceName,
+	})
+}
+
+// handleCreateUserNamespace handles requests to create a namespace for a new
+// user. Users may only create their own namespace; admins may create anyone's.
 func handleCreateUserNamespace(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Received request to create user namespace")
 
 	if clients == nil || clients.clientset == nil {
-		sendNamespaceErrorResponse(w, "Kubernetes client not available")
+		respondK8sUnavailable(w)
 		return
 	}
 
 	var nsRequest NamespaceRequest
 	if err := json.NewDecoder(r.Body).Decode(&nsRequest); err != nil {
 		fmt.Printf("Error parsing namespace request: %v\n", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
+	claims := authFromContext(r.Context())
+	if !isAdmin(claims) {
+		if nsRequest.UserID > 0 && nsRequest.UserID != claims.UserID {
+			respondError(w, http.StatusForbidden, "Cannot create another user's namespace")
+			return
+		}
+		nsRequest.UserID = claims.UserID
+		nsRequest.Username = claims.Username
+	}
+
 	if nsRequest.UserID <= 0 || nsRequest.Username == "" {
 		fmt.Println("Invalid user ID or username")
 		sendNamespaceErrorResponse(w, "User ID and username are required")
@@ -162,17 +303,33 @@ func handleDeployYAML(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Received request to deploy YAML file")
 
 	if clients == nil || clients.clientset == nil {
-		sendErrorResponse(w, "Kubernetes client not available")
+		respondK8sUnavailable(w)
 		return
 	}
 
 	var deployRequest DeploymentRequest
 	if err := json.NewDecoder(r.Body).Decode(&deployRequest); err != nil {
 		fmt.Printf("Error parsing request: %v\n", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
+	// Users deploy into their own namespace only; admins may name any
+	// namespace that checkDeployNamespace allows
+	claims := authFromContext(r.Context())
+	if !isAdmin(claims) {
+		if deployRequest.UserID > 0 && deployRequest.UserID != claims.UserID {
+			respondError(w, http.StatusForbidden, "Cannot deploy for another user")
+			return
+		}
+		if deployRequest.Namespace != "" && !ownsUserNamespace(claims, deployRequest.Namespace) {
+			respondError(w, http.StatusForbidden, "Cannot access another user's namespace")
+			return
+		}
+		deployRequest.UserID = claims.UserID
+		deployRequest.Username = claims.Username
+	}
+
 	var targetNamespace string
 
 	// If UserID and Username are provided, use the user's dedicated namespace
@@ -190,10 +347,18 @@ func handleDeployYAML(w http.ResponseWriter, r *http.Request) {
 	} else {
 		targetNamespace = deployRequest.Namespace
 		if targetNamespace == "" {
-			targetNamespace = "default"
+			targetNamespace = appConfig.DefaultDeployNamespace
 		}
 	}
 
+	// Arbitrary YAML may only land in db-saas namespaces or allowlisted ones,
+	// never in system namespaces such as kube-system
+	if err := checkDeployNamespace(r.Context(), targetNamespace); err != nil {
+		fmt.Printf("Rejected deploy to namespace '%s': %v\n", targetNamespace, err)
+		respondError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
 	fmt.Printf("Deploying '%s' to namespace '%s'\n", deployRequest.Name, targetNamespace)
 
 	// Read and deploy the YAML file
@@ -209,7 +374,7 @@ func handleDeployYAML(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		errMsg := fmt.Sprintf("Error deploying YAML: %v", err)
 		fmt.Println(errMsg)
-		sendErrorResponse(w, errMsg)
+		respondError(w, apperrors.HTTPStatus(err), errMsg)
 		return
 	}
 
@@ -217,6 +382,26 @@ func handleDeployYAML(w http.ResponseWriter, r *http.Request) {
 	sendSuccessResponse(w, deployRequest.Name)
 }
 
+// checkDeployNamespace allows YAML deploys only into namespaces listed in
+// DEPLOY_NAMESPACE_ALLOWLIST or managed by this db-saas instance
+func checkDeployNamespace(ctx context.Context, namespaceName string) error {
+	for _, allowed := range appConfig.DeployNamespaces {
+		if allowed == namespaceName {
+			return nil
+		}
+	}
+
+	namespace, err := clients.clientset.CoreV1().Namespaces().Get(ctx, namespaceName, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("error checking namespace '%s': %w", namespaceName, err)
+	}
+	if err == nil && managedNamespaceSelector().Matches(labels.Set(namespace.Labels)) {
+		return nil
+	}
+
+	return fmt.Errorf("namespace '%s' is not managed by db-saas and not in DEPLOY_NAMESPACE_ALLOWLIST", namespaceName)
+}
+
 // ensureNamespaceExists checks if a namespace exists and creates it if it doesn't
 func ensureNamespaceExists(namespaceName string, userID int, username string) error {
 	// Check if namespace already exists
@@ -240,16 +425,16 @@ func createUserNamespace(namespaceName string, userID int, username string) erro
 	namespace := &corev1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: namespaceName,
-			Labels: map[string]string{
+			Labels: withExtraMetadata(withInstanceLabel(map[string]string{
 				"app.kubernetes.io/managed-by": "db-saas",
 				"db-saas/user-id":              fmt.Sprintf("%d", userID),
 				"db-saas/username":             username,
 				"db-saas/type":                 "user-namespace",
-			},
-			Annotations: map[string]string{
+			}), appConfig.NamespaceLabels),
+			Annotations: withExtraMetadata(map[string]string{
 				"db-saas/created-for": fmt.Sprintf("User %s (ID: %d)", username, userID),
 				"db-saas/description": "Dedicated namespace for user databases and resources",
-			},
+			}, appConfig.NamespaceAnnotations),
 		},
 	}
 
@@ -262,13 +447,24 @@ func createUserNamespace(namespaceName string, userID int, username string) erro
 	return nil
 }
 
+// withExtraMetadata merges operator-configured labels or annotations into the
+// managed set; managed keys always win so ownership labels cannot be overridden
+func withExtraMetadata(managed, extra map[string]string) map[string]string {
+	for key, value := range extra {
+		if _, exists := managed[key]; !exists {
+			managed[key] = value
+		}
+	}
+	return managed
+}
+
 // createKubeClients creates Kubernetes client instances for YAML deployment
 func createKubeClients() (*kubeClients, error) {
 	kubeconfig := "kubeconfig.yaml"
 
 	// Try different kubeconfig locations
 	if _, err := os.Stat(kubeconfig); os.IsNotExist(err) {
-		kubeconfig = os.Getenv("KUBECONFIG")
+		kubeconfig = appConfig.Kubeconfig
 		if kubeconfig == "" {
 			homeDir, err := os.UserHomeDir()
 			if err != nil {
@@ -304,21 +500,30 @@ func createKubeClients() (*kubeClients, error) {
 func deployYAMLContent(yamlContent string, namespace string) error {
 	yamlDocs := strings.Split(yamlContent, "---")
 
+	// Decode and check every document first, so a disallowed kind applies nothing
+	var objects []*unstructured.Unstructured
 	for i, yamlDoc := range yamlDocs {
 		yamlDoc = strings.TrimSpace(yamlDoc)
 		if yamlDoc == "" {
 			continue
 		}
 
-		fmt.Printf("📄 Processing YAML document %d/%d\n", i+1, len(yamlDocs))
-
 		decoder := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
 		obj := &unstructured.Unstructured{}
 
 		_, gvk, err := decoder.Decode([]byte(yamlDoc), nil, obj)
 		if err != nil {
-			return fmt.Errorf("error decoding YAML document %d: %w", i+1, err)
+			return apperrors.New(apperrors.ErrInvalidInput, "error decoding YAML document %d: %v", i+1, err)
+		}
+		if !allowedDeployKinds[gvk.GroupKind()] {
+			return apperrors.New(apperrors.ErrInvalidInput, "YAML document %d: kind %s is not allowed", i+1, gvk.GroupKind())
 		}
+		objects = append(objects, obj)
+	}
+
+	for i, obj := range objects {
+		gvk := obj.GroupVersionKind()
+		fmt.Printf("📄 Processing YAML document %d/%d\n", i+1, len(objects))
 
 		if namespace != "" {
 			obj.SetNamespace(namespace)
@@ -332,7 +537,7 @@ func deployYAMLContent(yamlContent string, namespace string) error {
 
 		dr := clients.dynamicClient.Resource(gvr)
 
-		_, err = dr.Namespace(obj.GetNamespace()).Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+		_, err := dr.Namespace(obj.GetNamespace()).Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
 		if err != nil {
 			if errors.IsNotFound(err) {
 				fmt.Printf("Creating %s '%s' in namespace '%s'\n", gvk.Kind, obj.GetName(), obj.GetNamespace())
@@ -355,6 +560,21 @@ func deployYAMLContent(yamlContent string, namespace string) error {
 	return nil
 }
 
+// allowedDeployKinds are the only kinds the generic YAML deploy endpoint may
+// apply, all namespaced, so cluster-scoped objects like ClusterRoles can never
+// be created through it
+var allowedDeployKinds = map[schema.GroupKind]bool{
+	{Group: "apps", Kind: "Deployment"}:                  true,
+	{Group: "", Kind: "Service"}:                         true,
+	{Group: "", Kind: "ConfigMap"}:                       true,
+	{Group: "", Kind: "Secret"}:                          true,
+	{Group: "", Kind: "PersistentVolumeClaim"}:           true,
+	{Group: "traefik.io", Kind: "IngressRoute"}:          true,
+	{Group: "traefik.io", Kind: "Middleware"}:            true,
+	{Group: "traefik.containo.us", Kind: "IngressRoute"}: true,
+	{Group: "traefik.containo.us", Kind: "Middleware"}:   true,
+}
+
 // getPlural returns the plural form of common Kubernetes resources
 func getPlural(kind string) string {
 	switch kind {
@@ -387,48 +607,26 @@ func getPlural(kind string) string {
 
 // sendErrorResponse sends an error response to the client
 func sendErrorResponse(w http.ResponseWriter, errorMessage string) {
-	response := DeploymentResponse{
-		Success: false,
-		Message: errorMessage,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusInternalServerError)
-	json.NewEncoder(w).Encode(response)
+	respondError(w, http.StatusInternalServerError, errorMessage)
 }
 
 // sendSuccessResponse sends a success response to the client
 func sendSuccessResponse(w http.ResponseWriter, name string) {
-	response := DeploymentResponse{
-		Success: true,
+	respondSuccess(w, http.StatusOK, DeploymentResponse{
 		Message: "Deployment successful",
 		Name:    name,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	})
 }
 
 // sendNamespaceErrorResponse sends an error response for namespace operations
 func sendNamespaceErrorResponse(w http.ResponseWriter, errorMessage string) {
-	response := NamespaceResponse{
-		Success: false,
-		Message: errorMessage,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusInternalServerError)
-	json.NewEncoder(w).Encode(response)
+	respondError(w, http.StatusInternalServerError, errorMessage)
 }
 
 // sendNamespaceSuccessResponse sends a success response for namespace operations
 func sendNamespaceSuccessResponse(w http.ResponseWriter, namespaceName string) {
-	response := NamespaceResponse{
-		Success:   true,
+	respondSuccess(w, http.StatusOK, NamespaceResponse{
 		Message:   "Namespace created successfully",
 		Namespace: namespaceName,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	})
 }
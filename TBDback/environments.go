@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/BouchamiAhmed/TBD/apperrors"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// environmentLabel records a database's environment tier on its deployment
+const environmentLabel = "db-saas/environment"
+
+// Environment tiers a database may be labeled with
+const (
+	EnvironmentDev     = "dev"
+	EnvironmentStaging = "staging"
+	EnvironmentProd    = "prod"
+)
+
+// environmentDefaults is the operational policy applied to a tier; explicit
+// request fields always win over it
+type environmentDefaults struct {
+	Profile             string `json:"profile"`
+	PodDisruptionBudget bool   `json:"podDisruptionBudget"`
+	BackupSchedule      string `json:"backupSchedule,omitempty"`
+}
+
+// environmentPolicies maps each tier to its defaults. Tune the policy here.
+var environmentPolicies = map[string]environmentDefaults{
+	EnvironmentDev:     {Profile: "small", PodDisruptionBudget: false},
+	EnvironmentStaging: {Profile: "medium", PodDisruptionBudget: false},
+	EnvironmentProd:    {Profile: "large", PodDisruptionBudget: true, BackupSchedule: "0 2 * * *"},
+}
+
+// applyEnvironmentDefaults normalizes the request's environment and fills
+// the fields it left unset from that environment's policy. Requests without
+// an environment keep the global defaults.
+func applyEnvironmentDefaults(dbRequest *DatabaseRequest) error {
+	if dbRequest.Environment == "" {
+		return nil
+	}
+
+	environment := strings.ToLower(strings.TrimSpace(dbRequest.Environment))
+	defaults, ok := environmentPolicies[environment]
+	if !ok {
+		return apperrors.New(apperrors.ErrInvalidInput, "unknown environment '%s' (supported: %s)", dbRequest.Environment, strings.Join(supportedEnvironments(), ", "))
+	}
+	dbRequest.Environment = environment
+
+	if dbRequest.Profile == "" {
+		dbRequest.Profile = defaults.Profile
+	}
+	if dbRequest.PodDisruptionBudget == nil {
+		enabled := defaults.PodDisruptionBudget
+		dbRequest.PodDisruptionBudget = &enabled
+	}
+	if dbRequest.BackupSchedule == "" {
+		dbRequest.BackupSchedule = defaults.BackupSchedule
+	}
+	return nil
+}
+
+// supportedEnvironments returns the known environment tiers, sorted
+func supportedEnvironments() []string {
+	environments := make([]string, 0, len(environmentPolicies))
+	for environment := range environmentPolicies {
+		environments = append(environments, environment)
+	}
+	sort.Strings(environments)
+	return environments
+}
+
+// applyEnvironmentLabel labels a database deployment with its environment
+func applyEnvironmentLabel(deployment *appsv1.Deployment, dbRequest DatabaseRequest) {
+	if dbRequest.Environment == "" {
+		return
+	}
+	deployment.Labels[environmentLabel] = dbRequest.Environment
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const (
+	// maxExecOutput caps how much stdout and stderr are each captured
+	maxExecOutput = 64 * 1024
+	// execTimeout bounds how long a one-off query may run
+	execTimeout = 30 * time.Second
+)
+
+// ExecRequest is a one-off SQL statement to run inside a database pod
+type ExecRequest struct {
+	SQL string `json:"sql"`
+}
+
+// ExecResult holds the captured output of an exec call
+type ExecResult struct {
+	Pod       string `json:"pod"`
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	Truncated bool   `json:"truncated"`
+	Error     string `json:"error,omitempty"`
+}
+
+// sqlExecCommand returns the command running sql with the database's own
+// client and credentials. The statement is passed as a positional argument,
+// never interpolated into the shell script, so only SQL can be executed.
+func sqlExecCommand(dbType, sql string) ([]string, error) {
+	switch dbType {
+	case DatabaseTypePostgreSQL:
+		return []string{"sh", "-c", `psql -U "$POSTGRES_USER" -d "$POSTGRES_DB" -v ON_ERROR_STOP=1 -c "$1"`, "sh", sql}, nil
+	case DatabaseTypeMySQL:
+		return []string{"sh", "-c", `MYSQL_PWD="$MYSQL_PASSWORD" mysql -u "$MYSQL_USER" "$MYSQL_DATABASE" -e "$1"`, "sh", sql}, nil
+	}
+	return nil, fmt.Errorf("exec is not supported for database type %s", dbType)
+}
+
+// limitedBuffer keeps at most limit bytes, discarding (but accepting) the rest
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write buffers p up to the limit and always reports a full write so the
+// stream keeps draining
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); remaining < len(p) {
+		b.truncated = true
+		if remaining > 0 {
+			b.buf.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// findDatabasePod returns a running pod of the named database deployment
+func findDatabasePod(ctx context.Context, namespace, dbName string) (*corev1.Pod, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=" + dbName,
+		FieldSelector: "status.phase=Running",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	for i := range pods.Items {
+		if pods.Items[i].DeletionTimestamp == nil {
+			return &pods.Items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no running pod found for database %s", dbName)
+}
+
+// execSQL runs a SQL statement in the database's pod and returns its output.
+// A non-zero exit of the client is reported in the result, not as an error.
+func execSQL(ctx context.Context, namespace, dbName, sql string) (*ExecResult, error) {
+	if kubeRestConfig == nil {
+		return nil, fmt.Errorf("kubernetes client configuration not available")
+	}
+
+	dbType, err := getDatabaseType(ctx, dbName, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine database type: %w", err)
+	}
+
+	command, err := sqlExecCommand(dbType, sql)
+	if err != nil {
+		return nil, err
+	}
+
+	pod, err := findDatabasePod(ctx, namespace, dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: pod.Spec.Containers[0].Name,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(kubeRestConfig, "POST", req.URL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create executor: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, execTimeout)
+	defer cancel()
+
+	stdout := &limitedBuffer{limit: maxExecOutput}
+	stderr := &limitedBuffer{limit: maxExecOutput}
+	streamErr := executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+
+	result := &ExecResult{
+		Pod:       pod.Name,
+		Stdout:    stdout.buf.String(),
+		Stderr:    stderr.buf.String(),
+		Truncated: stdout.truncated || stderr.truncated,
+	}
+	if streamErr != nil {
+		result.Error = streamErr.Error()
+	}
+	return result, nil
+}
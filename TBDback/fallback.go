@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// notFoundHandler responds to requests for unregistered paths
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	respondErrorCode(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("No route for %s %s", r.Method, r.URL.Path))
+}
+
+// methodNotAllowedHandler responds to requests whose path is registered but
+// not for their method, listing the accepted methods in the Allow header
+func methodNotAllowedHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed := allowedMethods(router, r)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		respondErrorCode(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED",
+			fmt.Sprintf("Method %s not allowed for %s (allowed: %s)", r.Method, r.URL.Path, strings.Join(allowed, ", ")))
+	})
+}
+
+// allowedMethods returns the methods the router accepts for the request's path,
+// found by matching the request against each method the router uses
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	var allowed []string
+	for _, method := range routeMethods(router) {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+
+		var match mux.RouteMatch
+		if router.Match(probe, &match) && match.MatchErr == nil {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// healthCheckWorkers bounds how many databases are probed concurrently
+const healthCheckWorkers = 8
+
+// healthCheckTimeout bounds a whole health summary; databases not probed in
+// time are reported unreachable
+const healthCheckTimeout = 5 * time.Second
+
+// healthDialTimeout bounds the connection attempt to a single database
+const healthDialTimeout = 2 * time.Second
+
+// DatabaseHealth is the health of a single database
+type DatabaseHealth struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Status    string `json:"status"`
+	Reachable bool   `json:"reachable"`
+}
+
+// HealthSummary aggregates the health of all databases in a namespace
+type HealthSummary struct {
+	Namespace   string           `json:"namespace"`
+	Databases   []DatabaseHealth `json:"databases"`
+	Total       int              `json:"total"`
+	Running     int              `json:"running"`
+	Failed      int              `json:"failed"`
+	Unreachable int              `json:"unreachable"`
+}
+
+// summarizeNamespaceHealth reports the phase of every database in a namespace
+// and whether its service accepts TCP connections, probing concurrently
+func summarizeNamespaceHealth(ctx context.Context, namespace string) (*HealthSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	deployments, err := listDatabaseDeployments(ctx, namespace, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list databases: %w", err)
+	}
+
+	phases, err := listDatabasePhases(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database phases: %w", err)
+	}
+
+	databases := make([]DatabaseHealth, len(deployments))
+	for i, deployment := range deployments {
+		status, ok := phases[deployment.Name]
+		if !ok {
+			status = DatabaseStatusProvisioning
+		}
+		databases[i] = DatabaseHealth{
+			Name:   deployment.Name,
+			Type:   labeledDatabaseType(deployment.Labels),
+			Status: status,
+		}
+	}
+
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	for i := 0; i < healthCheckWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				db := &databases[idx]
+				db.Reachable = databaseReachable(ctx, namespace, db.Name, db.Type)
+			}
+		}()
+	}
+
+	for idx := range databases {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(databases, func(i, j int) bool { return databases[i].Name < databases[j].Name })
+
+	summary := &HealthSummary{Namespace: namespace, Databases: databases, Total: len(databases)}
+	for _, db := range databases {
+		switch db.Status {
+		case DatabaseStatusRunning:
+			summary.Running++
+		case DatabaseStatusFailed:
+			summary.Failed++
+		}
+		if !db.Reachable {
+			summary.Unreachable++
+		}
+	}
+	return summary, nil
+}
+
+// databaseReachable reports whether a database's service accepts a TCP
+// connection within healthDialTimeout
+func databaseReachable(ctx context.Context, namespace, name, dbType string) bool {
+	port, err := defaultPort(dbType)
+	if err != nil || ctx.Err() != nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthDialTimeout)
+	defer cancel()
+
+	address := net.JoinHostPort(fmt.Sprintf("%s.%s.svc.cluster.local", name, namespace), port)
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
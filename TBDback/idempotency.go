@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// idempotencyKeyHeader lets clients retry a create safely: a repeated key
+// replays the original response instead of deploying again
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyReplayedHeader marks a response replayed from an earlier request
+const idempotencyReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKeyLength bounds the stored key
+const maxIdempotencyKeyLength = 255
+
+// idempotencySweepInterval is how often expired idempotency keys are deleted
+const idempotencySweepInterval = 15 * time.Minute
+
+// defaultIdempotencyStaleAfter is how long an incomplete key blocks retries
+// when neither HTTP_REQUEST_TIMEOUT nor HTTP_WRITE_TIMEOUT bounds requests
+const defaultIdempotencyStaleAfter = 10 * time.Minute
+
+// idempotencyStaleAfter returns how long an incomplete key blocks retries. A
+// request holding a key cannot outlive the request timeout, so an incomplete
+// key older than that was left by a replica that crashed mid-request.
+func idempotencyStaleAfter() time.Duration {
+	if appConfig == nil {
+		return defaultIdempotencyStaleAfter
+	}
+	if timeout := appConfig.HTTPServer.RequestTimeout; timeout > 0 {
+		return timeout
+	}
+	if timeout := appConfig.HTTPServer.WriteTimeout; timeout > 0 {
+		return timeout
+	}
+	return defaultIdempotencyStaleAfter
+}
+
+// storedResponse is the response recorded for an idempotency key. Completed
+// is false while the original request is still running.
+type storedResponse struct {
+	RequestHash string
+	StatusCode  int
+	Body        []byte
+	Completed   bool
+}
+
+// ReserveIdempotencyKey claims a key for a new request. When the key is
+// already taken (and not older than expiresBefore) it returns the stored
+// response instead, and reserved is false. An incomplete key created at or
+// before staleBefore is reclaimed, since the request holding it is gone.
+func (c *DBClient) ReserveIdempotencyKey(ctx context.Context, userID int, key, requestHash string, expiresBefore, staleBefore time.Time) (*storedResponse, bool, error) {
+	// An expired key, or one abandoned mid-request, is free to reuse
+	if _, err := c.db.ExecContext(ctx, `
+	DELETE FROM idempotency_keys
+	WHERE user_id = $1 AND key = $2 AND (created_at <= $3 OR (NOT completed AND created_at <= $4))`, userID, key, expiresBefore, staleBefore); err != nil {
+		return nil, false, fmt.Errorf("error clearing expired idempotency key: %w", err)
+	}
+
+	result, err := c.db.ExecContext(ctx, `
+	INSERT INTO idempotency_keys (user_id, key, request_hash)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (user_id, key) DO NOTHING`, userID, key, requestHash)
+	if err != nil {
+		return nil, false, fmt.Errorf("error reserving idempotency key: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected > 0 {
+		return nil, true, nil
+	}
+
+	var stored storedResponse
+	err = c.db.QueryRowContext(ctx, `
+	SELECT request_hash, status_code, COALESCE(response, ''::bytea), completed
+	FROM idempotency_keys
+	WHERE user_id = $1 AND key = $2`, userID, key).Scan(&stored.RequestHash, &stored.StatusCode, &stored.Body, &stored.Completed)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Released between the insert and the select; let the caller retry
+		return &storedResponse{RequestHash: requestHash}, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading idempotency key: %w", err)
+	}
+	return &stored, false, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request holding a key
+func (c *DBClient) CompleteIdempotencyKey(userID int, key string, statusCode int, body []byte) error {
+	_, err := c.db.Exec(`
+	UPDATE idempotency_keys SET status_code = $3, response = $4, completed = TRUE
+	WHERE user_id = $1 AND key = $2`, userID, key, statusCode, body)
+	if err != nil {
+		return fmt.Errorf("error storing idempotent response: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey frees a key whose request failed, so it can be retried
+func (c *DBClient) ReleaseIdempotencyKey(userID int, key string) error {
+	if _, err := c.db.Exec(`DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2`, userID, key); err != nil {
+		return fmt.Errorf("error releasing idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys removes keys created at or before expiresBefore
+func (c *DBClient) DeleteExpiredIdempotencyKeys(expiresBefore time.Time) (int64, error) {
+	result, err := c.db.Exec(`DELETE FROM idempotency_keys WHERE created_at <= $1`, expiresBefore)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired idempotency keys: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected, nil
+}
+
+// runIdempotencySweeper periodically deletes expired idempotency keys
+func runIdempotencySweeper(dbClient *DBClient, interval, ttl time.Duration) {
+	fmt.Printf("🧹 Idempotency key sweeper started (interval %s, ttl %s)\n", interval, ttl)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := dbClient.DeleteExpiredIdempotencyKeys(time.Now().Add(-ttl))
+		if err != nil {
+			fmt.Printf("⚠️  Idempotency key sweep failed: %v\n", err)
+			continue
+		}
+		if deleted > 0 {
+			fmt.Printf("🧹 Deleted %d expired idempotency keys\n", deleted)
+		}
+	}
+}
+
+// idempotent makes a create handler safe to retry. A request carrying an
+// Idempotency-Key header is run once per user and key; repeats within
+// IDEMPOTENCY_KEY_TTL get the original response back. Only successful
+// responses are kept, so a failed create can be retried with the same key.
+// Must run inside requireAuth; without a database or a key it is a no-op.
+func idempotent(dbClient *DBClient, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if dbClient == nil || key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(sum[:])
+		userID := authFromContext(r.Context()).UserID
+
+		now := time.Now()
+		stored, reserved, err := dbClient.ReserveIdempotencyKey(r.Context(), userID, key, requestHash, now.Add(-appConfig.IdempotencyKeyTTL), now.Add(-idempotencyStaleAfter()))
+		if err != nil {
+			logf(r.Context(), "Error reserving idempotency key: %v\n", err)
+			respondError(w, http.StatusInternalServerError, "Failed to check idempotency key")
+			return
+		}
+
+		if !reserved {
+			switch {
+			case stored.RequestHash != requestHash:
+				respondError(w, http.StatusUnprocessableEntity, fmt.Sprintf("%s was already used with a different request body", idempotencyKeyHeader))
+			case !stored.Completed:
+				respondError(w, http.StatusConflict, fmt.Sprintf("A request with this %s is still in progress", idempotencyKeyHeader))
+			default:
+				logf(r.Context(), "🔁 Replaying response for idempotency key %q\n", key)
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set(idempotencyReplayedHeader, "true")
+				w.WriteHeader(stored.StatusCode)
+				w.Write(stored.Body)
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+
+		if recorder.status >= 200 && recorder.status < 300 {
+			err = dbClient.CompleteIdempotencyKey(userID, key, recorder.status, recorder.body.Bytes())
+		} else {
+			err = dbClient.ReleaseIdempotencyKey(userID, key)
+		}
+		if err != nil {
+			logf(r.Context(), "⚠️  %v\n", err)
+		}
+	}
+}
+
+// responseRecorder captures the status code and body written by a handler
+// while passing them through
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status code before writing it
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body before writing it
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
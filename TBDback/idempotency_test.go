@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdempotencyStaleAfterFollowsRequestTimeouts(t *testing.T) {
+	cfg := useTestConfig(t)
+
+	cfg.HTTPServer.RequestTimeout = 45 * time.Second
+	cfg.HTTPServer.WriteTimeout = 60 * time.Second
+	if got := idempotencyStaleAfter(); got != 45*time.Second {
+		t.Errorf("with HTTP_REQUEST_TIMEOUT: idempotencyStaleAfter() = %s, want 45s", got)
+	}
+
+	cfg.HTTPServer.RequestTimeout = 0
+	if got := idempotencyStaleAfter(); got != 60*time.Second {
+		t.Errorf("without HTTP_REQUEST_TIMEOUT: idempotencyStaleAfter() = %s, want the write timeout 60s", got)
+	}
+
+	cfg.HTTPServer.WriteTimeout = 0
+	if got := idempotencyStaleAfter(); got != defaultIdempotencyStaleAfter {
+		t.Errorf("without timeouts: idempotencyStaleAfter() = %s, want %s", got, defaultIdempotencyStaleAfter)
+	}
+}
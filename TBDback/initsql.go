@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BouchamiAhmed/TBD/apperrors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	initSQLVolumeName = "init-sql"
+	initSQLMountPath  = "/docker-entrypoint-initdb.d"
+	initSQLFileName   = "init.sql"
+)
+
+// maxInitSQLSize is the largest init script accepted, in bytes. A ConfigMap
+// may hold at most 1MiB; the rest is left for the object's metadata.
+const maxInitSQLSize = 1<<20 - 16<<10
+
+// validateInitSQL rejects init scripts that cannot be stored in a ConfigMap or
+// run by the database: oversized, non-UTF-8 or containing NUL bytes
+func validateInitSQL(script string) error {
+	if script == "" {
+		return nil
+	}
+	if len(script) > maxInitSQLSize {
+		return apperrors.New(apperrors.ErrInvalidInput, "init SQL script is %d bytes, the maximum is %d", len(script), maxInitSQLSize)
+	}
+	if !utf8.ValidString(script) {
+		return apperrors.New(apperrors.ErrInvalidInput, "init SQL script must be valid UTF-8")
+	}
+	if strings.ContainsRune(script, 0) {
+		return apperrors.New(apperrors.ErrInvalidInput, "init SQL script must not contain NUL bytes")
+	}
+	if strings.TrimSpace(script) == "" {
+		return apperrors.New(apperrors.ErrInvalidInput, "init SQL script is blank")
+	}
+	return nil
+}
+
+// initSQLConfigMapName returns the name of the ConfigMap holding a database's init script
+func initSQLConfigMapName(dbName string) string {
+	return dbName + "-init-sql"
+}
+
+// createInitSQLConfigMap stores the init SQL script of a database request in a ConfigMap
+func createInitSQLConfigMap(ctx context.Context, clientset kubernetes.Interface, dbRequest DatabaseRequest, namespace string) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      initSQLConfigMapName(dbRequest.Name),
+			Namespace: namespace,
+			Labels: withDatabaseLabels(map[string]string{
+				"app": dbRequest.Name,
+			}, dbRequest),
+		},
+		Data: map[string]string{
+			initSQLFileName: dbRequest.InitSQL,
+		},
+	}
+
+	if _, err := clientset.CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create init SQL ConfigMap: %w", err)
+	}
+	logf(ctx, "✅ Created init SQL ConfigMap: %s\n", configMap.Name)
+	logf(ctx, "⚠️ Init scripts only run when the database data volume is empty\n")
+	return nil
+}
+
+// addInitSQLVolume mounts the init SQL ConfigMap into the database container's
+// /docker-entrypoint-initdb.d, which both the Postgres and MySQL images execute on first boot
+func addInitSQLVolume(deployment *appsv1.Deployment, dbRequest DatabaseRequest) {
+	if dbRequest.InitSQL == "" {
+		return
+	}
+
+	podSpec := &deployment.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: initSQLVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: initSQLConfigMapName(dbRequest.Name)},
+			},
+		},
+	})
+	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      initSQLVolumeName,
+		MountPath: initSQLMountPath + "/" + initSQLFileName,
+		SubPath:   initSQLFileName,
+		ReadOnly:  true,
+	})
+}
+
+// deleteInitSQLConfigMap removes a database's init SQL ConfigMap if it exists
+func deleteInitSQLConfigMap(ctx context.Context, dbName, namespace string) {
+	err := clientset.CoreV1().ConfigMaps(namespace).Delete(ctx, initSQLConfigMapName(dbName), metav1.DeleteOptions{})
+	switch {
+	case err == nil:
+		logf(ctx, "✅ Deleted init SQL ConfigMap\n")
+	case !errors.IsNotFound(err):
+		logf(ctx, "Warning: Failed to delete init SQL ConfigMap: %v\n", err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// instanceLabel records on each namespace the NAMESPACE_PREFIX of the db-saas
+// instance that created it, so instances sharing a cluster only list their own
+const instanceLabel = "db-saas/instance"
+
+// instanceName returns the NAMESPACE_PREFIX as a label value, or "" when unset
+func instanceName() string {
+	return strings.Trim(appConfig.NamespacePrefix, "-.")
+}
+
+// withInstanceLabel adds the instance label to a namespace's labels when a
+// NAMESPACE_PREFIX is configured
+func withInstanceLabel(namespaceLabels map[string]string) map[string]string {
+	if instance := instanceName(); instance != "" {
+		namespaceLabels[instanceLabel] = instance
+	}
+	return namespaceLabels
+}
+
+// managedNamespaceSelector matches the managed namespaces of this instance:
+// those labeled with its instance name or, without a NAMESPACE_PREFIX, those
+// carrying no instance label, so prefixed instances' namespaces are left alone
+func managedNamespaceSelector() labels.Selector {
+	selector := labels.SelectorFromSet(labels.Set{"app.kubernetes.io/managed-by": "db-saas"})
+
+	var instance *labels.Requirement
+	if name := instanceName(); name != "" {
+		instance, _ = labels.NewRequirement(instanceLabel, selection.Equals, []string{name})
+	} else {
+		instance, _ = labels.NewRequirement(instanceLabel, selection.DoesNotExist, nil)
+	}
+	return selector.Add(*instance)
+}
+
+// ownsNamespace reports whether a namespace belongs to this instance, judged
+// by its labels; a namespace that does not exist is not owned
+func ownsNamespace(ctx context.Context, namespace string) (bool, error) {
+	ns, err := getNamespace(ctx, namespace)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return managedNamespaceSelector().Matches(labels.Set(ns.Labels)), nil
+}
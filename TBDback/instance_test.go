@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestManagedNamespaceSelectorIsolatesInstances(t *testing.T) {
+	unprefixed := labels.Set{"app.kubernetes.io/managed-by": "db-saas"}
+	staging := labels.Set{"app.kubernetes.io/managed-by": "db-saas", instanceLabel: "staging"}
+	foreign := labels.Set{"app.kubernetes.io/managed-by": "someone-else"}
+
+	tests := []struct {
+		prefix string
+		labels labels.Set
+		want   bool
+	}{
+		{"", unprefixed, true},
+		{"", staging, false},
+		{"", foreign, false},
+		{"staging-", staging, true},
+		{"staging-", unprefixed, false},
+		{"prod-", staging, false},
+	}
+
+	for _, tt := range tests {
+		cfg := useTestConfig(t)
+		cfg.NamespacePrefix = tt.prefix
+
+		if got := managedNamespaceSelector().Matches(tt.labels); got != tt.want {
+			t.Errorf("prefix %q: selector matches %v = %v, want %v", tt.prefix, tt.labels, got, tt.want)
+		}
+	}
+}
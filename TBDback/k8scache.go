@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// managedBySelector matches every resource created by the platform
+	managedBySelector = "app.kubernetes.io/managed-by=db-saas"
+	// cacheResyncPeriod is how often the informers replay their full state
+	cacheResyncPeriod = 10 * time.Minute
+	// cacheSyncTimeout bounds how long startup waits for the initial list
+	cacheSyncTimeout = 30 * time.Second
+)
+
+// resourceCache is the watch-backed cache used by the listing endpoints;
+// nil until startResourceCache succeeds, in which case callers hit the API directly
+var resourceCache *k8sCache
+
+// k8sCache holds listers for the namespaces, deployments and services the
+// platform manages, kept up to date by shared informers
+type k8sCache struct {
+	namespaces  corelisters.NamespaceLister
+	deployments appslisters.DeploymentLister
+	services    corelisters.ServiceLister
+}
+
+// startResourceCache starts the shared informers and waits for their initial sync
+func startResourceCache(clientset kubernetes.Interface, stopCh <-chan struct{}) (*k8sCache, error) {
+	// Namespaces and deployments carry the managed-by label
+	managedFactory := informers.NewSharedInformerFactoryWithOptions(clientset, cacheResyncPeriod,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = managedBySelector
+		}))
+	// Database services are only labeled with their app name
+	serviceFactory := informers.NewSharedInformerFactoryWithOptions(clientset, cacheResyncPeriod,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = "app"
+		}))
+
+	namespaceInformer := managedFactory.Core().V1().Namespaces()
+	deploymentInformer := managedFactory.Apps().V1().Deployments()
+	serviceInformer := serviceFactory.Core().V1().Services()
+
+	c := &k8sCache{
+		namespaces:  namespaceInformer.Lister(),
+		deployments: deploymentInformer.Lister(),
+		services:    serviceInformer.Lister(),
+	}
+
+	managedFactory.Start(stopCh)
+	serviceFactory.Start(stopCh)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheSyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(ctx.Done(),
+		namespaceInformer.Informer().HasSynced,
+		deploymentInformer.Informer().HasSynced,
+		serviceInformer.Informer().HasSynced,
+	) {
+		return nil, fmt.Errorf("timed out waiting for informer caches to sync")
+	}
+
+	return c, nil
+}
+
+// listDatabaseDeployments returns the database deployments in a namespace that
+// also match filter (which must already be validated),
+// served from the cache when it is available
+func listDatabaseDeployments(ctx context.Context, namespace string, filter labels.Set) ([]*appsv1.Deployment, error) {
+	selector := labels.SelectorFromSet(labels.Set{
+		"app.kubernetes.io/managed-by": "db-saas",
+		"app.kubernetes.io/component":  "database",
+	})
+	// The filter is ANDed with the managed labels, so it can only narrow the result
+	if len(filter) > 0 {
+		requirements, _ := labels.SelectorFromValidatedSet(filter).Requirements()
+		selector = selector.Add(requirements...)
+	}
+
+	if resourceCache != nil {
+		return resourceCache.deployments.Deployments(namespace).List(selector)
+	}
+
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*appsv1.Deployment, 0, len(deployments.Items))
+	for i := range deployments.Items {
+		result = append(result, &deployments.Items[i])
+	}
+	return result, nil
+}
+
+// getNamespace returns a namespace, served from the cache when it is
+// available; only managed namespaces are cached
+func getNamespace(ctx context.Context, name string) (*corev1.Namespace, error) {
+	if resourceCache != nil {
+		return resourceCache.namespaces.Get(name)
+	}
+	return clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+}
+
+// getService returns a service, served from the cache when it is available
+func getService(ctx context.Context, namespace, name string) (*corev1.Service, error) {
+	if resourceCache != nil {
+		return resourceCache.services.Services(namespace).Get(name)
+	}
+	return clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// dbPingTimeout bounds a single keep-alive ping
+const dbPingTimeout = 5 * time.Second
+
+// runDBKeepalive periodically pings the control database so an outage (e.g. a
+// restart of its pod) shows up in the logs as soon as it happens, rather than
+// on the next user request. Failed pings are counted and logged once per
+// outage; the recovery is logged with how long the database was unreachable.
+func runDBKeepalive(dbClient *DBClient, interval time.Duration) {
+	fmt.Printf("💓 Database keep-alive started (interval %s)\n", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var (
+		downSince   time.Time
+		failedPings int
+		outages     int
+	)
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), dbPingTimeout)
+		err := dbClient.db.PingContext(ctx)
+		cancel()
+
+		if err != nil {
+			failedPings++
+			if downSince.IsZero() {
+				downSince = time.Now()
+				outages++
+				fmt.Printf("❌ Lost connection to PostgreSQL (outage #%d): %v\n", outages, err)
+			}
+			continue
+		}
+
+		if !downSince.IsZero() {
+			fmt.Printf("✅ Reconnected to PostgreSQL after %s (%d failed pings)\n", time.Since(downSince).Round(time.Second), failedPings)
+			dbClient.resetIdleConns()
+			downSince = time.Time{}
+			failedPings = 0
+		}
+	}
+}
+
+// resetIdleConns closes the idle connections opened before an outage. They
+// point at the old server process and would otherwise only be dropped one by
+// one as queries fail on them or DB_CONN_MAX_LIFETIME expires; replacements
+// are opened on demand and get a fresh lifetime.
+func (c *DBClient) resetIdleConns() {
+	c.db.SetMaxIdleConns(0)
+	c.db.SetMaxIdleConns(c.pool.MaxIdleConns)
+
+	stats := c.db.Stats()
+	fmt.Printf("🔄 Reset idle database connections (open: %d, in use: %d, closed for lifetime: %d)\n",
+		stats.OpenConnections, stats.InUse, stats.MaxLifetimeClosed)
+}
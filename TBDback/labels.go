@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// databaseLabel names the database every child object belongs to, so all of
+// a database's objects can be selected (and deleted) with a single selector
+const databaseLabel = "db-saas/database"
+
+// commonLabelKeys are the labels commonLabels sets
+var commonLabelKeys = []string{
+	"app.kubernetes.io/managed-by",
+	databaseLabel,
+	"db-saas/type",
+	"db-saas/user-id",
+	"db-saas/username",
+}
+
+// commonLabels returns the ownership labels carried by every object created
+// for a database: its name, type and the id and username of the user owning
+// it. Usernames that are not valid label values are left out.
+func commonLabels(dbName string, userID int, username, dbType string) map[string]string {
+	common := map[string]string{
+		"app.kubernetes.io/managed-by": "db-saas",
+		databaseLabel:                  dbName,
+		"db-saas/type":                 dbType,
+		"db-saas/user-id":              strconv.Itoa(userID),
+	}
+	if username != "" && len(validation.IsValidLabelValue(username)) == 0 {
+		common["db-saas/username"] = username
+	}
+	return common
+}
+
+// withDatabaseLabels adds the common labels of a database request to an
+// object's own labels
+func withDatabaseLabels(objectLabels map[string]string, dbRequest DatabaseRequest) map[string]string {
+	return withLabels(objectLabels, commonLabels(dbRequest.Name, dbRequest.UserID, dbRequest.UserName, dbRequest.Type))
+}
+
+// withDeploymentLabels adds the common labels of an existing database
+// deployment to an object's own labels, for objects created after the deploy
+func withDeploymentLabels(objectLabels map[string]string, deployment *appsv1.Deployment) map[string]string {
+	common := map[string]string{databaseLabel: deployment.Name}
+	for _, key := range commonLabelKeys {
+		if value, ok := deployment.Labels[key]; ok {
+			common[key] = value
+		}
+	}
+	return withLabels(objectLabels, common)
+}
+
+// withLabels copies labels into objectLabels, overriding existing keys
+func withLabels(objectLabels, extra map[string]string) map[string]string {
+	for key, value := range extra {
+		objectLabels[key] = value
+	}
+	return objectLabels
+}
+
+// databaseSelector selects every object labeled as belonging to a database
+func databaseSelector(dbName string) string {
+	return labels.SelectorFromSet(labels.Set{
+		"app.kubernetes.io/managed-by": "db-saas",
+		databaseLabel:                  dbName,
+	}).String()
+}
+
+// unstructuredLabels converts labels for an unstructured object's metadata
+func unstructuredLabels(objectLabels map[string]string) map[string]interface{} {
+	result := make(map[string]interface{}, len(objectLabels))
+	for key, value := range objectLabels {
+		result[key] = value
+	}
+	return result
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/BouchamiAhmed/TBD/apperrors"
+)
+
+// namespaceLocks holds one single-slot semaphore per namespace so that deploy
+// and delete operations on the same namespace serialize, while different
+// namespaces proceed in parallel. Entries are reference-counted and removed
+// once no operation holds or waits for them, so the map only grows with the
+// number of namespaces currently in use.
+//
+// NOTE: these locks are per-process. When lockDBClient is set, a Postgres
+// advisory lock is taken as well so that multiple API replicas sharing the
+// same database also serialize on the namespace.
+var (
+	namespaceLocksMu sync.Mutex
+	namespaceLocks   = map[string]*namespaceLock{}
+)
+
+// namespaceLock is a namespace semaphore together with the number of
+// operations currently holding or waiting for it
+type namespaceLock struct {
+	sem  chan struct{}
+	refs int
+}
+
+// lockDBClient is the database used for cross-replica advisory locks.
+// It stays nil when no database is configured.
+var lockDBClient *DBClient
+
+// namespaceLockTimeout bounds how long an operation waits for a namespace
+// lock held by another request or replica before giving up
+const namespaceLockTimeout = 30 * time.Second
+
+// lockNamespace acquires the lock for the given namespace and returns the
+// function that releases it. It gives up with an ErrConflict error when ctx
+// is done or the lock is still held after namespaceLockTimeout.
+func lockNamespace(ctx context.Context, namespace string) (func(), error) {
+	ctx, cancel := context.WithTimeout(ctx, namespaceLockTimeout)
+	defer cancel()
+
+	lock := acquireNamespaceLockEntry(namespace)
+	select {
+	case lock.sem <- struct{}{}:
+	case <-ctx.Done():
+		releaseNamespaceLockEntry(namespace, lock)
+		return nil, namespaceBusy(namespace)
+	}
+	unlock := func() {
+		<-lock.sem
+		releaseNamespaceLockEntry(namespace, lock)
+	}
+
+	if lockDBClient == nil {
+		return unlock, nil
+	}
+
+	releaseAdvisory, err := lockDBClient.AcquireAdvisoryLock(ctx, namespaceLockKey(namespace))
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		unlock()
+		return nil, namespaceBusy(namespace)
+	}
+	if err != nil {
+		fmt.Printf("⚠️  Warning: Could not acquire advisory lock for namespace '%s', using in-process lock only: %v\n", namespace, err)
+		return unlock, nil
+	}
+
+	return func() {
+		releaseAdvisory()
+		unlock()
+	}, nil
+}
+
+// acquireNamespaceLockEntry returns the lock entry for namespace, creating it
+// if needed, and takes a reference on it
+func acquireNamespaceLockEntry(namespace string) *namespaceLock {
+	namespaceLocksMu.Lock()
+	defer namespaceLocksMu.Unlock()
+
+	lock, ok := namespaceLocks[namespace]
+	if !ok {
+		lock = &namespaceLock{sem: make(chan struct{}, 1)}
+		namespaceLocks[namespace] = lock
+	}
+	lock.refs++
+	return lock
+}
+
+// releaseNamespaceLockEntry drops a reference taken by acquireNamespaceLockEntry
+// and removes the entry once nothing holds or waits for it
+func releaseNamespaceLockEntry(namespace string, lock *namespaceLock) {
+	namespaceLocksMu.Lock()
+	defer namespaceLocksMu.Unlock()
+
+	lock.refs--
+	if lock.refs == 0 {
+		delete(namespaceLocks, namespace)
+	}
+}
+
+// namespaceBusy is the error returned when a namespace lock could not be taken in time
+func namespaceBusy(namespace string) error {
+	return apperrors.New(apperrors.ErrConflict, "namespace '%s' is busy with another operation, try again later", namespace)
+}
+
+// namespaceLockKey hashes a namespace name into a Postgres advisory lock key
+func namespaceLockKey(namespace string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("db-saas/namespace/" + namespace))
+	return int64(h.Sum64())
+}
@@ -9,9 +9,12 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
+	"github.com/BouchamiAhmed/TBD/apperrors"
+	"github.com/BouchamiAhmed/TBD/config"
+	"github.com/BouchamiAhmed/TBD/shared"
 	"github.com/gorilla/mux"
-	"github.com/rs/cors"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -20,18 +23,62 @@ import (
 
 // Add global dynamic client for Traefik resources
 var dynamicClient dynamic.Interface
-var clientset *kubernetes.Clientset
+var clientset kubernetes.Interface
+
+// kubeRestConfig is the configuration clientset was built from, needed for
+// streaming subresources such as exec
+var kubeRestConfig *rest.Config
+
+// appConfig holds the configuration read from the environment at startup
+var appConfig *config.Config
+
+// Build information, injected at build time with
+// -ldflags "-X main.version=... -X main.gitCommit=... -X main.buildTime=..."
+var (
+	version   = "dev"
+	gitCommit = "dev"
+	buildTime = "dev"
+)
 
 func main() {
 	fmt.Println("╔════════════════════════════════════════════════════════════╗")
 	fmt.Println("║                K3s Database SaaS API Server                ║")
 	fmt.Println("╚════════════════════════════════════════════════════════════╝")
+	fmt.Printf("📦 Version: %s (commit %s, built %s)\n", version, gitCommit, buildTime)
 
-	// Get database host from environment or use default
-	dbHost := os.Getenv("DB_HOST")
-	if dbHost == "" {
-		dbHost = "10.9.21.201"
+	// Load configuration and feature flags from the environment
+	appConfig = config.Load()
+	fmt.Printf("🚩 Feature flags: %+v\n", appConfig.Features)
+	if err := validateNamespaceMetadata(appConfig.NamespaceLabels, appConfig.NamespaceAnnotations); err != nil {
+		log.Fatalf("Invalid namespace metadata configuration: %v", err)
+	}
+	switch appConfig.ImagePullPolicy {
+	case "", "Always", "IfNotPresent", "Never":
+	default:
+		log.Printf("Warning: Ignoring invalid IMAGE_PULL_POLICY %q (expected Always, IfNotPresent or Never)", appConfig.ImagePullPolicy)
+	}
+	switch appConfig.Probes.Type {
+	case ProbeTypeExec, ProbeTypeTCP, ProbeTypeOff:
+	default:
+		log.Printf("Warning: Ignoring invalid PROBE_TYPE %q (expected exec, tcp or off), using exec", appConfig.Probes.Type)
+		appConfig.Probes.Type = ProbeTypeExec
+	}
+	if !shared.ValidTraefikMatcherVersion(appConfig.TraefikMatcherVersion) {
+		log.Printf("Warning: Unknown TRAEFIK_MATCHER_VERSION %q, using %s", appConfig.TraefikMatcherVersion, shared.TraefikMatcherV2)
+		appConfig.TraefikMatcherVersion = shared.TraefikMatcherV2
 	}
+	if err := pgAdminRouting().Validate(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	initTokenSecret(appConfig.JWTSecret)
+	loadTrustedProxies(appConfig.TrustedProxies)
+
+	// Profiling is off by default; when enabled it listens on its own address
+	if appConfig.Features.Pprof {
+		startPprofServer(appConfig.PprofAddr)
+	}
+
+	dbHost := appConfig.DBHost
 	fmt.Printf("🔄 Using database host: %s\n", dbHost)
 
 	// Initialize Kubernetes client
@@ -43,6 +90,16 @@ func main() {
 		clientset = nil
 	} else {
 		log.Println("Successfully connected to Kubernetes cluster")
+
+		// Serve listings from a watch-backed cache instead of polling the API server
+		resourceCache, err = startResourceCache(clientset, make(chan struct{}))
+		if err != nil {
+			log.Printf("Warning: Could not start resource cache: %v", err)
+			log.Println("Listings will query the Kubernetes API directly")
+			resourceCache = nil
+		} else {
+			log.Println("Successfully synced resource cache")
+		}
 	}
 
 	// Initialize dynamic client for Traefik resources
@@ -54,170 +111,755 @@ func main() {
 		log.Println("Successfully initialized dynamic client for Traefik")
 	}
 
+	// Optionally self-heal databases whose Service or Traefik routing went missing
+	if appConfig.ReconcileInterval > 0 && clientset != nil && dynamicClient != nil {
+		go runReconcileLoop(appConfig.ReconcileInterval)
+	}
+
 	// Initialize database client with configurable host
-	dbClient, err := NewDBClient(dbHost)
+	dbClient, err := NewDBClient(dbHost, appConfig.DBPassword, appConfig.DBPasswordFile, appConfig.DBPool)
 	if err != nil {
 		log.Printf("Warning: Could not connect to PostgreSQL database: %v", err)
 		log.Println("Database functionality will not be available")
 		dbClient = nil
 	} else {
-		// Initialize database tables
-		if err := dbClient.CreateTablesIfNotExist(); err != nil {
-			log.Printf("Error initializing database tables: %v", err)
+		// Bring the schema up to date before anything queries it
+		if err := dbClient.Migrate(); err != nil {
+			log.Printf("Error migrating database schema: %v", err)
 		}
 		defer dbClient.Close()
+
+		// Detect control database outages before a user request hits one
+		if appConfig.DBPool.PingInterval > 0 {
+			go runDBKeepalive(dbClient, appConfig.DBPool.PingInterval)
+		}
+
+		// Use Postgres advisory locks so multiple replicas serialize per namespace
+		lockDBClient = dbClient
+
+		// Write the phases the phase watcher observes back to the records
+		phaseDBClient = dbClient
+
+		// Track issued tokens server-side so sessions can be listed and revoked
+		sessionDBClient = dbClient
+		go runSessionSweeper(dbClient, sessionSweepInterval)
+
+		// Remember create responses so retries with an Idempotency-Key are replayed
+		go runIdempotencySweeper(dbClient, idempotencySweepInterval, appConfig.IdempotencyKeyTTL)
+	}
+
+	// Report phase transitions and keep record statuses current, independently
+	// of the reconcile loop
+	if appConfig.PhaseWatchInterval > 0 && clientset != nil {
+		go runPhaseWatcher(appConfig.PhaseWatchInterval)
 	}
 
 	// Initialize router
 	r := mux.NewRouter()
+	r.Use(requestIDMiddleware)
+	r.Use(timeoutMiddleware(appConfig.HTTPServer.RequestTimeout, appConfig.HTTPServer.StreamingPaths))
 
 	// Root endpoint
 	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Println("API root accessed")
-		w.Write([]byte("K3s Database SaaS API is running"))
+		respondSuccess(w, http.StatusOK, map[string]interface{}{
+			"service": "K3s Database SaaS API",
+			"status":  "running",
+			"version": version,
+		})
 	}).Methods("GET")
 
+	// Build version endpoint
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		respondSuccess(w, http.StatusOK, map[string]interface{}{
+			"version":   version,
+			"gitCommit": gitCommit,
+			"buildTime": buildTime,
+		})
+	}).Methods("GET")
+
+	// OpenAPI description of the API
+	r.HandleFunc("/api/openapi.json", handleOpenAPISpec).Methods("GET")
+
 	// Database creation endpoint - UPDATED TO MATCH ACTUAL INGRESSROUTE PATTERN
-	r.HandleFunc("/api/databases", func(w http.ResponseWriter, r *http.Request) {
+	r.HandleFunc("/api/databases", requireAuth(idempotent(dbClient, func(w http.ResponseWriter, r *http.Request) {
+		if !requireK8s(w) {
+			return
+		}
+
 		var dbRequest DatabaseRequest
 		if err := json.NewDecoder(r.Body).Decode(&dbRequest); err != nil {
-			fmt.Println("Error parsing request:", err)
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			logf(r.Context(), "Error parsing request: %v\n", err)
+			respondError(w, http.StatusBadRequest, "Invalid request body")
 			return
 		}
 
-		fmt.Println("Database request received:")
-		fmt.Printf("  Type: %s\n", dbRequest.Type)
-		fmt.Printf("  Name: %s\n", dbRequest.Name)
-		fmt.Printf("  Username: %s\n", dbRequest.Username)
-		fmt.Printf("  Password: %s\n", "********")
+		// The target namespace comes from the authenticated identity only;
+		// a body naming another user is rejected rather than trusted
+		claims := authFromContext(r.Context())
+		if dbRequest.UserID > 0 && dbRequest.UserID != claims.UserID {
+			respondError(w, http.StatusForbidden, "Cannot deploy databases for another user")
+			return
+		}
+		dbRequest.UserID = claims.UserID
+		dbRequest.UserName = claims.Username
+		dbRequest.Email = lookupUserEmail(dbClient, claims.UserID)
+
+		if err := prepareDatabaseRequest(&dbRequest); err != nil {
+			respondRequestError(w, err)
+			return
+		}
+
+		logf(r.Context(), "Database request received:\n")
+		logf(r.Context(), "  Type: %s\n", dbRequest.Type)
+		logf(r.Context(), "  Name: %s\n", dbRequest.Name)
+		logf(r.Context(), "  Username: %s\n", dbRequest.Username)
+		logf(r.Context(), "  Password: %s\n", "********")
 
-		if clientset == nil {
-			http.Error(w, "Kubernetes client not available", http.StatusInternalServerError)
+		if err := checkDatabaseLimit(r.Context(), dbRequest.UserID, 1); err != nil {
+			logf(r.Context(), "Database limit check failed: %v\n", err)
+			respondError(w, apperrors.HTTPStatus(err), err.Error())
 			return
 		}
 
 		var targetNamespace string
 		if dbRequest.UserID > 0 && dbRequest.UserName != "" {
 			targetNamespace = GetUserNamespace(dbRequest.UserID, dbRequest.UserName)
-			fmt.Printf("  Target Namespace: %s (user: %s, ID: %d)\n", targetNamespace, dbRequest.UserName, dbRequest.UserID)
+			logf(r.Context(), "  Target Namespace: %s (user: %s, ID: %d)\n", targetNamespace, dbRequest.UserName, dbRequest.UserID)
 
-			if err := deployDatabaseToUserNamespace(dbRequest, clientset); err != nil {
-				fmt.Printf("Error deploying database: %v\n", err)
-				http.Error(w, "Failed to deploy database: "+err.Error(), http.StatusInternalServerError)
+			if err := deployDatabaseToUserNamespace(context.WithoutCancel(r.Context()), dbRequest, clientset); err != nil {
+				logf(r.Context(), "Error deploying database: %v\n", err)
+				respondError(w, apperrors.HTTPStatus(err), "Failed to deploy database: "+err.Error())
 				return
 			}
 		} else {
-			http.Error(w, "User information (UserID and UserName) is required", http.StatusBadRequest)
+			respondError(w, http.StatusBadRequest, "User information (UserID and UserName) is required")
 			return
 		}
-		port := os.Getenv("DB_PORT")
-		if port == "" {
-			port = "5432"
+
+		response := newDatabaseResponse(dbRequest, targetNamespace)
+		respondSuccess(w, http.StatusAccepted, response)
+
+		logf(r.Context(), "Response sent to React frontend\n")
+	}))).Methods("POST")
+
+	// Batch database creation endpoint: the namespace is ensured once, then each
+	// database is deployed concurrently and reported individually
+	r.HandleFunc("/api/databases/batch", requireAuth(idempotent(dbClient, func(w http.ResponseWriter, r *http.Request) {
+		if !requireK8s(w) {
+			return
+		}
+
+		var dbRequests []DatabaseRequest
+		if err := json.NewDecoder(r.Body).Decode(&dbRequests); err != nil {
+			logf(r.Context(), "Error parsing batch request: %v\n", err)
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
 		}
-		if dbRequest.Type == "mysql" {
-			port = "3306"
+		if len(dbRequests) == 0 {
+			respondError(w, http.StatusBadRequest, "At least one database is required")
+			return
 		}
 
-		var host string
-		var adminURL string
-		var adminType string
+		claims := authFromContext(r.Context())
+		targetNamespace := GetUserNamespace(claims.UserID, claims.Username)
+		email := lookupUserEmail(dbClient, claims.UserID)
 
-		host = fmt.Sprintf("%s.%s.svc.cluster.local", dbRequest.Name, targetNamespace)
+		// Validate every item up front; invalid items are reported without
+		// blocking the rest of the batch
+		results := make([]BatchCreateResult, len(dbRequests))
+		var valid []DatabaseRequest
+		var validIdx []int
+		seen := make(map[string]bool)
+		for i := range dbRequests {
+			dbRequest := &dbRequests[i]
+			results[i].Name = dbRequest.Name
 
-		// CORRECTED URL PATTERN TO MATCH ACTUAL INGRESSROUTE: /{namespace}/{dbname}-{admintype}
-		if dbRequest.Type == "mysql" {
-			adminURL = fmt.Sprintf("http://10.9.21.201/%s/%s-phpmyadmin", targetNamespace, dbRequest.Name)
-			adminType = "phpMyAdmin"
-		} else {
-			adminURL = fmt.Sprintf("http://10.9.21.201/%s/%s-pgadmin/login?next=", targetNamespace, dbRequest.Name)
-			adminType = "pgAdmin"
+			if dbRequest.UserID > 0 && dbRequest.UserID != claims.UserID {
+				results[i].Error = "Cannot deploy databases for another user"
+				continue
+			}
+			dbRequest.UserID = claims.UserID
+			dbRequest.UserName = claims.Username
+			dbRequest.Email = email
+
+			if err := prepareDatabaseRequest(dbRequest); err != nil {
+				results[i].Error = err.Error()
+				results[i].Errors = validationProblems(err)
+				continue
+			}
+			if seen[dbRequest.Name] {
+				results[i].Error = fmt.Sprintf("duplicate database name '%s' in batch", dbRequest.Name)
+				continue
+			}
+			seen[dbRequest.Name] = true
+
+			valid = append(valid, *dbRequest)
+			validIdx = append(validIdx, i)
 		}
 
-		response := DatabaseResponse{
-			Name:      dbRequest.Name,
-			Host:      host,
-			Port:      port,
-			Username:  dbRequest.Username,
-			Type:      dbRequest.Type,
-			Status:    "creating",
-			Message:   fmt.Sprintf("Database and %s dashboard deployment initiated in namespace '%s'", adminType, targetNamespace),
-			Namespace: targetNamespace,
-			AdminURL:  adminURL,
-			AdminType: adminType,
+		logf(r.Context(), "📦 Batch create of %d databases (%d valid) in namespace '%s'\n", len(dbRequests), len(valid), targetNamespace)
+
+		if err := checkDatabaseLimit(r.Context(), claims.UserID, len(valid)); err != nil {
+			logf(r.Context(), "Database limit check failed: %v\n", err)
+			respondError(w, apperrors.HTTPStatus(err), err.Error())
+			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusAccepted)
-		json.NewEncoder(w).Encode(response)
+		if len(valid) > 0 {
+			ctx := context.WithoutCancel(r.Context())
 
-		fmt.Println("Response sent to React frontend")
-	}).Methods("POST")
+			unlock, err := lockNamespace(ctx, targetNamespace)
+			if err != nil {
+				respondError(w, apperrors.HTTPStatus(err), err.Error())
+				return
+			}
+			if err := ensureNamespace(ctx, clientset, targetNamespace); err != nil {
+				unlock()
+				logf(r.Context(), "Error ensuring namespace: %v\n", err)
+				respondError(w, http.StatusInternalServerError, "Failed to ensure namespace: "+err.Error())
+				return
+			}
+			deployed := createDatabasesBatch(ctx, valid, targetNamespace)
+			unlock()
 
-	// Database deletion endpoint
-	r.HandleFunc("/api/databases/{namespace}/{name}", func(w http.ResponseWriter, r *http.Request) {
-		if clientset == nil || dynamicClient == nil {
-			http.Error(w, "Kubernetes clients not available", http.StatusInternalServerError)
-			return
+			for i, result := range deployed {
+				results[validIdx[i]] = result
+			}
 		}
 
+		failed := 0
+		for _, result := range results {
+			if !result.Success {
+				failed++
+			}
+		}
+
+		response := map[string]interface{}{
+			"namespace": targetNamespace,
+			"results":   results,
+			"created":   len(results) - failed,
+			"failed":    failed,
+		}
+
+		// Partial failures still return 202, with success=false in the envelope
+		writeJSON(w, http.StatusAccepted, apiResponse{Success: failed == 0, Data: response})
+		logf(r.Context(), "✅ Batch create finished: %d created, %d failed\n", len(results)-failed, failed)
+	}))).Methods("POST")
+
+	// Database deletion endpoint
+	r.HandleFunc("/api/databases/{namespace}/{name}", requireAuth(func(w http.ResponseWriter, r *http.Request) {
 		// Get parameters from URL
 		vars := mux.Vars(r)
 		namespace := vars["namespace"]
 		dbName := vars["name"]
 
-		fmt.Printf("🗑️ Received request to delete database '%s' from namespace '%s'\n", dbName, namespace)
+		if !requireNamespaceAccess(w, r, namespace) {
+			return
+		}
+
+		logf(r.Context(), "🗑️ Received request to delete database '%s' from namespace '%s'\n", dbName, namespace)
 
-		// Delete the database deployment
-		if err := deleteDatabaseDeployment(dbName, namespace); err != nil {
-			fmt.Printf("Error deleting database: %v\n", err)
-			http.Error(w, "Failed to delete database: "+err.Error(), http.StatusInternalServerError)
+		// Guard against fat-fingered deletes: ?confirm= must repeat the database name
+		// when REQUIRE_DELETE_CONFIRM is on, and must match whenever it is given
+		if !deleteConfirmed(r, dbName) {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Deletion not confirmed: pass ?confirm=%s to delete this database", dbName))
+			return
+		}
+
+		gracePeriod, err := parseGracePeriod(r)
+		if err != nil {
+			respondError(w, apperrors.HTTPStatus(err), err.Error())
+			return
+		}
+
+		ctx := withDeleteDeadline(context.WithoutCancel(r.Context()))
+		unlock, err := lockNamespace(ctx, namespace)
+		if err != nil {
+			respondError(w, apperrors.HTTPStatus(err), err.Error())
+			return
+		}
+
+		// Delete the Kubernetes resources, then the record; the response says which succeeded
+		deletion, err := deleteDatabase(ctx, dbClient, dbName, namespace, gracePeriod)
+		unlock()
+		if err == errK8sUnavailable {
+			respondK8sUnavailable(w)
+			return
+		}
+		if err != nil {
+			logf(r.Context(), "Error deleting database: %v\n", err)
+			writeJSON(w, apperrors.HTTPStatus(err), apiResponse{
+				Success: false,
+				Data:    deletion,
+				Error:   "Failed to delete database: " + err.Error(),
+			})
+			return
+		}
+
+		// External databases are only tracked records - the cluster was never touched
+		if deletion.External {
+			respondSuccess(w, http.StatusOK, map[string]interface{}{
+				"message":   fmt.Sprintf("External database '%s' removed from namespace '%s'", dbName, namespace),
+				"name":      dbName,
+				"namespace": namespace,
+				"external":  true,
+			})
+			logf(r.Context(), "✅ External database record '%s' removed\n", dbName)
 			return
 		}
 
 		// Send success response
 		response := map[string]interface{}{
-			"success":   true,
-			"message":   fmt.Sprintf("Database '%s' deleted successfully from namespace '%s'", dbName, namespace),
+			"message":           fmt.Sprintf("Database '%s' deleted successfully from namespace '%s'", dbName, namespace),
+			"name":              dbName,
+			"namespace":         namespace,
+			"kubernetesDeleted": deletion.KubernetesDeleted,
+			"recordDeleted":     deletion.RecordDeleted,
+		}
+
+		respondSuccess(w, http.StatusOK, response)
+		logf(r.Context(), "✅ Database '%s' deleted successfully\n", dbName)
+	})).Methods("DELETE")
+
+	// Batch database deletion endpoint
+	r.HandleFunc("/api/databases/{namespace}/batch-delete", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		namespace := mux.Vars(r)["namespace"]
+		if !requireNamespaceAccess(w, r, namespace) {
+			return
+		}
+
+		gracePeriod, err := parseGracePeriod(r)
+		if err != nil {
+			respondError(w, apperrors.HTTPStatus(err), err.Error())
+			return
+		}
+
+		var batchRequest BatchDeleteRequest
+		if err := json.NewDecoder(r.Body).Decode(&batchRequest); err != nil {
+			logf(r.Context(), "Error parsing batch delete request: %v\n", err)
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if len(batchRequest.Names) == 0 {
+			respondError(w, http.StatusBadRequest, "At least one database name is required")
+			return
+		}
+
+		// Like a single delete, ?confirm= must repeat every name (e.g. ?confirm=a&confirm=b)
+		if !deleteConfirmed(r, batchRequest.Names...) {
+			respondError(w, http.StatusBadRequest, "Deletion not confirmed: pass ?confirm=<name> once for each database to delete")
+			return
+		}
+
+		logf(r.Context(), "🗑️ Received request to delete %d databases from namespace '%s'\n", len(batchRequest.Names), namespace)
+
+		ctx := withDeleteDeadline(context.WithoutCancel(r.Context()))
+
+		// One lock for the whole batch, so its workers do not queue on each other
+		unlock, err := lockNamespace(ctx, namespace)
+		if err != nil {
+			respondError(w, apperrors.HTTPStatus(err), err.Error())
+			return
+		}
+		results := deleteDatabasesBatch(ctx, dbClient, batchRequest.Names, namespace, gracePeriod)
+		unlock()
+
+		failed := 0
+		for _, result := range results {
+			if !result.Success {
+				failed++
+			}
+		}
+
+		response := map[string]interface{}{
+			"namespace": namespace,
+			"results":   results,
+			"deleted":   len(results) - failed,
+			"failed":    failed,
+		}
+
+		// Partial failures still return 200, with success=false in the envelope
+		writeJSON(w, http.StatusOK, apiResponse{Success: failed == 0, Data: response})
+		logf(r.Context(), "✅ Batch delete finished: %d deleted, %d failed\n", len(results)-failed, failed)
+	})).Methods("POST")
+
+	// Run a one-off SQL statement inside a database pod (admins only)
+	r.HandleFunc("/api/databases/{namespace}/{name}/exec", requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		if !requireK8s(w) {
+			return
+		}
+
+		var execRequest ExecRequest
+		if err := json.NewDecoder(r.Body).Decode(&execRequest); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if strings.TrimSpace(execRequest.SQL) == "" {
+			respondError(w, http.StatusBadRequest, "sql is required")
+			return
+		}
+
+		vars := mux.Vars(r)
+		namespace := vars["namespace"]
+		dbName := vars["name"]
+		claims := authFromContext(r.Context())
+
+		logf(r.Context(), "🖥️  %s is running SQL in database '%s' (namespace '%s')\n", claims.Username, dbName, namespace)
+
+		result, err := execSQL(r.Context(), namespace, dbName, execRequest.SQL)
+		if err != nil {
+			logf(r.Context(), "Error executing SQL: %v\n", err)
+			respondError(w, http.StatusInternalServerError, "Failed to execute SQL: "+err.Error())
+			return
+		}
+
+		respondSuccess(w, http.StatusOK, result)
+	})).Methods("POST")
+
+	// List the pods of a database and its admin dashboard
+	r.HandleFunc("/api/databases/{namespace}/{name}/pods", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		namespace := vars["namespace"]
+		dbName := vars["name"]
+		if !requireNamespaceAccess(w, r, namespace) {
+			return
+		}
+
+		if !requireK8s(w) {
+			return
+		}
+
+		pods, err := listDatabasePods(r.Context(), namespace, dbName)
+		if err != nil {
+			logf(r.Context(), "Error listing database pods: %v\n", err)
+			respondError(w, http.StatusInternalServerError, "Failed to list pods: "+err.Error())
+			return
+		}
+
+		phase, err := getDatabasePhase(r.Context(), namespace, dbName)
+		if err != nil {
+			logf(r.Context(), "Error getting database phase: %v\n", err)
+			respondError(w, http.StatusInternalServerError, "Failed to get database phase: "+err.Error())
+			return
+		}
+
+		respondSuccess(w, http.StatusOK, map[string]interface{}{
+			"namespace":  namespace,
+			"database":   dbName,
+			"phase":      phase,
+			"pods":       pods,
+			"count":      len(pods),
+			"serverTime": serverTime(),
+		})
+	})).Methods("GET")
+
+	// Database restart endpoint (?component=admin restarts the admin dashboard)
+	r.HandleFunc("/api/databases/{namespace}/{name}/restart", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		namespace := vars["namespace"]
+		dbName := vars["name"]
+		if !requireNamespaceAccess(w, r, namespace) {
+			return
+		}
+
+		if !requireK8s(w) {
+			return
+		}
+		component := r.URL.Query().Get("component")
+
+		deploymentName := dbName
+		if component == "admin" {
+			dbType, err := getDatabaseType(r.Context(), dbName, namespace)
+			if err != nil {
+				logf(r.Context(), "Error determining database type: %v\n", err)
+				respondError(w, apperrors.HTTPStatus(err), "Failed to determine database type: "+err.Error())
+				return
+			}
+			deploymentName = adminDeploymentName(dbName, dbType)
+		} else if component != "" && component != "database" {
+			respondError(w, http.StatusBadRequest, "Invalid component (expected 'database' or 'admin')")
+			return
+		}
+
+		logf(r.Context(), "🔄 Received request to restart '%s' in namespace '%s'\n", deploymentName, namespace)
+
+		revision, err := restartDeployment(r.Context(), namespace, deploymentName)
+		if err != nil {
+			logf(r.Context(), "Error restarting deployment: %v\n", err)
+			respondError(w, apperrors.HTTPStatus(err), "Failed to restart deployment: "+err.Error())
+			return
+		}
+
+		respondSuccess(w, http.StatusOK, map[string]interface{}{
+			"message":    fmt.Sprintf("Deployment '%s' restart triggered in namespace '%s'", deploymentName, namespace),
+			"name":       deploymentName,
+			"namespace":  namespace,
+			"revision":   revision,
+			"restarting": true,
+		})
+	})).Methods("POST")
+
+	// Database backup schedule endpoint: creates or updates the backup CronJob
+	r.HandleFunc("/api/databases/{namespace}/{name}/backup-schedule", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		namespace := vars["namespace"]
+		dbName := vars["name"]
+		if !requireNamespaceAccess(w, r, namespace) {
+			return
+		}
+
+		if !requireK8s(w) {
+			return
+		}
+
+		var scheduleRequest BackupScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&scheduleRequest); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		scheduleRequest.Schedule = strings.TrimSpace(scheduleRequest.Schedule)
+		if err := validateCronSchedule(scheduleRequest.Schedule); err != nil {
+			respondError(w, apperrors.HTTPStatus(err), err.Error())
+			return
+		}
+		if scheduleRequest.Retention < 0 || scheduleRequest.Retention > maxBackupRetention {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("retention must be between 1 and %d", maxBackupRetention))
+			return
+		}
+		if scheduleRequest.Retention == 0 {
+			scheduleRequest.Retention = defaultBackupRetention
+		}
+
+		logf(r.Context(), "💾 Setting backup schedule %q for '%s' in namespace '%s'\n", scheduleRequest.Schedule, dbName, namespace)
+
+		if err := setBackupSchedule(r.Context(), dbName, namespace, scheduleRequest.Schedule, scheduleRequest.Retention); err != nil {
+			logf(r.Context(), "Error setting backup schedule: %v\n", err)
+			respondError(w, apperrors.HTTPStatus(err), "Failed to set backup schedule: "+err.Error())
+			return
+		}
+
+		respondSuccess(w, http.StatusOK, map[string]interface{}{
 			"name":      dbName,
 			"namespace": namespace,
+			"cronJob":   backupCronJobName(dbName),
+			"schedule":  scheduleRequest.Schedule,
+			"retention": scheduleRequest.Retention,
+		})
+	})).Methods("POST")
+
+	// Database backups listing endpoint, newest first
+	r.HandleFunc("/api/databases/{namespace}/{name}/backups", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		namespace := vars["namespace"]
+		dbName := vars["name"]
+		if !requireNamespaceAccess(w, r, namespace) {
+			return
+		}
+
+		if !requireK8s(w) {
+			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
-		fmt.Printf("✅ Database '%s' deleted successfully\n", dbName)
-	}).Methods("DELETE")
+		logf(r.Context(), "💾 Listing backups of '%s' in namespace '%s'\n", dbName, namespace)
 
-	// List databases for a namespace endpoint
-	r.HandleFunc("/api/databases/{namespace}", func(w http.ResponseWriter, r *http.Request) {
-		if clientset == nil {
-			http.Error(w, "Kubernetes client not available", http.StatusInternalServerError)
+		backups, err := listBackups(r.Context(), dbName, namespace)
+		if err != nil {
+			logf(r.Context(), "Error listing backups: %v\n", err)
+			respondError(w, http.StatusInternalServerError, "Failed to list backups: "+err.Error())
+			return
+		}
+
+		respondSuccess(w, http.StatusOK, map[string]interface{}{
+			"name":      dbName,
+			"namespace": namespace,
+			"backups":   backups,
+			"count":     len(backups),
+		})
+	})).Methods("GET")
+
+	// Database manifests export endpoint (multi-document YAML)
+	r.HandleFunc("/api/databases/{namespace}/{name}/manifests", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		namespace := vars["namespace"]
+		dbName := vars["name"]
+		if !requireNamespaceAccess(w, r, namespace) {
+			return
+		}
+
+		if !requireK8s(w) {
 			return
 		}
 
+		logf(r.Context(), "📄 Exporting manifests for '%s' in namespace '%s'\n", dbName, namespace)
+
+		manifests, err := getDatabaseManifests(r.Context(), dbName, namespace)
+		if err != nil {
+			logf(r.Context(), "Error exporting manifests: %v\n", err)
+			respondError(w, apperrors.HTTPStatus(err), "Failed to export manifests: "+err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write([]byte(manifests))
+	})).Methods("GET")
+
+	// List all db-saas namespaces endpoint (admin only)
+	r.HandleFunc("/api/namespaces", requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		if !requireK8s(w) {
+			return
+		}
+
+		logf(r.Context(), "📋 Getting all db-saas namespaces\n")
+
+		namespaces, err := listManagedNamespaces(r.Context())
+		if err != nil {
+			logf(r.Context(), "Error listing namespaces: %v\n", err)
+			respondError(w, http.StatusInternalServerError, "Failed to list namespaces: "+err.Error())
+			return
+		}
+
+		respondSuccess(w, http.StatusOK, map[string]interface{}{
+			"namespaces": namespaces,
+			"count":      len(namespaces),
+		})
+	})).Methods("GET")
+
+	// List databases for a namespace endpoint
+	r.HandleFunc("/api/databases/{namespace}", requireAuth(func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		namespace := vars["namespace"]
+		if !requireNamespaceAccess(w, r, namespace) {
+			return
+		}
+
+		if !requireK8s(w) {
+			return
+		}
+
+		// Optional ?label=key=value filters (repeatable), validated before
+		// they reach a label selector
+		filter, err := parseLabelFilters(r.URL.Query()["label"])
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 
 		fmt.Printf("📋 Getting databases for namespace: %s\n", namespace)
 
-		databases, err := listDatabasesInNamespace(namespace)
+		databases, err := listDatabasesInNamespace(namespace, filter)
 		if err != nil {
 			fmt.Printf("Error listing databases: %v\n", err)
-			http.Error(w, "Failed to list databases: "+err.Error(), http.StatusInternalServerError)
+			respondError(w, http.StatusInternalServerError, "Failed to list databases: "+err.Error())
 			return
 		}
 
+		// Include imported external databases, flagged as such; they carry no
+		// labels, so a label filter excludes them
+		if dbClient != nil && len(filter) == 0 {
+			records, err := dbClient.ListDatabaseRecords(namespace)
+			if err != nil {
+				fmt.Printf("Warning: Failed to list database records: %v\n", err)
+			}
+			for _, record := range records {
+				if record.Status != DatabaseStatusExternal {
+					continue
+				}
+				databases = append(databases, map[string]interface{}{
+					"name":      record.Name,
+					"type":      record.Type,
+					"status":    record.Status,
+					"namespace": record.Namespace,
+					"userId":    strconv.Itoa(record.UserID),
+					"host":      record.Host,
+					"port":      record.Port,
+					"username":  record.Username,
+					"createdAt": record.CreatedAt,
+					"external":  true,
+				})
+			}
+		}
+
 		response := map[string]interface{}{
-			"success":   true,
 			"namespace": namespace,
 			"databases": databases,
 			"count":     len(databases),
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		respondSuccess(w, http.StatusOK, response)
 		fmt.Printf("📋 Returned %d databases for namespace %s\n", len(databases), namespace)
-	}).Methods("GET")
+	})).Methods("GET")
+
+	// Canonical namespace of a user, so clients never derive it themselves.
+	// Users may look up their own namespace; admins may look up anyone's.
+	r.HandleFunc("/api/users/{id}/namespace", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		username, ok := targetUsername(w, r, dbClient, id, "Cannot look up another user's namespace")
+		if !ok {
+			return
+		}
+
+		respondSuccess(w, http.StatusOK, map[string]interface{}{
+			"userId":    id,
+			"namespace": GetUserNamespace(id, username),
+		})
+	})).Methods("GET")
+
+	// Health summary of all of a user's databases, for the dashboard landing
+	// page. Users may see their own; admins may see anyone's.
+	r.HandleFunc("/api/users/{id}/health", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		username, ok := targetUsername(w, r, dbClient, id, "Cannot view another user's databases")
+		if !ok {
+			return
+		}
+
+		if !requireK8s(w) {
+			return
+		}
+
+		summary, err := summarizeNamespaceHealth(r.Context(), GetUserNamespace(id, username))
+		if err != nil {
+			logf(r.Context(), "Error summarizing database health: %v\n", err)
+			respondError(w, http.StatusInternalServerError, "Failed to get database health: "+err.Error())
+			return
+		}
+
+		respondSuccess(w, http.StatusOK, summary)
+	})).Methods("GET")
+
+	// Effective server configuration, secrets redacted (admin only)
+	r.HandleFunc("/api/admin/config", requireAdmin(handleEffectiveConfig)).Methods("GET")
+
+	// Remove Traefik objects left behind by deleted databases (admin only)
+	r.HandleFunc("/api/admin/cleanup-orphans", requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		if !requireK8s(w) {
+			return
+		}
+
+		logf(r.Context(), "🧹 Cleaning up orphaned Traefik objects\n")
+
+		result, err := cleanupOrphanedTraefikObjects(r.Context())
+		if err != nil {
+			logf(r.Context(), "Error cleaning up orphaned Traefik objects: %v\n", err)
+			respondError(w, http.StatusInternalServerError, "Failed to clean up orphans: "+err.Error())
+			return
+		}
+
+		logf(r.Context(), "✅ Orphan cleanup removed %d of %d Traefik objects\n", result.Removed, result.Checked)
+		respondSuccess(w, http.StatusOK, result)
+	})).Methods("POST")
 
 	// Register other handlers...
 	if clientset != nil {
@@ -231,8 +873,85 @@ func main() {
 	if dbClient != nil {
 		RegisterAuthHandlers(r, dbClient)
 
-		// User creation endpoints (keeping your existing logic)
-		r.HandleFunc("/api/users", func(w http.ResponseWriter, r *http.Request) {
+		// Import an existing external database as a tracked record (no Kubernetes resources)
+		r.HandleFunc("/api/databases/import", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+			var importRequest ImportDatabaseRequest
+			if err := json.NewDecoder(r.Body).Decode(&importRequest); err != nil {
+				fmt.Println("Error parsing import request:", err)
+				respondError(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+
+			if importRequest.Name == "" || importRequest.Type == "" || importRequest.Host == "" ||
+				importRequest.Port == "" || importRequest.Username == "" {
+				respondError(w, http.StatusBadRequest, "Name, type, host, port and username are required")
+				return
+			}
+
+			dbType, err := normalizeDatabaseType(importRequest.Type)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			importRequest.Type = dbType
+
+			// Records are imported into the caller's own namespace, like creates
+			claims := authFromContext(r.Context())
+			if importRequest.UserID > 0 && importRequest.UserID != claims.UserID {
+				respondError(w, http.StatusForbidden, "Cannot import databases for another user")
+				return
+			}
+			importRequest.UserID = claims.UserID
+			importRequest.UserName = claims.Username
+
+			namespace := GetUserNamespace(importRequest.UserID, importRequest.UserName)
+			fmt.Printf("📥 Importing external %s database '%s' (%s:%s) into namespace '%s'\n",
+				importRequest.Type, importRequest.Name, importRequest.Host, importRequest.Port, namespace)
+
+			record, err := dbClient.CreateDatabaseRecord(DatabaseRecord{
+				Name:      importRequest.Name,
+				Type:      importRequest.Type,
+				Host:      importRequest.Host,
+				Port:      importRequest.Port,
+				Username:  importRequest.Username,
+				Namespace: namespace,
+				UserID:    importRequest.UserID,
+				Status:    DatabaseStatusExternal,
+			})
+			if err != nil {
+				fmt.Printf("Error importing database: %v\n", err)
+				respondError(w, apperrors.HTTPStatus(err), "Failed to import database: "+err.Error())
+				return
+			}
+
+			respondSuccess(w, http.StatusCreated, record)
+			fmt.Printf("✅ External database '%s' imported\n", record.Name)
+		})).Methods("POST")
+
+		// Refresh persisted database statuses from the cluster (admin only)
+		r.HandleFunc("/api/admin/databases/reconcile-status", requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			if !requireK8s(w) {
+				return
+			}
+
+			logf(r.Context(), "🔁 Reconciling database record statuses\n")
+
+			checked, changes, err := reconcileRecordStatuses(r.Context(), dbClient)
+			if err != nil {
+				logf(r.Context(), "Error reconciling database statuses: %v\n", err)
+				respondError(w, http.StatusInternalServerError, "Failed to reconcile statuses: "+err.Error())
+				return
+			}
+
+			respondSuccess(w, http.StatusOK, map[string]interface{}{
+				"checked": checked,
+				"updated": len(changes),
+				"changes": changes,
+			})
+		})).Methods("POST")
+
+		// User creation endpoints (admin only; users register through /api/auth/register)
+		r.HandleFunc("/api/users", requireAdmin(func(w http.ResponseWriter, r *http.Request) {
 			var userRequest struct {
 				FirstName string `json:"firstName"`
 				LastName  string `json:"lastName"`
@@ -240,7 +959,7 @@ func main() {
 
 			if err := json.NewDecoder(r.Body).Decode(&userRequest); err != nil {
 				fmt.Println("Error parsing user request:", err)
-				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				respondError(w, http.StatusBadRequest, "Invalid request body")
 				return
 			}
 
@@ -249,43 +968,46 @@ func main() {
 			user, err := dbClient.CreateUser(userRequest.LastName, userRequest.FirstName)
 			if err != nil {
 				fmt.Printf("Error creating user: %v\n", err)
-				http.Error(w, "Failed to create user: "+err.Error(), http.StatusInternalServerError)
+				respondError(w, apperrors.HTTPStatus(err), "Failed to create user: "+err.Error())
 				return
 			}
 
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusCreated)
-			json.NewEncoder(w).Encode(user)
+			respondSuccess(w, http.StatusCreated, user)
 			fmt.Printf("User created with ID: %d\n", user.ID)
-		}).Methods("POST")
+		})).Methods("POST")
 
-		// Get all users
-		r.HandleFunc("/api/users", func(w http.ResponseWriter, r *http.Request) {
+		// Get all users (admin only)
+		r.HandleFunc("/api/users", requireAdmin(func(w http.ResponseWriter, r *http.Request) {
 			fmt.Println("Getting all users")
 
 			users, err := dbClient.GetAllUsers()
 			if err != nil {
 				fmt.Printf("Error getting users: %v\n", err)
-				http.Error(w, "Failed to get users: "+err.Error(), http.StatusInternalServerError)
+				respondError(w, http.StatusInternalServerError, "Failed to get users: "+err.Error())
 				return
 			}
 
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]interface{}{
+			respondSuccess(w, http.StatusOK, map[string]interface{}{
 				"users": users,
 				"count": len(users),
 			})
 			fmt.Printf("Returned %d users\n", len(users))
-		}).Methods("GET")
+		})).Methods("GET")
 
-		// Get user by ID
-		r.HandleFunc("/api/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		// Get user by ID; users may read their own, admins anyone's
+		r.HandleFunc("/api/users/{id}", requireAuth(func(w http.ResponseWriter, r *http.Request) {
 			vars := mux.Vars(r)
 			idStr := vars["id"]
 
 			id, err := strconv.Atoi(idStr)
 			if err != nil {
-				http.Error(w, "Invalid user ID", http.StatusBadRequest)
+				respondError(w, http.StatusBadRequest, "Invalid user ID")
+				return
+			}
+
+			claims := authFromContext(r.Context())
+			if claims.UserID != id && !isAdmin(claims) {
+				respondError(w, http.StatusForbidden, "Cannot view another user")
 				return
 			}
 
@@ -294,55 +1016,261 @@ func main() {
 			user, err := dbClient.GetUserByID(id)
 			if err != nil {
 				fmt.Printf("Error getting user: %v\n", err)
-				http.Error(w, "Failed to get user: "+err.Error(), http.StatusInternalServerError)
+				respondError(w, http.StatusInternalServerError, "Failed to get user: "+err.Error())
 				return
 			}
 
 			if user == nil {
-				http.Error(w, "User not found", http.StatusNotFound)
+				respondError(w, http.StatusNotFound, "User not found")
 				return
 			}
 
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(user)
-		}).Methods("GET")
+			respondSuccess(w, http.StatusOK, user)
+		})).Methods("GET")
+
+		// Update an authenticated user's profile; only the user themselves or an admin may do so
+		r.HandleFunc("/api/users/{id}", requireAuth(func(w http.ResponseWriter, r *http.Request) {
+			id, err := strconv.Atoi(mux.Vars(r)["id"])
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "Invalid user ID")
+				return
+			}
+
+			claims := authFromContext(r.Context())
+			if claims.UserID != id && !isAdmin(claims) {
+				respondError(w, http.StatusForbidden, "Cannot update another user's profile")
+				return
+			}
+
+			var updateRequest UpdateProfileRequest
+			if err := json.NewDecoder(r.Body).Decode(&updateRequest); err != nil {
+				fmt.Println("Error parsing profile update request:", err)
+				respondError(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+
+			if err := validateProfileUpdate(&updateRequest); err != nil {
+				respondError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			user, err := dbClient.UpdateUserProfile(id, updateRequest)
+			if err != nil {
+				fmt.Printf("Error updating user: %v\n", err)
+				respondError(w, http.StatusInternalServerError, "Failed to update user")
+				return
+			}
+
+			if user == nil {
+				respondError(w, http.StatusNotFound, "User not found")
+				return
+			}
+
+			respondSuccess(w, http.StatusOK, user)
+		})).Methods("PATCH")
 
 		fmt.Println("User API endpoints registered at /api/users")
 	}
 
+	// JSON errors for unknown paths and unsupported methods
+	r.NotFoundHandler = http.HandlerFunc(notFoundHandler)
+	r.MethodNotAllowedHandler = methodNotAllowedHandler(r)
+
 	// CORS setup
-	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
-		AllowCredentials: true,
-	})
+	c := newCORS(r)
 
 	// Start server
-	port := "8080"
-	fmt.Printf("✅ Server starting on http://localhost:%s\n", port)
+	addr := appConfig.HTTPServer.Addr
+	fmt.Printf("✅ Server starting on %s\n", addr)
 	fmt.Println("Waiting for requests from React...")
-	log.Fatal(http.ListenAndServe(":"+port, c.Handler(r)))
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           c.Handler(r),
+		ReadTimeout:       appConfig.HTTPServer.ReadTimeout,
+		ReadHeaderTimeout: appConfig.HTTPServer.ReadTimeout,
+		WriteTimeout:      appConfig.HTTPServer.WriteTimeout,
+		IdleTimeout:       appConfig.HTTPServer.IdleTimeout,
+	}
+	log.Fatal(server.ListenAndServe())
 }
 
 // deployDatabaseToUserNamespace deploys database resources using Go client with Traefik
-func deployDatabaseToUserNamespace(dbRequest DatabaseRequest, clientset *kubernetes.Clientset) error {
+func deployDatabaseToUserNamespace(ctx context.Context, dbRequest DatabaseRequest, clientset kubernetes.Interface) error {
 	userNamespace := GetUserNamespace(dbRequest.UserID, dbRequest.UserName)
 
-	fmt.Printf("🚀 Deploying %s database '%s' to namespace '%s'\n", dbRequest.Type, dbRequest.Name, userNamespace)
+	logf(ctx, "🚀 Deploying %s database '%s' to namespace '%s'\n", dbRequest.Type, dbRequest.Name, userNamespace)
 
-	ctx := context.Background()
+	// Serialize operations on the same namespace
+	unlock, err := lockNamespace(ctx, userNamespace)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	// Ensure namespace exists
 	if err := ensureNamespace(ctx, clientset, userNamespace); err != nil {
 		return fmt.Errorf("failed to ensure namespace: %w", err)
 	}
 
-	if dbRequest.Type == "mysql" {
-		return deployMySQL(ctx, clientset, dbRequest, userNamespace)
+	return deployDatabase(ctx, clientset, dbRequest, userNamespace)
+}
+
+// lookupUserEmail returns a user's email for use as their pgAdmin login, or ""
+// when the control database is unavailable or the lookup fails
+func lookupUserEmail(dbClient *DBClient, userID int) string {
+	if dbClient == nil {
+		return ""
+	}
+	email, err := dbClient.GetUserEmail(userID)
+	if err != nil {
+		fmt.Printf("Warning: Could not look up email for user %d: %v\n", userID, err)
+	}
+	return email
+}
+
+// deployDatabase deploys a database and its admin dashboard into an existing
+// namespace; callers are responsible for locking and ensuring the namespace
+func deployDatabase(ctx context.Context, clientset kubernetes.Interface, dbRequest DatabaseRequest, namespace string) error {
+	if err := ensureNetworkPolicy(ctx, clientset, namespace); err != nil {
+		return err
+	}
+	if dbRequest.Type == DatabaseTypeMySQL {
+		return deployMySQL(ctx, clientset, dbRequest, namespace)
+	}
+	return deployPostgreSQL(ctx, clientset, dbRequest, namespace)
+}
+
+// targetUsername resolves the username of user id for a self-or-admin
+// endpoint. Users get their own name from the token; admins may name anyone,
+// looked up in the user database. On failure it writes the error response
+// (forbidden carries the 403 message) and returns false.
+func targetUsername(w http.ResponseWriter, r *http.Request, dbClient *DBClient, id int, forbidden string) (string, bool) {
+	claims := authFromContext(r.Context())
+	if id == claims.UserID {
+		return claims.Username, true
+	}
+
+	if !isAdmin(claims) {
+		respondError(w, http.StatusForbidden, forbidden)
+		return "", false
+	}
+	if dbClient == nil {
+		respondError(w, http.StatusServiceUnavailable, "User database not available")
+		return "", false
+	}
+
+	username, err := dbClient.GetUsername(id)
+	if err != nil {
+		fmt.Printf("Error getting username: %v\n", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get user")
+		return "", false
+	}
+	if username == "" {
+		respondError(w, http.StatusNotFound, "User not found")
+		return "", false
+	}
+	return username, true
+}
+
+// prepareDatabaseRequest normalizes the database type and validates the
+// user-supplied fields of a create request in place. Every invalid field is
+// reported in the returned ValidationErrors, not just the first.
+func prepareDatabaseRequest(dbRequest *DatabaseRequest) error {
+	var problems ValidationErrors
+
+	dbType, err := normalizeDatabaseType(dbRequest.Type)
+	problems.add("type", err)
+	typeValid := err == nil
+	if typeValid {
+		dbRequest.Type = dbType
+	}
+
+	problems.add("name", validateResourceName(dbRequest.Name))
+	problems.add("username", validateDatabaseUsername(dbRequest.Type, dbRequest.Username))
+	problems.add("password", validateDatabasePassword(dbRequest.Password))
+	problems.add("databaseName", validateDatabaseName(dbRequest.DatabaseName))
+	problems.add("env", validateCustomEnv(dbRequest.Env))
+	problems.add("podAnnotations", validatePodAnnotations(dbRequest.PodAnnotations))
+	problems.add("initSql", validateInitSQL(dbRequest.InitSQL))
+	if typeValid {
+		_, err := defaultPortNumber(dbRequest.Type)
+		problems.add("type", err)
+		problems.add("args", validateDatabaseArgs(dbRequest.Type, dbRequest.Args))
+	}
+
+	// Environment defaults fill in the profile, so they apply before it is checked
+	if err := applyEnvironmentDefaults(dbRequest); err != nil {
+		problems.add("environment", err)
 	} else {
-		return deployPostgreSQL(ctx, clientset, dbRequest, userNamespace)
+		problems.add("resources", validateResources(dbRequest.Profile, dbRequest.Resources))
+	}
+
+	if dbRequest.BackupSchedule != "" && dbRequest.BackupSchedule != backupScheduleNone {
+		problems.add("backupSchedule", validateCronSchedule(dbRequest.BackupSchedule))
+	}
+
+	sqlDatabase := dbRequest.Type == DatabaseTypePostgreSQL || dbRequest.Type == DatabaseTypeMySQL
+	if dbRequest.EnableMetrics && !appConfig.Features.Metrics {
+		problems.add("enableMetrics", apperrors.New(apperrors.ErrInvalidInput, "metrics exporters are disabled on this server"))
+	} else if typeValid && dbRequest.EnableMetrics && !sqlDatabase {
+		problems.add("enableMetrics", apperrors.New(apperrors.ErrInvalidInput, "metrics exporters are only supported for SQL databases"))
+	}
+	if typeValid && dbRequest.ReadOnlyUser && !sqlDatabase {
+		problems.add("readOnlyUser", apperrors.New(apperrors.ErrInvalidInput, "read-only users are only supported for SQL databases"))
+	}
+
+	if err := problems.err(); err != nil {
+		return err
+	}
+
+	if dbRequest.ReadOnlyUser {
+		password, err := generatePassword()
+		if err != nil {
+			return err
+		}
+		dbRequest.ReadOnlyPassword = password
+	}
+	return nil
+}
+
+// newDatabaseResponse builds the response returned once a database deployment
+// has been initiated
+func newDatabaseResponse(dbRequest DatabaseRequest, namespace string) DatabaseResponse {
+	adminType := "pgAdmin"
+	if dbRequest.Type == DatabaseTypeMySQL {
+		adminType = "phpMyAdmin"
+	}
+
+	var readOnly *ReadOnlyCredentials
+	if dbRequest.ReadOnlyUser {
+		readOnly = &ReadOnlyCredentials{
+			Username: readOnlyUsername(dbRequest),
+			Password: dbRequest.ReadOnlyPassword,
+		}
+	}
+
+	response := DatabaseResponse{
+		Name:         dbRequest.Name,
+		Host:         fmt.Sprintf("%s.%s.svc.cluster.local", dbRequest.Name, namespace),
+		Port:         advertisedPort(dbRequest.Type),
+		Username:     dbRequest.Username,
+		Type:         dbRequest.Type,
+		Status:       DatabaseStatusProvisioning,
+		DatabaseName: dbRequest.LogicalDatabaseName(),
+		Environment:  dbRequest.Environment,
+		Message:      fmt.Sprintf("Database and %s dashboard deployment initiated in namespace '%s'", adminType, namespace),
+		Namespace:    namespace,
+		AdminURL:     adminURL(namespace, dbRequest.Name, dbRequest.Type),
+		AdminType:    adminType,
+		ReadOnly:     readOnly,
+	}
+
+	if !adminDashboardsEnabled() {
+		response.Message = fmt.Sprintf("Database deployment initiated in namespace '%s'", namespace)
+		response.AdminURL = ""
+		response.AdminType = ""
 	}
+	return response
 }
 
 // ensureNamespace creates namespace if it doesn't exist
@@ -355,10 +1283,10 @@ func getDynamicClient() (dynamic.Interface, error) {
 	// Try in-cluster configuration first
 	config, err = rest.InClusterConfig()
 	if err != nil {
-		if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		if appConfig.KubernetesServiceHost == "" {
 			kubeconfig := "kubeconfig.yaml"
 			if _, err := os.Stat(kubeconfig); os.IsNotExist(err) {
-				kubeconfig = os.Getenv("KUBECONFIG")
+				kubeconfig = appConfig.Kubeconfig
 				if kubeconfig == "" {
 					homeDir, herr := os.UserHomeDir()
 					if herr != nil {
@@ -390,10 +1318,10 @@ func getKubernetesClient() (*kubernetes.Clientset, error) {
 	// Try in-cluster configuration first
 	config, err = rest.InClusterConfig()
 	if err != nil {
-		if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		if appConfig.KubernetesServiceHost == "" {
 			kubeconfig := "kubeconfig.yaml"
 			if _, err := os.Stat(kubeconfig); os.IsNotExist(err) {
-				kubeconfig = os.Getenv("KUBECONFIG")
+				kubeconfig = appConfig.Kubeconfig
 				if kubeconfig == "" {
 					homeDir, herr := os.UserHomeDir()
 					if herr != nil {
@@ -414,5 +1342,6 @@ func getKubernetesClient() (*kubernetes.Clientset, error) {
 		fmt.Println("Using in-cluster configuration (ServiceAccount)")
 	}
 	config.UserAgent = "tbdback/1.0"
+	kubeRestConfig = config
 	return kubernetes.NewForConfig(config)
 }
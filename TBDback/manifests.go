@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kjson "k8s.io/apimachinery/pkg/runtime/serializer/json"
+)
+
+// manifestObject identifies a Kubernetes object belonging to a database
+type manifestObject struct {
+	gvr       schema.GroupVersionResource
+	name      string
+	namespace string // defaults to the database's namespace
+	optional  bool   // skipped when missing instead of failing the export
+}
+
+var (
+	deploymentsGVR   = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	servicesGVR      = schema.GroupVersionResource{Version: "v1", Resource: "services"}
+	configMapsGVR    = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	ingressRoutesGVR = schema.GroupVersionResource{Group: "traefik.io", Version: "v1alpha1", Resource: "ingressroutes"}
+	middlewaresGVR   = schema.GroupVersionResource{Group: "traefik.io", Version: "v1alpha1", Resource: "middlewares"}
+)
+
+// getDatabaseManifests fetches every object created for a database and returns
+// them as a multi-document YAML string, stripped of server-populated fields
+func getDatabaseManifests(ctx context.Context, dbName, namespace string) (string, error) {
+	dbType, err := getDatabaseType(ctx, dbName, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	adminName := adminDeploymentName(dbName, dbType)
+	adminType := strings.TrimPrefix(adminName, dbName+"-")
+
+	objects := []manifestObject{
+		{gvr: deploymentsGVR, name: dbName},
+		{gvr: servicesGVR, name: dbName},
+		{gvr: configMapsGVR, name: initSQLConfigMapName(dbName), optional: true},
+		{gvr: deploymentsGVR, name: adminName, optional: true},
+		{gvr: servicesGVR, name: adminName, optional: true},
+		{gvr: ingressRoutesGVR, name: traefikIngressRouteName(namespace, dbName, adminType), namespace: traefikNamespace(namespace), optional: true},
+		{gvr: middlewaresGVR, name: traefikHeadersMiddlewareName(namespace, dbName, adminType), namespace: traefikNamespace(namespace), optional: true},
+	}
+	if dbType == DatabaseTypeMySQL {
+		objects = append(objects, manifestObject{gvr: middlewaresGVR, name: traefikReplacePathMiddlewareName(namespace, dbName, adminType), namespace: traefikNamespace(namespace), optional: true})
+	}
+
+	serializer := kjson.NewSerializerWithOptions(kjson.DefaultMetaFactory, nil, nil, kjson.SerializerOptions{Yaml: true})
+
+	var buf bytes.Buffer
+	for _, object := range objects {
+		objectNamespace := object.namespace
+		if objectNamespace == "" {
+			objectNamespace = namespace
+		}
+
+		obj, err := dynamicClient.Resource(object.gvr).Namespace(objectNamespace).Get(ctx, object.name, metav1.GetOptions{})
+		if err != nil {
+			if object.optional && errors.IsNotFound(err) {
+				continue
+			}
+			return "", fmt.Errorf("failed to get %s %s: %w", object.gvr.Resource, object.name, err)
+		}
+
+		stripServerFields(obj)
+		redactSecretEnv(obj)
+
+		if buf.Len() > 0 {
+			buf.WriteString("---\n")
+		}
+		if err := serializer.Encode(obj, &buf); err != nil {
+			return "", fmt.Errorf("failed to encode %s %s: %w", object.gvr.Resource, object.name, err)
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// stripServerFields removes fields populated by the API server so the exported
+// manifest can be applied again as-is
+func stripServerFields(obj *unstructured.Unstructured) {
+	obj.SetManagedFields(nil)
+	obj.SetResourceVersion("")
+	obj.SetUID("")
+	obj.SetGeneration(0)
+	obj.SetCreationTimestamp(metav1.Time{})
+	unstructured.RemoveNestedField(obj.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(obj.Object, "status")
+
+	// Cluster IPs are allocated by the API server and rejected if they clash on apply
+	unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+	unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+}
+
+// secretEnvVars are the container environment variables that carry database
+// or dashboard credentials
+var secretEnvVars = map[string]bool{
+	"POSTGRES_PASSWORD":        true,
+	"MYSQL_ROOT_PASSWORD":      true,
+	"MYSQL_PASSWORD":           true,
+	"PMA_PASSWORD":             true,
+	"PGADMIN_DEFAULT_PASSWORD": true,
+	"MYSQLD_EXPORTER_PASSWORD": true,
+	"DATA_SOURCE_PASS":         true,
+}
+
+// redactSecretEnv replaces the values of credential environment variables in
+// a Deployment's containers with a placeholder, so exported manifests never
+// carry passwords. Variables read from a secretKeyRef are left as they are.
+func redactSecretEnv(obj *unstructured.Unstructured) {
+	for _, field := range []string{"containers", "initContainers"} {
+		containers, found, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", field)
+		if !found || err != nil {
+			continue
+		}
+
+		for _, container := range containers {
+			containerMap, ok := container.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			env, _ := containerMap["env"].([]interface{})
+			for _, entry := range env {
+				envVar, ok := entry.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := envVar["name"].(string)
+				if _, hasValue := envVar["value"]; hasValue && secretEnvVars[name] {
+					envVar["value"] = redactedValue
+				}
+			}
+		}
+
+		_ = unstructured.SetNestedSlice(obj.Object, containers, "spec", "template", "spec", field)
+	}
+}
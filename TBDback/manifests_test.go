@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestStripServerFieldsDropsClusterIPs(t *testing.T) {
+	service := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata":   map[string]interface{}{"name": "orders", "resourceVersion": "42", "uid": "abc"},
+		"spec": map[string]interface{}{
+			"clusterIP":  "10.43.0.10",
+			"clusterIPs": []interface{}{"10.43.0.10"},
+			"selector":   map[string]interface{}{"app": "orders"},
+		},
+		"status": map[string]interface{}{},
+	}}
+
+	stripServerFields(service)
+
+	for _, field := range [][]string{{"spec", "clusterIP"}, {"spec", "clusterIPs"}, {"status"}, {"metadata", "resourceVersion"}} {
+		if _, found, _ := unstructured.NestedFieldNoCopy(service.Object, field...); found {
+			t.Errorf("%v was not stripped", field)
+		}
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(service.Object, "spec", "selector"); !found {
+		t.Error("spec.selector was stripped")
+	}
+}
+
+func TestRedactSecretEnv(t *testing.T) {
+	deployment := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name": "mysql",
+							"env": []interface{}{
+								map[string]interface{}{"name": "MYSQL_ROOT_PASSWORD", "value": "s3cret"},
+								map[string]interface{}{"name": "MYSQL_PASSWORD", "value": "s3cret"},
+								map[string]interface{}{"name": "MYSQL_USER", "value": "app"},
+								map[string]interface{}{"name": "PMA_PASSWORD", "valueFrom": map[string]interface{}{
+									"secretKeyRef": map[string]interface{}{"name": "creds", "key": "password"},
+								}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}}
+
+	redactSecretEnv(deployment)
+
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	env := containers[0].(map[string]interface{})["env"].([]interface{})
+	want := map[string]interface{}{
+		"MYSQL_ROOT_PASSWORD": redactedValue,
+		"MYSQL_PASSWORD":      redactedValue,
+		"MYSQL_USER":          "app",
+		"PMA_PASSWORD":        nil,
+	}
+	for _, entry := range env {
+		envVar := entry.(map[string]interface{})
+		name := envVar["name"].(string)
+		if got := envVar["value"]; got != want[name] {
+			t.Errorf("%s = %v, want %v", name, got, want[name])
+		}
+	}
+}
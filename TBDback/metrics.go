@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Prometheus exporter sidecar images and the ports they serve /metrics on
+const (
+	postgresExporterImage = "quay.io/prometheuscommunity/postgres-exporter:latest"
+	postgresExporterPort  = 9187
+	mysqlExporterImage    = "prom/mysqld-exporter:latest"
+	mysqlExporterPort     = 9104
+)
+
+// metricsExporterContainer returns the exporter sidecar for a database type.
+// It reaches the database over localhost with the managed credentials.
+func metricsExporterContainer(dbRequest DatabaseRequest) (corev1.Container, int32) {
+	if dbRequest.Type == DatabaseTypeMySQL {
+		return corev1.Container{
+			Name:  "mysqld-exporter",
+			Image: mysqlExporterImage,
+			Args: []string{
+				"--mysqld.address=localhost:" + mustDefaultPort(DatabaseTypeMySQL),
+				"--mysqld.username=" + dbRequest.Username,
+			},
+			Env: []corev1.EnvVar{
+				{Name: "MYSQLD_EXPORTER_PASSWORD", Value: dbRequest.Password},
+			},
+		}, mysqlExporterPort
+	}
+
+	return corev1.Container{
+		Name:  "postgres-exporter",
+		Image: postgresExporterImage,
+		Env: []corev1.EnvVar{
+			{Name: "DATA_SOURCE_URI", Value: "localhost:" + mustDefaultPort(DatabaseTypePostgreSQL) + "/" + dbRequest.LogicalDatabaseName() + "?sslmode=disable"},
+			{Name: "DATA_SOURCE_USER", Value: dbRequest.Username},
+			{Name: "DATA_SOURCE_PASS", Value: dbRequest.Password},
+		},
+	}, postgresExporterPort
+}
+
+// addMetricsExporter injects a Prometheus exporter sidecar into the database
+// pod and annotates the pod for scraping. Annotations the user set explicitly
+// are kept. The sidecar is part of the pod, so deleting the database removes it.
+// Nothing is added while ENABLE_METRICS is off.
+func addMetricsExporter(deployment *appsv1.Deployment, dbRequest DatabaseRequest) {
+	if !dbRequest.EnableMetrics || appConfig == nil || !appConfig.Features.Metrics {
+		return
+	}
+
+	container, port := metricsExporterContainer(dbRequest)
+	container.Ports = []corev1.ContainerPort{
+		{Name: "metrics", ContainerPort: port},
+	}
+	container.Resources = corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceMemory: mustParseQuantity("32Mi"),
+			corev1.ResourceCPU:    mustParseQuantity("10m"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: mustParseQuantity("64Mi"),
+			corev1.ResourceCPU:    mustParseQuantity("100m"),
+		},
+	}
+
+	podSpec := &deployment.Spec.Template.Spec
+	podSpec.Containers = append(podSpec.Containers, container)
+
+	// Copy rather than mutate the request's annotations map
+	annotations := map[string]string{
+		"prometheus.io/scrape": "true",
+		"prometheus.io/port":   strconv.Itoa(int(port)),
+		"prometheus.io/path":   "/metrics",
+	}
+	for key, value := range deployment.Spec.Template.Annotations {
+		annotations[key] = value
+	}
+	deployment.Spec.Template.Annotations = annotations
+}
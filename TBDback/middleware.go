@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BouchamiAhmed/TBD/shared"
+)
+
+// contextKey is the type for values stored in request contexts
+type contextKey string
+
+const authClaimsKey contextKey = "authClaims"
+
+// requireAuth rejects requests without a valid bearer token and stores the
+// token's claims in the request context
+func requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(header, "Bearer ")
+		if header == "" || token == header {
+			respondError(w, http.StatusUnauthorized, "Authorization token required")
+			return
+		}
+
+		claims, err := ParseToken(token)
+		if err != nil {
+			fmt.Printf("Rejected token: %v\n", err)
+			respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), authClaimsKey, claims)
+
+		// Revoked tokens have no session row even though they still verify
+		if sessionDBClient != nil {
+			sessionID, err := sessionDBClient.TouchSession(r.Context(), token)
+			if errors.Is(err, sql.ErrNoRows) {
+				respondError(w, http.StatusUnauthorized, "Session revoked or expired")
+				return
+			}
+			if err != nil {
+				fmt.Printf("Error checking session: %v\n", err)
+				respondError(w, http.StatusInternalServerError, "Failed to verify session")
+				return
+			}
+			ctx = context.WithValue(ctx, sessionIDKey, sessionID)
+		}
+
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// requireAdmin is requireAuth restricted to the users listed in ADMIN_USERNAMES
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if !isAdmin(authFromContext(r.Context())) {
+			respondError(w, http.StatusForbidden, "Admin privileges required")
+			return
+		}
+		next(w, r)
+	})
+}
+
+// requireK8s responds 503 and returns false unless every Kubernetes client is
+// available. Handlers that touch the cluster call it first, so a process whose
+// clients only partly initialized refuses the request up front instead of
+// failing halfway through an operation.
+func requireK8s(w http.ResponseWriter) bool {
+	if clientset == nil || dynamicClient == nil {
+		respondK8sUnavailable(w)
+		return false
+	}
+	return true
+}
+
+// respondK8sUnavailable writes the uniform response for a missing Kubernetes client
+func respondK8sUnavailable(w http.ResponseWriter) {
+	respondErrorCode(w, http.StatusServiceUnavailable, "K8S_UNAVAILABLE", "Kubernetes unavailable")
+}
+
+// isAdmin reports whether the authenticated user is a configured admin
+func isAdmin(claims *TokenClaims) bool {
+	if claims == nil {
+		return false
+	}
+	for _, username := range appConfig.AdminUsernames {
+		if username == claims.Username {
+			return true
+		}
+	}
+	return false
+}
+
+// requireNamespaceAccess responds 403 and returns false unless the
+// authenticated user owns namespace or is an admin. Handlers under requireAuth
+// call it before touching anything addressed by a namespace in the URL.
+func requireNamespaceAccess(w http.ResponseWriter, r *http.Request, namespace string) bool {
+	claims := authFromContext(r.Context())
+	if isAdmin(claims) || (claims != nil && ownsUserNamespace(claims, namespace)) {
+		return true
+	}
+	respondError(w, http.StatusForbidden, "Cannot access another user's namespace")
+	return false
+}
+
+// ownsUserNamespace reports whether namespace is the caller's own, under
+// either the current or the truncated legacy naming scheme
+func ownsUserNamespace(claims *TokenClaims, namespace string) bool {
+	base := userNamespaceBase(claims.UserID, claims.Username)
+	return namespace == GetUserNamespace(claims.UserID, claims.Username) ||
+		namespace == shared.ShortenNamespaceName(base)
+}
+
+// authFromContext returns the authenticated user's claims, or nil
+func authFromContext(ctx context.Context) *TokenClaims {
+	claims, _ := ctx.Value(authClaimsKey).(*TokenClaims)
+	return claims
+}
+
+const requestIDKey contextKey = "requestID"
+
+// requestIDHeader carries the request ID in both directions
+const requestIDHeader = "X-Request-ID"
+
+// requestIDMiddleware assigns each request an ID (honoring a sane incoming
+// X-Request-ID), stores it in the context, echoes it in the response and
+// logs the request once it completes
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, requestID)
+		ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		logf(ctx, "method=%s path=%s status=%d duration=%s client_ip=%s\n", r.Method, r.URL.Path, recorder.status, time.Since(start), clientIP(r))
+	})
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code before writing it
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// newRequestID returns a random 16-character hex request ID
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// requestIDFromContext returns the request ID stored in the context, or ""
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// logf prints a log line prefixed with the request ID carried by ctx
+func logf(ctx context.Context, format string, args ...interface{}) {
+	if requestID := requestIDFromContext(ctx); requestID != "" {
+		format = "[request_id=" + requestID + "] " + format
+	}
+	fmt.Printf(format, args...)
+}
+
+// streaming exempts a long-lived handler (log streams, watches) from the
+// server's WriteTimeout by clearing the connection's write deadline
+func streaming(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			logf(r.Context(), "Warning: Could not clear write deadline for streaming response: %v\n", err)
+		}
+		next(w, r)
+	}
+}
+
+// timeoutMiddleware bounds every request's context by timeout so a handler
+// that forgets to limit its own work cannot hang the connection. When the
+// deadline passes first the client gets a 504 and anything the handler writes
+// afterwards is discarded. Requests whose path starts with one of
+// streamingPaths are passed through untouched, since a buffered response
+// would defeat the point of streaming.
+func timeoutMiddleware(timeout time.Duration, streamingPaths []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, prefix := range streamingPaths {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				for key, values := range tw.header {
+					w.Header()[key] = values
+				}
+				if tw.status == 0 {
+					tw.status = http.StatusOK
+				}
+				w.WriteHeader(tw.status)
+				w.Write(tw.body.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				logf(r.Context(), "⏱️ Request exceeded %s: %s %s\n", timeout, r.Method, r.URL.Path)
+				respondErrorCode(w, http.StatusGatewayTimeout, "TIMEOUT", "Request timed out")
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers a handler's response until timeoutMiddleware knows
+// whether it finished in time
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+// Header returns the buffered response headers
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// Write buffers b, failing once the request has timed out
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.body.Write(b)
+}
+
+// WriteHeader records the status code; only the first call counts
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+	tw.status = status
+}
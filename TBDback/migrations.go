@@ -0,0 +1,9 @@
+package main
+
+import "github.com/BouchamiAhmed/TBD/shared"
+
+// Migrate brings the control database schema up to date with the migrations
+// shared with the admin service
+func (c *DBClient) Migrate() error {
+	return shared.Migrate(c.db)
+}
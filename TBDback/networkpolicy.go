@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// namespaceNetworkPolicyName is the name of the NetworkPolicy isolating a user namespace
+const namespaceNetworkPolicyName = "db-saas-isolation"
+
+// ensureNetworkPolicy isolates a user namespace when ENABLE_NETWORK_POLICY is
+// on: its pods only accept traffic from the same namespace, from
+// TRAEFIK_NAMESPACE (so the admin dashboards stay reachable) and from the
+// namespaces in NETWORK_POLICY_ALLOWED_NAMESPACES. An existing policy is kept.
+func ensureNetworkPolicy(ctx context.Context, clientset kubernetes.Interface, namespace string) error {
+	if appConfig == nil || !appConfig.Features.NetworkPolicy {
+		return nil
+	}
+
+	peers := []networkingv1.NetworkPolicyPeer{
+		{PodSelector: &metav1.LabelSelector{}},
+	}
+	allowed := appConfig.NetworkPolicyNamespaces
+	if appConfig.TraefikNamespace != "" {
+		allowed = append([]string{appConfig.TraefikNamespace}, allowed...)
+	}
+	for _, name := range allowed {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"kubernetes.io/metadata.name": name},
+			},
+		})
+	}
+
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      namespaceNetworkPolicyName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "db-saas",
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: peers}},
+		},
+	}
+
+	_, err := clientset.NetworkingV1().NetworkPolicies(namespace).Create(ctx, policy, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create NetworkPolicy: %w", err)
+	}
+	logf(ctx, "✅ Created NetworkPolicy: %s\n", policy.Name)
+	return nil
+}
@@ -3,48 +3,83 @@ package main
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/BouchamiAhmed/TBD/apperrors"
+	"github.com/BouchamiAhmed/TBD/shared"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 )
 
-func ensureNamespace(ctx context.Context, clientset *kubernetes.Clientset, namespace string) error {
+func ensureNamespace(ctx context.Context, clientset kubernetes.Interface, namespace string) error {
 	_, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
 	if err != nil {
 		ns := &corev1.Namespace{
 			ObjectMeta: metav1.ObjectMeta{
 				Name: namespace,
-				Labels: map[string]string{
+				Labels: withExtraMetadata(withInstanceLabel(map[string]string{
 					"app.kubernetes.io/managed-by": "db-saas",
 					"db-saas/user-namespace":       "true",
-				},
+				}), appConfig.NamespaceLabels),
+				Annotations: withExtraMetadata(map[string]string{}, appConfig.NamespaceAnnotations),
 			},
 		}
 		_, err = clientset.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
 		if err != nil {
 			return err
 		}
-		fmt.Printf("✅ Created namespace: %s\n", namespace)
+		logf(ctx, "✅ Created namespace: %s\n", namespace)
 	}
 	return nil
 }
 
-// deployPostgreSQL deploys PostgreSQL database with pgAdmin and Traefik routing
-func deployPostgreSQL(ctx context.Context, clientset *kubernetes.Clientset, dbRequest DatabaseRequest, namespace string) error {
+// deployPostgreSQL deploys PostgreSQL database with pgAdmin and Traefik routing;
+// the dashboard is skipped when ENABLE_ADMIN_DASHBOARDS is off
+func deployPostgreSQL(ctx context.Context, clientset kubernetes.Interface, dbRequest DatabaseRequest, namespace string) error {
+	// Create init SQL ConfigMap before the deployment that mounts it
+	if dbRequest.InitSQL != "" {
+		if err := createInitSQLConfigMap(ctx, clientset, dbRequest, namespace); err != nil {
+			return err
+		}
+	}
+	if dbRequest.ReadOnlyUser {
+		if err := createReadOnlySecret(ctx, clientset, dbRequest, namespace); err != nil {
+			return err
+		}
+	}
+	if err := createDataPVC(ctx, clientset, dbRequest, namespace); err != nil {
+		return err
+	}
+
 	// Create PostgreSQL deployment
 	postgresDeployment := createPostgreSQLDeployment(dbRequest, namespace)
 	_, err := clientset.AppsV1().Deployments(namespace).Create(ctx, postgresDeployment, metav1.CreateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to create PostgreSQL deployment: %w", err)
 	}
-	fmt.Printf("✅ Created PostgreSQL deployment: %s\n", dbRequest.Name)
+	logf(ctx, "✅ Created PostgreSQL deployment: %s\n", dbRequest.Name)
+
+	if err := createPodDisruptionBudget(ctx, clientset, dbRequest, namespace); err != nil {
+		return err
+	}
+	if err := createBackupCronJob(ctx, clientset, dbRequest, postgresDeployment); err != nil {
+		return err
+	}
 
 	// Create PostgreSQL service
 	postgresService := createPostgreSQLService(dbRequest)
@@ -52,7 +87,13 @@ func deployPostgreSQL(ctx context.Context, clientset *kubernetes.Clientset, dbRe
 	if err != nil {
 		return fmt.Errorf("failed to create PostgreSQL service: %w", err)
 	}
-	fmt.Printf("✅ Created PostgreSQL service: %s\n", dbRequest.Name)
+	logf(ctx, "✅ Created PostgreSQL service: %s\n", dbRequest.Name)
+
+	if !adminDashboardsEnabled() {
+		return nil
+	}
+
+	waitForDatabaseReady(ctx, clientset, namespace, dbRequest.Name)
 
 	// Create pgAdmin deployment
 	pgAdminDeployment := createPgAdminDeployment(dbRequest, namespace)
@@ -60,7 +101,7 @@ func deployPostgreSQL(ctx context.Context, clientset *kubernetes.Clientset, dbRe
 	if err != nil {
 		return fmt.Errorf("failed to create pgAdmin deployment: %w", err)
 	}
-	fmt.Printf("✅ Created pgAdmin deployment: %s-pgadmin\n", dbRequest.Name)
+	logf(ctx, "✅ Created pgAdmin deployment: %s-pgadmin\n", dbRequest.Name)
 
 	// Create pgAdmin service (ClusterIP)
 	pgAdminService := createPgAdminService(dbRequest)
@@ -68,19 +109,19 @@ func deployPostgreSQL(ctx context.Context, clientset *kubernetes.Clientset, dbRe
 	if err != nil {
 		return fmt.Errorf("failed to create pgAdmin service: %w", err)
 	}
-	fmt.Printf("✅ Created pgAdmin ClusterIP service: %s-pgadmin\n", dbRequest.Name)
+	logf(ctx, "✅ Created pgAdmin ClusterIP service: %s-pgadmin\n", dbRequest.Name)
 
 	// Create ONLY headers middleware for pgAdmin (NO stripPrefix)
 	if err := createPgAdminMiddleware(ctx, dbRequest, namespace); err != nil {
 		return fmt.Errorf("failed to create pgAdmin middleware: %w", err)
 	}
-	fmt.Printf("✅ Created pgAdmin headers middleware (NO strip prefix)\n")
+	logf(ctx, "✅ Created pgAdmin headers middleware (NO strip prefix)\n")
 
 	// Create Traefik IngressRoute for pgAdmin (NO stripPrefix)
 	if err := createPgAdminIngressRoute(ctx, dbRequest, namespace, 80); err != nil {
 		return fmt.Errorf("failed to create pgAdmin IngressRoute: %w", err)
 	}
-	fmt.Printf("✅ Created pgAdmin IngressRoute (NO strip prefix)\n")
+	logf(ctx, "✅ Created pgAdmin IngressRoute (NO strip prefix)\n")
 
 	return nil
 }
@@ -97,8 +138,9 @@ func createPgAdminMiddleware(ctx context.Context, dbRequest DatabaseRequest, nam
 			"apiVersion": "traefik.io/v1alpha1",
 			"kind":       "Middleware",
 			"metadata": map[string]interface{}{
-				"name":      fmt.Sprintf("%s-pgadmin-headers", dbRequest.Name),
-				"namespace": namespace,
+				"name":      traefikHeadersMiddlewareName(namespace, dbRequest.Name, "pgadmin"),
+				"namespace": traefikNamespace(namespace),
+				"labels":    unstructuredLabels(withDatabaseLabels(map[string]string{shared.TraefikNamespaceLabel: namespace}, dbRequest)),
 			},
 			"spec": map[string]interface{}{
 				"headers": map[string]interface{}{
@@ -118,12 +160,12 @@ func createPgAdminMiddleware(ctx context.Context, dbRequest DatabaseRequest, nam
 		Resource: "middlewares",
 	}
 
-	_, err := dynamicClient.Resource(headersGVR).Namespace(namespace).Create(ctx, headersMiddleware, metav1.CreateOptions{})
+	_, err := dynamicClient.Resource(headersGVR).Namespace(traefikNamespace(namespace)).Create(ctx, headersMiddleware, metav1.CreateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to create headers middleware: %w", err)
 	}
 
-	fmt.Printf("✅ Created ONLY headers middleware for pgAdmin (no stripPrefix)\n")
+	logf(ctx, "✅ Created ONLY headers middleware for pgAdmin (no stripPrefix)\n")
 	return nil
 }
 
@@ -133,15 +175,15 @@ func createPgAdminIngressRoute(ctx context.Context, dbRequest DatabaseRequest, n
 		return fmt.Errorf("dynamic client not available")
 	}
 
-	ingressName := fmt.Sprintf("%s-pgadmin-ingress", dbRequest.Name)
+	ingressName := traefikIngressRouteName(namespace, dbRequest.Name, "pgadmin")
 	serviceName := fmt.Sprintf("%s-pgadmin", dbRequest.Name)
-	headersMW := fmt.Sprintf("%s-pgadmin-headers", dbRequest.Name)
-	pathPrefix := fmt.Sprintf("/%s/%s-pgadmin", namespace, dbRequest.Name)
+	headersMW := traefikHeadersMiddlewareName(namespace, dbRequest.Name, "pgadmin")
+	matchRule := pgAdminMatchRule(namespace, dbRequest.Name)
 
-	fmt.Printf("🔍 Creating pgAdmin IngressRoute:\n")
-	fmt.Printf("   - Service: %s (port %d)\n", serviceName, port)
-	fmt.Printf("   - Path: %s\n", pathPrefix)
-	fmt.Printf("   - Middleware: %s (headers ONLY, NO stripPrefix)\n", headersMW)
+	logf(ctx, "🔍 Creating pgAdmin IngressRoute:\n")
+	logf(ctx, "   - Service: %s (port %d)\n", serviceName, port)
+	logf(ctx, "   - Match: %s (%s routing)\n", matchRule, pgAdminRouting().Strategy())
+	logf(ctx, "   - Middleware: %s (headers ONLY, NO stripPrefix)\n", headersMW)
 
 	ingressRoute := &unstructured.Unstructured{
 		Object: map[string]interface{}{
@@ -149,17 +191,17 @@ func createPgAdminIngressRoute(ctx context.Context, dbRequest DatabaseRequest, n
 			"kind":       "IngressRoute",
 			"metadata": map[string]interface{}{
 				"name":      ingressName,
-				"namespace": namespace,
-				"labels": map[string]interface{}{
-					"app":                          serviceName,
-					"app.kubernetes.io/managed-by": "db-saas",
-				},
+				"namespace": traefikNamespace(namespace),
+				"labels": unstructuredLabels(withDatabaseLabels(map[string]string{
+					"app":                        serviceName,
+					shared.TraefikNamespaceLabel: namespace,
+				}, dbRequest)),
 			},
 			"spec": map[string]interface{}{
 				"entryPoints": []interface{}{"web"},
 				"routes": []interface{}{
 					map[string]interface{}{
-						"match": fmt.Sprintf(`Host("10.9.21.201") && PathPrefix("%s")`, pathPrefix),
+						"match": matchRule,
 						"kind":  "Rule",
 						// CRITICAL: ONLY headers middleware, NO stripPrefix
 						"middlewares": []interface{}{
@@ -167,8 +209,9 @@ func createPgAdminIngressRoute(ctx context.Context, dbRequest DatabaseRequest, n
 						},
 						"services": []interface{}{
 							map[string]interface{}{
-								"name": serviceName,
-								"port": port,
+								"name":      serviceName,
+								"namespace": namespace,
+								"port":      int64(port), // unstructured content must hold JSON types
 							},
 						},
 					},
@@ -183,31 +226,47 @@ func createPgAdminIngressRoute(ctx context.Context, dbRequest DatabaseRequest, n
 		Resource: "ingressroutes",
 	}
 
-	_, err := dynamicClient.Resource(gvr).Namespace(namespace).Create(ctx, ingressRoute, metav1.CreateOptions{})
+	if err := applyTraefikTLS(ingressRoute); err != nil {
+		return fmt.Errorf("failed to configure IngressRoute TLS: %w", err)
+	}
+
+	_, err := dynamicClient.Resource(gvr).Namespace(traefikNamespace(namespace)).Create(ctx, ingressRoute, metav1.CreateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to create IngressRoute: %w", err)
 	}
 
-	fmt.Printf("✅ Created pgAdmin IngressRoute: %s (NO stripPrefix)\n", ingressName)
+	logf(ctx, "✅ Created pgAdmin IngressRoute: %s (NO stripPrefix)\n", ingressName)
 	return nil
 }
 
 // Simplified pgAdmin deployment
 func createPgAdminDeployment(dbRequest DatabaseRequest, namespace string) *appsv1.Deployment {
 	replicas := int32(1)
-	scriptName := fmt.Sprintf("/%s/%s-pgadmin", namespace, dbRequest.Name)
 
-	fmt.Printf("🔍 pgAdmin SCRIPT_NAME: %s\n", scriptName)
-	fmt.Printf("🔍 pgAdmin should receive full paths like: %s/login\n", scriptName)
+	env := []corev1.EnvVar{
+		{Name: "PGADMIN_DEFAULT_EMAIL", Value: pgAdminEmail(dbRequest)},
+		{Name: "PGADMIN_DEFAULT_PASSWORD", Value: dbRequest.Password},
+		// Disable problematic features
+		{Name: "PGADMIN_CONFIG_WTF_CSRF_ENABLED", Value: "False"},
+		{Name: "PGADMIN_CONFIG_SESSION_COOKIE_SECURE", Value: "False"},
+		// Ensure it binds to all interfaces
+		{Name: "PGADMIN_LISTEN_ADDRESS", Value: "0.0.0.0"},
+		{Name: "PGADMIN_LISTEN_PORT", Value: "80"},
+	}
+
+	// CRITICAL: Tell pgAdmin its subdirectory when served under a path prefix
+	if pgAdminRouting().Strategy() == shared.PgAdminRoutingPathPrefix {
+		env = append(env, corev1.EnvVar{Name: "SCRIPT_NAME", Value: shared.PgAdminPathPrefix(namespace, dbRequest.Name)})
+	}
 
-	return &appsv1.Deployment{
+	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      dbRequest.Name + "-pgadmin",
 			Namespace: namespace,
-			Labels: map[string]string{
-				"app":                          dbRequest.Name + "-pgadmin",
-				"app.kubernetes.io/managed-by": "db-saas",
-			},
+			Labels: withDatabaseLabels(map[string]string{
+				"app":                         dbRequest.Name + "-pgadmin",
+				"app.kubernetes.io/component": "admin-dashboard",
+			}, dbRequest),
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: &replicas,
@@ -218,9 +277,9 @@ func createPgAdminDeployment(dbRequest DatabaseRequest, namespace string) *appsv
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
+					Labels: withDatabaseLabels(map[string]string{
 						"app": dbRequest.Name + "-pgadmin",
-					},
+					}, dbRequest),
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
@@ -229,27 +288,82 @@ func createPgAdminDeployment(dbRequest DatabaseRequest, namespace string) *appsv
 							Image: "dpage/pgadmin4:latest",
 							Ports: []corev1.ContainerPort{
 								{
+									Name:          "http",
 									ContainerPort: 80,
 								},
 							},
-							Env: []corev1.EnvVar{
-								{Name: "PGADMIN_DEFAULT_EMAIL", Value: fmt.Sprintf("%s@gmail.com", dbRequest.Username)},
-								{Name: "PGADMIN_DEFAULT_PASSWORD", Value: dbRequest.Password},
-								// CRITICAL: Tell pgAdmin its subdirectory
-								{Name: "SCRIPT_NAME", Value: scriptName},
-								// Disable problematic features
-								{Name: "PGADMIN_CONFIG_WTF_CSRF_ENABLED", Value: "False"},
-								{Name: "PGADMIN_CONFIG_SESSION_COOKIE_SECURE", Value: "False"},
-								// Ensure it binds to all interfaces
-								{Name: "PGADMIN_LISTEN_ADDRESS", Value: "0.0.0.0"},
-								{Name: "PGADMIN_LISTEN_PORT", Value: "80"},
-							},
+							Env: env,
 						},
 					},
 				},
 			},
 		},
 	}
+
+	// pgAdmin binds port 80 as a non-root user, so it keeps NET_BIND_SERVICE
+	applyPodSecurity(deployment, pgAdminUID, "NET_BIND_SERVICE")
+	applyAdminColocation(deployment, dbRequest.Name)
+	applyImagePullSettings(deployment)
+	return deployment
+}
+
+// applyImagePullSettings sets the configured image pull policy on every
+// container and references the configured pull secret, for private registries
+func applyImagePullSettings(deployment *appsv1.Deployment) {
+	if appConfig == nil {
+		return
+	}
+
+	podSpec := &deployment.Spec.Template.Spec
+	switch policy := corev1.PullPolicy(appConfig.ImagePullPolicy); policy {
+	case corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+		for i := range podSpec.Containers {
+			podSpec.Containers[i].ImagePullPolicy = policy
+		}
+	}
+
+	if appConfig.ImagePullSecret != "" {
+		podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets, corev1.LocalObjectReference{
+			Name: appConfig.ImagePullSecret,
+		})
+	}
+}
+
+// applyAdminColocation adds a preferred pod affinity so an admin dashboard is
+// scheduled on the same node as its database. Preferred rather than required,
+// so scheduling never blocks when the node is full.
+func applyAdminColocation(deployment *appsv1.Deployment, dbName string) {
+	if appConfig == nil || !appConfig.Features.AdminColocation {
+		return
+	}
+
+	deployment.Spec.Template.Spec.Affinity = &corev1.Affinity{
+		PodAffinity: &corev1.PodAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: corev1.PodAffinityTerm{
+						LabelSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"app": dbName},
+						},
+						TopologyKey: "kubernetes.io/hostname",
+					},
+				},
+			},
+		},
+	}
+}
+
+// servicePort builds a named TCP service port targeting the container port of
+// the same name, so the Service follows the pod if the container port changes.
+// Every generated Service goes through here since some service meshes require named ports.
+func servicePort(name string, port int32) corev1.ServicePort {
+	return corev1.ServicePort{
+		Name:       name,
+		Port:       port,
+		TargetPort: intstr.FromString(name),
+		Protocol:   corev1.ProtocolTCP,
+	}
 }
 
 // Simple pgAdmin service
@@ -257,16 +371,14 @@ func createPgAdminService(dbRequest DatabaseRequest) *corev1.Service {
 	return &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: dbRequest.Name + "-pgadmin",
-			Labels: map[string]string{
-				"app": dbRequest.Name + "-pgadmin",
-			},
+			Labels: withDatabaseLabels(map[string]string{
+				"app":                         dbRequest.Name + "-pgadmin",
+				"app.kubernetes.io/component": "admin-dashboard",
+			}, dbRequest),
 		},
 		Spec: corev1.ServiceSpec{
 			Ports: []corev1.ServicePort{
-				{
-					Port:       80,
-					TargetPort: intstr.FromInt(80),
-				},
+				servicePort("http", 80),
 			},
 			Selector: map[string]string{
 				"app": dbRequest.Name + "-pgadmin",
@@ -288,8 +400,9 @@ func createTraefikMiddleware(ctx context.Context, dbRequest DatabaseRequest, nam
 			"apiVersion": "traefik.io/v1alpha1",
 			"kind":       "Middleware",
 			"metadata": map[string]interface{}{
-				"name":      fmt.Sprintf("%s-%s-headers", dbRequest.Name, adminType),
-				"namespace": namespace,
+				"name":      traefikHeadersMiddlewareName(namespace, dbRequest.Name, adminType),
+				"namespace": traefikNamespace(namespace),
+				"labels":    unstructuredLabels(withDatabaseLabels(map[string]string{shared.TraefikNamespaceLabel: namespace}, dbRequest)),
 			},
 			"spec": map[string]interface{}{
 				"headers": map[string]interface{}{
@@ -309,7 +422,7 @@ func createTraefikMiddleware(ctx context.Context, dbRequest DatabaseRequest, nam
 		Resource: "middlewares",
 	}
 
-	if _, err := dynamicClient.Resource(headersGVR).Namespace(namespace).Create(ctx, headersMiddleware, metav1.CreateOptions{}); err != nil {
+	if _, err := dynamicClient.Resource(headersGVR).Namespace(traefikNamespace(namespace)).Create(ctx, headersMiddleware, metav1.CreateOptions{}); err != nil {
 		return fmt.Errorf("failed to create headers middleware: %w", err)
 	}
 
@@ -322,8 +435,9 @@ func createTraefikMiddleware(ctx context.Context, dbRequest DatabaseRequest, nam
 				"apiVersion": "traefik.io/v1alpha1",
 				"kind":       "Middleware",
 				"metadata": map[string]interface{}{
-					"name":      fmt.Sprintf("%s-%s-replacepath", dbRequest.Name, adminType),
-					"namespace": namespace,
+					"name":      traefikReplacePathMiddlewareName(namespace, dbRequest.Name, adminType),
+					"namespace": traefikNamespace(namespace),
+					"labels":    unstructuredLabels(withDatabaseLabels(map[string]string{shared.TraefikNamespaceLabel: namespace}, dbRequest)),
 				},
 				"spec": map[string]interface{}{
 					"replacePathRegex": map[string]interface{}{
@@ -334,14 +448,14 @@ func createTraefikMiddleware(ctx context.Context, dbRequest DatabaseRequest, nam
 			},
 		}
 
-		if _, err := dynamicClient.Resource(headersGVR).Namespace(namespace).Create(ctx, replacePathMiddleware, metav1.CreateOptions{}); err != nil {
+		if _, err := dynamicClient.Resource(headersGVR).Namespace(traefikNamespace(namespace)).Create(ctx, replacePathMiddleware, metav1.CreateOptions{}); err != nil {
 			return fmt.Errorf("failed to create replacePathRegex middleware: %w", err)
 		}
 
-		fmt.Printf("✅ Created headers and replacePathRegex middlewares for %s-%s\n", dbRequest.Name, adminType)
-		fmt.Printf("💡 phpMyAdmin: path %s will be rewritten using regex\n", pathPrefix)
+		logf(ctx, "✅ Created headers and replacePathRegex middlewares for %s-%s\n", dbRequest.Name, adminType)
+		logf(ctx, "💡 phpMyAdmin: path %s will be rewritten using regex\n", pathPrefix)
 	} else if adminType == "pgadmin" {
-		fmt.Printf("✅ Created headers middleware for %s-%s (NO path rewriting for pgAdmin)\n", dbRequest.Name, adminType)
+		logf(ctx, "✅ Created headers middleware for %s-%s (NO path rewriting for pgAdmin)\n", dbRequest.Name, adminType)
 	}
 
 	return nil
@@ -353,21 +467,26 @@ func createTraefikIngressRoute(ctx context.Context, dbRequest DatabaseRequest, n
 		return fmt.Errorf("dynamic client not available")
 	}
 
-	ingressName := fmt.Sprintf("%s-%s-ingress", dbRequest.Name, adminType)
+	ingressName := traefikIngressRouteName(namespace, dbRequest.Name, adminType)
 	serviceName := fmt.Sprintf("%s-%s", dbRequest.Name, adminType)
-	headersMW := fmt.Sprintf("%s-%s-headers", dbRequest.Name, adminType)
+	headersMW := traefikHeadersMiddlewareName(namespace, dbRequest.Name, adminType)
 	pathPrefix := fmt.Sprintf("/%s/%s-%s", namespace, dbRequest.Name, adminType)
 
+	matchRule := traefikHostPathRule(publicHost(), pathPrefix)
+	if adminType == "pgadmin" {
+		matchRule = pgAdminMatchRule(namespace, dbRequest.Name)
+	}
+
 	var middlewares []interface{}
 	middlewares = append(middlewares, map[string]interface{}{"name": headersMW})
 
 	// ONLY add replacePathRegex for phpMyAdmin, NOT for pgAdmin
 	if adminType == "phpmyadmin" {
-		replacePathMW := fmt.Sprintf("%s-%s-replacepath", dbRequest.Name, adminType)
+		replacePathMW := traefikReplacePathMiddlewareName(namespace, dbRequest.Name, adminType)
 		middlewares = append(middlewares, map[string]interface{}{"name": replacePathMW})
-		fmt.Printf("🔍 phpMyAdmin IngressRoute: PathPrefix=%s WITH ReplacePathRegex\n", pathPrefix)
+		logf(ctx, "🔍 phpMyAdmin IngressRoute: PathPrefix=%s WITH ReplacePathRegex\n", pathPrefix)
 	} else if adminType == "pgadmin" {
-		fmt.Printf("🔍 pgAdmin IngressRoute: PathPrefix=%s WITHOUT path rewriting\n", pathPrefix)
+		logf(ctx, "🔍 pgAdmin IngressRoute: PathPrefix=%s WITHOUT path rewriting\n", pathPrefix)
 	}
 
 	ingressRoute := &unstructured.Unstructured{
@@ -376,23 +495,24 @@ func createTraefikIngressRoute(ctx context.Context, dbRequest DatabaseRequest, n
 			"kind":       "IngressRoute",
 			"metadata": map[string]interface{}{
 				"name":      ingressName,
-				"namespace": namespace,
-				"labels": map[string]interface{}{
-					"app":                          serviceName,
-					"app.kubernetes.io/managed-by": "db-saas",
-				},
+				"namespace": traefikNamespace(namespace),
+				"labels": unstructuredLabels(withDatabaseLabels(map[string]string{
+					"app":                        serviceName,
+					shared.TraefikNamespaceLabel: namespace,
+				}, dbRequest)),
 			},
 			"spec": map[string]interface{}{
 				"entryPoints": []interface{}{"web"},
 				"routes": []interface{}{
 					map[string]interface{}{
-						"match":       fmt.Sprintf(`Host("10.9.21.201") && PathPrefix("%s")`, pathPrefix),
+						"match":       matchRule,
 						"kind":        "Rule",
 						"middlewares": middlewares,
 						"services": []interface{}{
 							map[string]interface{}{
-								"name": serviceName,
-								"port": port,
+								"name":      serviceName,
+								"namespace": namespace,
+								"port":      int64(port),
 							},
 						},
 					},
@@ -407,12 +527,16 @@ func createTraefikIngressRoute(ctx context.Context, dbRequest DatabaseRequest, n
 		Resource: "ingressroutes",
 	}
 
-	_, err := dynamicClient.Resource(gvr).Namespace(namespace).Create(ctx, ingressRoute, metav1.CreateOptions{})
+	if err := applyTraefikTLS(ingressRoute); err != nil {
+		return fmt.Errorf("failed to configure IngressRoute TLS: %w", err)
+	}
+
+	_, err := dynamicClient.Resource(gvr).Namespace(traefikNamespace(namespace)).Create(ctx, ingressRoute, metav1.CreateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to create IngressRoute: %w", err)
 	}
 
-	fmt.Printf("✅ Created IngressRoute: %s\n", ingressName)
+	logf(ctx, "✅ Created IngressRoute: %s\n", ingressName)
 	return nil
 }
 
@@ -420,17 +544,14 @@ func createTraefikIngressRoute(ctx context.Context, dbRequest DatabaseRequest, n
 func createPhpMyAdminDeployment(dbRequest DatabaseRequest, namespace string) *appsv1.Deployment {
 	replicas := int32(1)
 
-	return &appsv1.Deployment{
+	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      dbRequest.Name + "-phpmyadmin",
 			Namespace: namespace,
-			Labels: map[string]string{
-				"app":                          dbRequest.Name + "-phpmyadmin",
-				"app.kubernetes.io/component":  "admin-dashboard",
-				"app.kubernetes.io/managed-by": "db-saas",
-				"db-saas/type":                 "phpmyadmin",
-				"db-saas/user-id":              strconv.Itoa(dbRequest.UserID),
-			},
+			Labels: withDatabaseLabels(map[string]string{
+				"app":                         dbRequest.Name + "-phpmyadmin",
+				"app.kubernetes.io/component": "admin-dashboard",
+			}, dbRequest),
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: &replicas,
@@ -441,19 +562,21 @@ func createPhpMyAdminDeployment(dbRequest DatabaseRequest, namespace string) *ap
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
+					Labels: withDatabaseLabels(map[string]string{
 						"app": dbRequest.Name + "-phpmyadmin",
-					},
+					}, dbRequest),
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
 						{
 							Name:  "phpmyadmin",
 							Image: "phpmyadmin:5.2",
-							Ports: []corev1.ContainerPort{{ContainerPort: 80}},
+							Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: phpMyAdminPort}},
 							Env: []corev1.EnvVar{
+								// Listen on an unprivileged port so Apache can run as www-data
+								{Name: "APACHE_PORT", Value: strconv.Itoa(int(phpMyAdminPort))},
 								{Name: "PMA_HOST", Value: dbRequest.Name},
-								{Name: "PMA_PORT", Value: "3306"},
+								{Name: "PMA_PORT", Value: mustDefaultPort(DatabaseTypeMySQL)},
 								{Name: "PMA_USER", Value: dbRequest.Username},
 								{Name: "PMA_PASSWORD", Value: dbRequest.Password},
 								{Name: "MYSQL_ROOT_PASSWORD", Value: dbRequest.Password},
@@ -475,22 +598,24 @@ func createPhpMyAdminDeployment(dbRequest DatabaseRequest, namespace string) *ap
 			},
 		},
 	}
+
+	applyPodSecurity(deployment, phpMyAdminUID)
+	applyAdminColocation(deployment, dbRequest.Name)
+	applyImagePullSettings(deployment)
+	return deployment
 }
 
 // MySQL resource creation functions
 func createMySQLDeployment(dbRequest DatabaseRequest, namespace string) *appsv1.Deployment {
 	replicas := int32(1)
-	return &appsv1.Deployment{
+	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      dbRequest.Name,
 			Namespace: namespace,
-			Labels: map[string]string{
-				"app":                          dbRequest.Name,
-				"app.kubernetes.io/component":  "database",
-				"app.kubernetes.io/managed-by": "db-saas",
-				"db-saas/type":                 "mysql",
-				"db-saas/user-id":              strconv.Itoa(dbRequest.UserID),
-			},
+			Labels: withDatabaseLabels(map[string]string{
+				"app":                         dbRequest.Name,
+				"app.kubernetes.io/component": "database",
+			}, dbRequest),
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: &replicas,
@@ -501,60 +626,60 @@ func createMySQLDeployment(dbRequest DatabaseRequest, namespace string) *appsv1.
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
+					Labels: withDatabaseLabels(map[string]string{
 						"app": dbRequest.Name,
-					},
+					}, dbRequest),
+					Annotations: dbRequest.PodAnnotations,
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
 						{
 							Name:  "mysql",
 							Image: "mysql:latest",
+							Args:  serverArgs("mysqld", dbRequest.Args),
 							Ports: []corev1.ContainerPort{
 								{
-									ContainerPort: 3306,
+									Name:          "mysql",
+									ContainerPort: mustDefaultPortNumber(DatabaseTypeMySQL),
 								},
 							},
-							Env: []corev1.EnvVar{
+							Env: withCustomEnv([]corev1.EnvVar{
 								{Name: "MYSQL_ROOT_PASSWORD", Value: dbRequest.Password},
-								{Name: "MYSQL_DATABASE", Value: dbRequest.Name},
+								{Name: "MYSQL_DATABASE", Value: dbRequest.LogicalDatabaseName()},
 								{Name: "MYSQL_USER", Value: dbRequest.Username},
 								{Name: "MYSQL_PASSWORD", Value: dbRequest.Password},
-							},
-							Resources: corev1.ResourceRequirements{
-								Requests: corev1.ResourceList{
-									corev1.ResourceMemory: mustParseQuantity("256Mi"),
-									corev1.ResourceCPU:    mustParseQuantity("100m"),
-								},
-								Limits: corev1.ResourceList{
-									corev1.ResourceMemory: mustParseQuantity("512Mi"),
-									corev1.ResourceCPU:    mustParseQuantity("500m"),
-								},
-							},
+							}, dbRequest.Env),
+							Resources: databaseResources(dbRequest),
 						},
 					},
 				},
 			},
 		},
 	}
+
+	addDataVolume(deployment, dbRequest)
+	addInitSQLVolume(deployment, dbRequest)
+	addReadOnlySQLVolume(deployment, dbRequest)
+	addMetricsExporter(deployment, dbRequest)
+	applyPodSecurity(deployment, mysqlUID)
+	applyDatabaseProbes(deployment, DatabaseTypeMySQL)
+	applyEnvironmentLabel(deployment, dbRequest)
+	applyImagePullSettings(deployment)
+	return deployment
 }
 
 func createMySQLService(dbRequest DatabaseRequest) *corev1.Service {
 	return &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: dbRequest.Name,
-			Labels: map[string]string{
-				"app": dbRequest.Name,
-			},
+			Labels: withDatabaseLabels(map[string]string{
+				"app":                         dbRequest.Name,
+				"app.kubernetes.io/component": "database",
+			}, dbRequest),
 		},
 		Spec: corev1.ServiceSpec{
 			Ports: []corev1.ServicePort{
-				{
-					Port:       3306,
-					TargetPort: intstr.FromInt(3306),
-					Protocol:   corev1.ProtocolTCP,
-					Name:       "mysql",
-				},
+				servicePort("mysql", mustDefaultPortNumber(DatabaseTypeMySQL)),
 			},
 			Selector: map[string]string{
 				"app": dbRequest.Name,
@@ -632,20 +757,14 @@ func createPhpMyAdminService(dbRequest DatabaseRequest) *corev1.Service {
 	return &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: dbRequest.Name + "-phpmyadmin",
-			Labels: map[string]string{
-				"app":                          dbRequest.Name + "-phpmyadmin",
-				"app.kubernetes.io/component":  "admin-dashboard",
-				"app.kubernetes.io/managed-by": "db-saas",
-			},
+			Labels: withDatabaseLabels(map[string]string{
+				"app":                         dbRequest.Name + "-phpmyadmin",
+				"app.kubernetes.io/component": "admin-dashboard",
+			}, dbRequest),
 		},
 		Spec: corev1.ServiceSpec{
 			Ports: []corev1.ServicePort{
-				{
-					Port:       80, // Internal cluster port
-					TargetPort: intstr.FromInt(80),
-					Protocol:   corev1.ProtocolTCP,
-					Name:       "http",
-				},
+				servicePort("http", 80), // Port 80 for Traefik, forwarded to Apache's unprivileged port
 			},
 			Selector: map[string]string{
 				"app": dbRequest.Name + "-phpmyadmin",
@@ -658,17 +777,14 @@ func createPhpMyAdminService(dbRequest DatabaseRequest) *corev1.Service {
 // PostgreSQL resource creation functions
 func createPostgreSQLDeployment(dbRequest DatabaseRequest, namespace string) *appsv1.Deployment {
 	replicas := int32(1)
-	return &appsv1.Deployment{
+	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      dbRequest.Name,
 			Namespace: namespace,
-			Labels: map[string]string{
-				"app":                          dbRequest.Name,
-				"app.kubernetes.io/component":  "database",
-				"app.kubernetes.io/managed-by": "db-saas",
-				"db-saas/type":                 "postgresql",
-				"db-saas/user-id":              strconv.Itoa(dbRequest.UserID),
-			},
+			Labels: withDatabaseLabels(map[string]string{
+				"app":                         dbRequest.Name,
+				"app.kubernetes.io/component": "database",
+			}, dbRequest),
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: &replicas,
@@ -679,59 +795,59 @@ func createPostgreSQLDeployment(dbRequest DatabaseRequest, namespace string) *ap
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
+					Labels: withDatabaseLabels(map[string]string{
 						"app": dbRequest.Name,
-					},
+					}, dbRequest),
+					Annotations: dbRequest.PodAnnotations,
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
 						{
 							Name:  "postgres",
 							Image: "postgres:latest",
+							Args:  serverArgs("postgres", dbRequest.Args),
 							Ports: []corev1.ContainerPort{
 								{
-									ContainerPort: 5432,
+									Name:          "postgres",
+									ContainerPort: mustDefaultPortNumber(DatabaseTypePostgreSQL),
 								},
 							},
-							Env: []corev1.EnvVar{
-								{Name: "POSTGRES_DB", Value: dbRequest.Name},
+							Env: withCustomEnv([]corev1.EnvVar{
+								{Name: "POSTGRES_DB", Value: dbRequest.LogicalDatabaseName()},
 								{Name: "POSTGRES_USER", Value: dbRequest.Username},
 								{Name: "POSTGRES_PASSWORD", Value: dbRequest.Password},
-							},
-							Resources: corev1.ResourceRequirements{
-								Requests: corev1.ResourceList{
-									corev1.ResourceMemory: mustParseQuantity("256Mi"),
-									corev1.ResourceCPU:    mustParseQuantity("100m"),
-								},
-								Limits: corev1.ResourceList{
-									corev1.ResourceMemory: mustParseQuantity("512Mi"),
-									corev1.ResourceCPU:    mustParseQuantity("500m"),
-								},
-							},
+							}, dbRequest.Env),
+							Resources: databaseResources(dbRequest),
 						},
 					},
 				},
 			},
 		},
 	}
+
+	addDataVolume(deployment, dbRequest)
+	addInitSQLVolume(deployment, dbRequest)
+	addReadOnlySQLVolume(deployment, dbRequest)
+	addMetricsExporter(deployment, dbRequest)
+	applyPodSecurity(deployment, postgresUID)
+	applyDatabaseProbes(deployment, DatabaseTypePostgreSQL)
+	applyEnvironmentLabel(deployment, dbRequest)
+	applyImagePullSettings(deployment)
+	return deployment
 }
 
 func createPostgreSQLService(dbRequest DatabaseRequest) *corev1.Service {
 	return &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: dbRequest.Name,
-			Labels: map[string]string{
-				"app": dbRequest.Name,
-			},
+			Labels: withDatabaseLabels(map[string]string{
+				"app":                         dbRequest.Name,
+				"app.kubernetes.io/component": "database",
+			}, dbRequest),
 		},
 		Spec: corev1.ServiceSpec{
 			Ports: []corev1.ServicePort{
-				{
-					Port:       5432,
-					TargetPort: intstr.FromInt(5432),
-					Protocol:   corev1.ProtocolTCP,
-					Name:       "postgres",
-				},
+				servicePort("postgres", mustDefaultPortNumber(DatabaseTypePostgreSQL)),
 			},
 			Selector: map[string]string{
 				"app": dbRequest.Name,
@@ -741,131 +857,276 @@ func createPostgreSQLService(dbRequest DatabaseRequest) *corev1.Service {
 	}
 }
 
-// deleteDatabaseDeployment removes all resources for a database
-func deleteDatabaseDeployment(dbName, namespace string) error {
-	ctx := context.Background()
+// deleteDatabaseDeployment removes all resources for a database, returning once
+// its deployments and their pods are gone. gracePeriod, when non-nil, overrides
+// the pods' termination grace period.
+func deleteDatabaseDeployment(ctx context.Context, dbName, namespace string, gracePeriod *int64) error {
+	// Serialize operations on the same namespace
+	unlock, err := lockNamespace(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return deleteDatabaseDeploymentLocked(ctx, dbName, namespace, gracePeriod)
+}
+
+// deleteDatabaseDeploymentLocked is deleteDatabaseDeployment for a caller
+// already holding the namespace lock
+func deleteDatabaseDeploymentLocked(ctx context.Context, dbName, namespace string, gracePeriod *int64) error {
+	logf(ctx, "🗑️ Starting deletion of database '%s' in namespace '%s'\n", dbName, namespace)
+
+	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, dbName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return apperrors.New(apperrors.ErrNotFound, "database '%s' not found in namespace '%s'", dbName, namespace)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get database deployment: %w", err)
+	}
 
-	fmt.Printf("🗑️ Starting deletion of database '%s' in namespace '%s'\n", dbName, namespace)
+	// Every object of a labeled database is found by its database label
+	if deployment.Labels[databaseLabel] == dbName {
+		return deleteLabeledDatabaseResources(ctx, dbName, namespace, gracePeriod)
+	}
 
-	// First, determine the database type by checking existing deployments
-	dbType, err := getDatabaseType(dbName, namespace)
+	// Databases created before objects were labeled are deleted by name,
+	// which needs the database type to know the admin dashboard's objects
+	logf(ctx, "⚠️  Database '%s' predates the %s label, deleting its objects by name\n", dbName, databaseLabel)
+	dbType, err := getDatabaseType(ctx, dbName, namespace)
 	if err != nil {
 		return fmt.Errorf("failed to determine database type: %w", err)
 	}
 
-	fmt.Printf("📝 Detected database type: %s\n", dbType)
+	logf(ctx, "📝 Detected database type: %s\n", dbType)
 
 	// Delete based on database type
-	if dbType == "mysql" {
-		return deleteMySQLResources(ctx, dbName, namespace)
-	} else if dbType == "postgresql" {
-		return deletePostgreSQLResources(ctx, dbName, namespace)
+	if dbType == DatabaseTypeMySQL {
+		return deleteMySQLResources(ctx, dbName, namespace, gracePeriod)
+	} else if dbType == DatabaseTypePostgreSQL {
+		return deletePostgreSQLResources(ctx, dbName, namespace, gracePeriod)
 	}
 
 	return fmt.Errorf("unknown database type: %s", dbType)
 }
 
-// getDatabaseType determines if database is MySQL or PostgreSQL
-func getDatabaseType(dbName, namespace string) (string, error) {
-	ctx := context.Background()
+// batchDeleteWorkers bounds how many databases are deleted concurrently in a batch
+const batchDeleteWorkers = 4
+
+// deleteDatabasesBatch deletes each named database the way a single delete
+// does, concurrently with a bounded worker pool, from a namespace the caller
+// has already locked. Individual failures do not stop the rest of the batch.
+func deleteDatabasesBatch(ctx context.Context, dbClient *DBClient, names []string, namespace string, gracePeriod *int64) map[string]BatchDeleteResult {
+	results := make(map[string]BatchDeleteResult, len(names))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	jobs := make(chan string)
+	for i := 0; i < batchDeleteWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for dbName := range jobs {
+				deletion, err := deleteDatabase(ctx, dbClient, dbName, namespace, gracePeriod)
+				result := BatchDeleteResult{Success: true, DatabaseDeletion: deletion}
+				if err != nil {
+					logf(ctx, "❌ Batch delete of '%s' failed: %v\n", dbName, err)
+					result.Success = false
+					result.Error = err.Error()
+				}
+				mu.Lock()
+				results[dbName] = result
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, dbName := range names {
+		jobs <- dbName
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// batchCreateWorkers bounds how many databases are deployed concurrently in a batch
+const batchCreateWorkers = 4
+
+// createDatabasesBatch deploys each request concurrently with a bounded worker
+// pool into a namespace the caller has already ensured. Results are returned in
+// request order and individual failures do not stop the rest of the batch.
+func createDatabasesBatch(ctx context.Context, requests []DatabaseRequest, namespace string) []BatchCreateResult {
+	results := make([]BatchCreateResult, len(requests))
+	var wg sync.WaitGroup
+
+	jobs := make(chan int)
+	for i := 0; i < batchCreateWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				dbRequest := requests[idx]
+				if err := deployDatabase(ctx, clientset, dbRequest, namespace); err != nil {
+					logf(ctx, "❌ Batch create of '%s' failed: %v\n", dbRequest.Name, err)
+					results[idx] = BatchCreateResult{Name: dbRequest.Name, Success: false, Error: err.Error()}
+					continue
+				}
+				response := newDatabaseResponse(dbRequest, namespace)
+				results[idx] = BatchCreateResult{Name: dbRequest.Name, Success: true, Database: &response}
+			}
+		}()
+	}
+
+	for idx := range requests {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
 
+// getDatabaseType determines if database is MySQL or PostgreSQL
+func getDatabaseType(ctx context.Context, dbName, namespace string) (string, error) {
 	// Check deployment labels to determine type
 	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, dbName, metav1.GetOptions{})
 	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", apperrors.New(apperrors.ErrNotFound, "database '%s' not found in namespace '%s'", dbName, namespace)
+		}
 		return "", err
 	}
 
-	if dbType, exists := deployment.Labels["db-saas/type"]; exists {
-		return dbType, nil
+	if _, exists := deployment.Labels["db-saas/type"]; exists {
+		return labeledDatabaseType(deployment.Labels), nil
+	}
+
+	// Fallback for legacy or partially-labeled databases: inspect the container image
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		image := strings.ToLower(container.Image)
+		switch {
+		case strings.Contains(image, "mysql") || strings.Contains(image, "mariadb"):
+			logf(ctx, "⚠️  Database type label missing on '%s', inferred 'mysql' from image %s\n", dbName, container.Image)
+			return DatabaseTypeMySQL, nil
+		case strings.Contains(image, "postgres"):
+			logf(ctx, "⚠️  Database type label missing on '%s', inferred 'postgresql' from image %s\n", dbName, container.Image)
+			return DatabaseTypePostgreSQL, nil
+		}
 	}
 
-	return "", fmt.Errorf("database type not found in labels")
+	// Then look for the admin dashboard deployed alongside it
+	if _, err := clientset.AppsV1().Deployments(namespace).Get(ctx, dbName+"-phpmyadmin", metav1.GetOptions{}); err == nil {
+		logf(ctx, "⚠️  Database type label missing on '%s', inferred 'mysql' from phpMyAdmin sibling\n", dbName)
+		return DatabaseTypeMySQL, nil
+	}
+	if _, err := clientset.AppsV1().Deployments(namespace).Get(ctx, dbName+"-pgadmin", metav1.GetOptions{}); err == nil {
+		logf(ctx, "⚠️  Database type label missing on '%s', inferred 'postgresql' from pgAdmin sibling\n", dbName)
+		return DatabaseTypePostgreSQL, nil
+	}
+
+	return "", fmt.Errorf("database type not found in labels and could not be inferred")
 }
 
-// deleteMySQLResources removes all MySQL-related resources
-func deleteMySQLResources(ctx context.Context, dbName, namespace string) error {
-	fmt.Printf("🗑️ Deleting MySQL resources for '%s'\n", dbName)
+// deleteMySQLResources removes all MySQL-related resources by name, for
+// databases created before their objects carried the database label
+func deleteMySQLResources(ctx context.Context, dbName, namespace string, gracePeriod *int64) error {
+	logf(ctx, "🗑️ Deleting MySQL resources for '%s'\n", dbName)
 
 	// Delete Traefik IngressRoute
 	if err := deleteTraefikIngressRoute(ctx, dbName, namespace, "phpmyadmin"); err != nil {
-		fmt.Printf("Warning: Failed to delete IngressRoute: %v\n", err)
+		logf(ctx, "Warning: Failed to delete IngressRoute: %v\n", err)
 	}
 
 	// Delete Traefik Middleware
 	if err := deleteTraefikMiddleware(ctx, dbName, namespace, "phpmyadmin"); err != nil {
-		fmt.Printf("Warning: Failed to delete Middleware: %v\n", err)
+		logf(ctx, "Warning: Failed to delete Middleware: %v\n", err)
 	}
 
 	// Delete phpMyAdmin service
 	if err := clientset.CoreV1().Services(namespace).Delete(ctx, dbName+"-phpmyadmin", metav1.DeleteOptions{}); err != nil {
-		fmt.Printf("Warning: Failed to delete phpMyAdmin service: %v\n", err)
+		logf(ctx, "Warning: Failed to delete phpMyAdmin service: %v\n", err)
 	} else {
-		fmt.Printf("✅ Deleted phpMyAdmin service\n")
+		logf(ctx, "✅ Deleted phpMyAdmin service\n")
 	}
 
 	// Delete phpMyAdmin deployment
-	if err := clientset.AppsV1().Deployments(namespace).Delete(ctx, dbName+"-phpmyadmin", metav1.DeleteOptions{}); err != nil {
-		fmt.Printf("Warning: Failed to delete phpMyAdmin deployment: %v\n", err)
+	if err := deleteDeploymentAndWait(ctx, namespace, dbName+"-phpmyadmin", gracePeriod); err != nil {
+		logf(ctx, "Warning: Failed to delete phpMyAdmin deployment: %v\n", err)
 	} else {
-		fmt.Printf("✅ Deleted phpMyAdmin deployment\n")
+		logf(ctx, "✅ Deleted phpMyAdmin deployment\n")
 	}
 
 	// Delete MySQL service
 	if err := clientset.CoreV1().Services(namespace).Delete(ctx, dbName, metav1.DeleteOptions{}); err != nil {
-		fmt.Printf("Warning: Failed to delete MySQL service: %v\n", err)
+		logf(ctx, "Warning: Failed to delete MySQL service: %v\n", err)
 	} else {
-		fmt.Printf("✅ Deleted MySQL service\n")
+		logf(ctx, "✅ Deleted MySQL service\n")
 	}
 
 	// Delete MySQL deployment
-	if err := clientset.AppsV1().Deployments(namespace).Delete(ctx, dbName, metav1.DeleteOptions{}); err != nil {
+	if err := deleteDeploymentAndWait(ctx, namespace, dbName, gracePeriod); err != nil {
 		return fmt.Errorf("failed to delete MySQL deployment: %w", err)
 	}
-	fmt.Printf("✅ Deleted MySQL deployment\n")
+	logf(ctx, "✅ Deleted MySQL deployment\n")
+
+	// Delete init SQL ConfigMap (only present when the database was seeded)
+	deleteInitSQLConfigMap(ctx, dbName, namespace)
+	deleteReadOnlySecret(ctx, dbName, namespace)
+	deletePodDisruptionBudget(ctx, dbName, namespace)
+	deleteBackupCronJob(ctx, dbName, namespace)
+	deleteDataPVC(ctx, dbName, namespace)
 
 	return nil
 }
 
-// deletePostgreSQLResources removes all PostgreSQL-related resources
-func deletePostgreSQLResources(ctx context.Context, dbName, namespace string) error {
-	fmt.Printf("🗑️ Deleting PostgreSQL resources for '%s'\n", dbName)
+// deletePostgreSQLResources removes all PostgreSQL-related resources by name, for
+// databases created before their objects carried the database label
+func deletePostgreSQLResources(ctx context.Context, dbName, namespace string, gracePeriod *int64) error {
+	logf(ctx, "🗑️ Deleting PostgreSQL resources for '%s'\n", dbName)
 
 	// Delete Traefik IngressRoute
 	if err := deleteTraefikIngressRoute(ctx, dbName, namespace, "pgadmin"); err != nil {
-		fmt.Printf("Warning: Failed to delete IngressRoute: %v\n", err)
+		logf(ctx, "Warning: Failed to delete IngressRoute: %v\n", err)
 	}
 
 	// Delete Traefik Middleware
 	if err := deleteTraefikMiddleware(ctx, dbName, namespace, "pgadmin"); err != nil {
-		fmt.Printf("Warning: Failed to delete Middleware: %v\n", err)
+		logf(ctx, "Warning: Failed to delete Middleware: %v\n", err)
 	}
 
 	// Delete pgAdmin service
 	if err := clientset.CoreV1().Services(namespace).Delete(ctx, dbName+"-pgadmin", metav1.DeleteOptions{}); err != nil {
-		fmt.Printf("Warning: Failed to delete pgAdmin service: %v\n", err)
+		logf(ctx, "Warning: Failed to delete pgAdmin service: %v\n", err)
 	} else {
-		fmt.Printf("✅ Deleted pgAdmin service\n")
+		logf(ctx, "✅ Deleted pgAdmin service\n")
 	}
 
 	// Delete pgAdmin deployment
-	if err := clientset.AppsV1().Deployments(namespace).Delete(ctx, dbName+"-pgadmin", metav1.DeleteOptions{}); err != nil {
-		fmt.Printf("Warning: Failed to delete pgAdmin deployment: %v\n", err)
+	if err := deleteDeploymentAndWait(ctx, namespace, dbName+"-pgadmin", gracePeriod); err != nil {
+		logf(ctx, "Warning: Failed to delete pgAdmin deployment: %v\n", err)
 	} else {
-		fmt.Printf("✅ Deleted pgAdmin deployment\n")
+		logf(ctx, "✅ Deleted pgAdmin deployment\n")
 	}
 
 	// Delete PostgreSQL service
 	if err := clientset.CoreV1().Services(namespace).Delete(ctx, dbName, metav1.DeleteOptions{}); err != nil {
-		fmt.Printf("Warning: Failed to delete PostgreSQL service: %v\n", err)
+		logf(ctx, "Warning: Failed to delete PostgreSQL service: %v\n", err)
 	} else {
-		fmt.Printf("✅ Deleted PostgreSQL service\n")
+		logf(ctx, "✅ Deleted PostgreSQL service\n")
 	}
 
 	// Delete PostgreSQL deployment
-	if err := clientset.AppsV1().Deployments(namespace).Delete(ctx, dbName, metav1.DeleteOptions{}); err != nil {
+	if err := deleteDeploymentAndWait(ctx, namespace, dbName, gracePeriod); err != nil {
 		return fmt.Errorf("failed to delete PostgreSQL deployment: %w", err)
 	}
-	fmt.Printf("✅ Deleted PostgreSQL deployment\n")
+	logf(ctx, "✅ Deleted PostgreSQL deployment\n")
+
+	// Delete init SQL ConfigMap (only present when the database was seeded)
+	deleteInitSQLConfigMap(ctx, dbName, namespace)
+	deleteReadOnlySecret(ctx, dbName, namespace)
+	deletePodDisruptionBudget(ctx, dbName, namespace)
+	deleteBackupCronJob(ctx, dbName, namespace)
+	deleteDataPVC(ctx, dbName, namespace)
 
 	return nil
 }
@@ -876,7 +1137,7 @@ func deleteTraefikIngressRoute(ctx context.Context, dbName, namespace, adminType
 		return fmt.Errorf("dynamic client not available")
 	}
 
-	ingressName := fmt.Sprintf("%s-%s-ingress", dbName, adminType)
+	ingressName := traefikIngressRouteName(namespace, dbName, adminType)
 
 	gvr := schema.GroupVersionResource{
 		Group:    "traefik.io",
@@ -884,73 +1145,198 @@ func deleteTraefikIngressRoute(ctx context.Context, dbName, namespace, adminType
 		Resource: "ingressroutes",
 	}
 
-	err := dynamicClient.Resource(gvr).Namespace(namespace).Delete(ctx, ingressName, metav1.DeleteOptions{})
+	err := dynamicClient.Resource(gvr).Namespace(traefikNamespace(namespace)).Delete(ctx, ingressName, metav1.DeleteOptions{})
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("✅ Deleted Traefik IngressRoute: %s\n", ingressName)
+	logf(ctx, "✅ Deleted Traefik IngressRoute: %s\n", ingressName)
 	return nil
 }
 
-// deleteTraefikMiddleware removes a Traefik Middleware
+// deleteTraefikMiddleware removes the Traefik middlewares created for an admin
+// dashboard: the headers middleware, plus the replacePathRegex one for phpMyAdmin.
+// A failure on one middleware does not stop the others; all failures are returned.
 func deleteTraefikMiddleware(ctx context.Context, dbName, namespace, adminType string) error {
 	if dynamicClient == nil {
 		return fmt.Errorf("dynamic client not available")
 	}
 
-	middlewareName := fmt.Sprintf("%s-%s-stripprefix", dbName, adminType)
-
 	gvr := schema.GroupVersionResource{
 		Group:    "traefik.io",
 		Version:  "v1alpha1",
 		Resource: "middlewares",
 	}
 
-	err := dynamicClient.Resource(gvr).Namespace(namespace).Delete(ctx, middlewareName, metav1.DeleteOptions{})
-	if err != nil {
-		return err
+	middlewareNames := []string{traefikHeadersMiddlewareName(namespace, dbName, adminType)}
+	if adminType == "phpmyadmin" {
+		middlewareNames = append(middlewareNames, traefikReplacePathMiddlewareName(namespace, dbName, adminType))
+	}
+
+	var errs []error
+	for _, middlewareName := range middlewareNames {
+		err := dynamicClient.Resource(gvr).Namespace(traefikNamespace(namespace)).Delete(ctx, middlewareName, metav1.DeleteOptions{})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete middleware %s: %w", middlewareName, err))
+			continue
+		}
+		logf(ctx, "✅ Deleted Traefik Middleware: %s\n", middlewareName)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// deleteNamespaceTraefikObjects removes the IngressRoutes and Middlewares kept
+// in a central TRAEFIK_NAMESPACE for a namespace being deleted; objects in the
+// namespace itself go away with it
+func deleteNamespaceTraefikObjects(ctx context.Context, namespace string) error {
+	routeNamespace := traefikNamespace(namespace)
+	if routeNamespace == namespace {
+		return nil
+	}
+	if dynamicClient == nil {
+		return fmt.Errorf("dynamic client not available")
+	}
+
+	selector := metav1.ListOptions{LabelSelector: shared.TraefikNamespaceLabel + "=" + namespace}
+	for _, gvr := range []schema.GroupVersionResource{ingressRoutesGVR, middlewaresGVR} {
+		if err := dynamicClient.Resource(gvr).Namespace(routeNamespace).DeleteCollection(ctx, metav1.DeleteOptions{}, selector); err != nil {
+			return fmt.Errorf("failed to delete %s for namespace %s: %w", gvr.Resource, namespace, err)
+		}
 	}
 
-	fmt.Printf("✅ Deleted Traefik Middleware: %s\n", middlewareName)
+	logf(ctx, "✅ Deleted Traefik objects for namespace %s from %s\n", namespace, routeNamespace)
 	return nil
 }
 
+// Traefik object names, shared by the create and delete paths so they always match
+func traefikIngressRouteName(namespace, dbName, adminType string) string {
+	return traefikObjectName(namespace, fmt.Sprintf("%s-%s-ingress", dbName, adminType))
+}
+
+func traefikHeadersMiddlewareName(namespace, dbName, adminType string) string {
+	return traefikObjectName(namespace, fmt.Sprintf("%s-%s-headers", dbName, adminType))
+}
+
+func traefikReplacePathMiddlewareName(namespace, dbName, adminType string) string {
+	return traefikObjectName(namespace, fmt.Sprintf("%s-%s-replacepath", dbName, adminType))
+}
+
+// withCustomEnv appends user-supplied environment variables to the managed ones,
+// sorted by name so the generated pod spec is stable
+func withCustomEnv(managed []corev1.EnvVar, custom map[string]string) []corev1.EnvVar {
+	names := make([]string, 0, len(custom))
+	for name := range custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		managed = append(managed, corev1.EnvVar{Name: name, Value: custom[name]})
+	}
+	return managed
+}
+
+// serverArgs returns the container args that start the database server with
+// extra flags, leaving the image's entrypoint in place. With no flags the
+// image's default command is used.
+func serverArgs(server string, args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	return append([]string{server}, args...)
+}
+
+// adminDeploymentName returns the name of the admin dashboard deployment for a database
+func adminDeploymentName(dbName, dbType string) string {
+	if dbType == DatabaseTypeMySQL {
+		return dbName + "-phpmyadmin"
+	}
+	return dbName + "-pgadmin"
+}
+
+// revisionAnnotation is where the deployment controller records a deployment's rollout revision
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// restartRevisionTimeout bounds how long a restart waits for the deployment
+// controller to observe the new pod template and bump the revision
+const restartRevisionTimeout = 5 * time.Second
+
+// restartDeployment triggers a rolling restart by stamping the pod template with
+// the kubectl restartedAt annotation, returning the deployment's rollout
+// revision once the controller has observed the change (or the last known one
+// if it has not within restartRevisionTimeout)
+func restartDeployment(ctx context.Context, namespace, deploymentName string) (string, error) {
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":"%s"}}}}}`,
+		time.Now().Format(time.RFC3339))
+
+	deployment, err := clientset.AppsV1().Deployments(namespace).Patch(ctx, deploymentName, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	if errors.IsNotFound(err) {
+		return "", apperrors.New(apperrors.ErrNotFound, "deployment '%s' not found in namespace '%s'", deploymentName, namespace)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to restart deployment %s: %w", deploymentName, err)
+	}
+
+	generation := deployment.Generation
+	revision := deployment.Annotations[revisionAnnotation]
+	_ = wait.PollUntilContextTimeout(ctx, 250*time.Millisecond, restartRevisionTimeout, true, func(ctx context.Context) (bool, error) {
+		current, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		if current.Status.ObservedGeneration < generation {
+			return false, nil
+		}
+		revision = current.Annotations[revisionAnnotation]
+		return true, nil
+	})
+
+	logf(ctx, "🔄 Restarted deployment '%s' in namespace '%s' (revision %s)\n", deploymentName, namespace, revision)
+	return revision, nil
+}
+
 // listDatabasesInNamespace returns all databases in a namespace
 // listDatabasesInNamespace returns all databases in a namespace with STABLE URLs
-func listDatabasesInNamespace(namespace string) ([]map[string]interface{}, error) {
+func listDatabasesInNamespace(namespace string, filter labels.Set) ([]map[string]interface{}, error) {
 	ctx := context.Background()
 
-	// Get all deployments with db-saas labels
-	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: "app.kubernetes.io/managed-by=db-saas,app.kubernetes.io/component=database",
-	})
+	// Get all deployments with db-saas labels (narrowed by the optional filter)
+	deployments, err := listDatabaseDeployments(ctx, namespace, filter)
 	if err != nil {
 		return nil, err
 	}
 
+	phases, err := listDatabasePhases(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database phases: %w", err)
+	}
+
 	var databases []map[string]interface{}
 
-	for _, deployment := range deployments.Items {
-		dbType := deployment.Labels["db-saas/type"]
+	for _, deployment := range deployments {
+		dbType := labeledDatabaseType(deployment.Labels)
 		userID := deployment.Labels["db-saas/user-id"]
 
-		// Get service to check if it's running
-		_, err := clientset.CoreV1().Services(namespace).Get(ctx, deployment.Name, metav1.GetOptions{})
-		status := "running"
-		if err != nil {
+		// The phase comes from the pods; a missing service is an error regardless
+		status, ok := phases[deployment.Name]
+		if !ok {
+			status = DatabaseStatusProvisioning
+		}
+		if _, err := getService(ctx, namespace, deployment.Name); err != nil {
 			status = "error"
 		}
 
-		// STABLE URL PATTERN: /{namespace}/admin/{adminType}/{dbname}
-		adminURL := ""
+		dashboardURL := ""
 		adminType := ""
-		if dbType == "mysql" {
-			adminURL = fmt.Sprintf("http://10.9.21.201/%s/admin/phpmyadmin/%s", namespace, deployment.Name)
-			adminType = "phpMyAdmin"
-		} else if dbType == "postgresql" {
-			adminURL = fmt.Sprintf("http://10.9.21.201/%s/admin/pgadmin/%s", namespace, deployment.Name)
-			adminType = "pgAdmin"
+		if adminDashboardsEnabled() {
+			if dbType == DatabaseTypeMySQL {
+				dashboardURL = adminURL(namespace, deployment.Name, dbType)
+				adminType = "phpMyAdmin"
+			} else if dbType == DatabaseTypePostgreSQL {
+				dashboardURL = adminURL(namespace, deployment.Name, dbType)
+				adminType = "pgAdmin"
+			}
 		}
 
 		database := map[string]interface{}{
@@ -959,9 +1345,13 @@ func listDatabasesInNamespace(namespace string) ([]map[string]interface{}, error
 			"status":    status,
 			"namespace": namespace,
 			"userId":    userID,
-			"adminUrl":  adminURL,
+			"adminUrl":  dashboardURL,
 			"adminType": adminType,
 			"createdAt": deployment.CreationTimestamp.Time,
+			"external":  false,
+		}
+		if environment := deployment.Labels[environmentLabel]; environment != "" {
+			database["environment"] = environment
 		}
 
 		databases = append(databases, database)
@@ -970,6 +1360,87 @@ func listDatabasesInNamespace(namespace string) ([]map[string]interface{}, error
 	return databases, nil
 }
 
+// checkDatabaseLimit returns an error when adding more databases would
+// take the user past MAX_DATABASES_PER_USER. Existing databases are counted
+// by their user-id label across all namespaces.
+func checkDatabaseLimit(ctx context.Context, userID, adding int) error {
+	limit := appConfig.MaxDatabasesPerUser
+	if limit <= 0 {
+		return nil
+	}
+
+	deployments, err := listDatabaseDeployments(ctx, metav1.NamespaceAll, labels.Set{
+		"db-saas/user-id": strconv.Itoa(userID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count existing databases: %w", err)
+	}
+
+	count := 0
+	owned := map[string]bool{}
+	for _, deployment := range deployments {
+		ours, checked := owned[deployment.Namespace]
+		if !checked {
+			if ours, err = ownsNamespace(ctx, deployment.Namespace); err != nil {
+				return fmt.Errorf("failed to count existing databases: %w", err)
+			}
+			owned[deployment.Namespace] = ours
+		}
+		if ours {
+			count++
+		}
+	}
+
+	if count+adding > limit {
+		return apperrors.New(apperrors.ErrLimitExceeded, "database limit reached: you have %d of %d allowed databases", count, limit)
+	}
+	return nil
+}
+
+// listManagedNamespaces returns all db-saas namespaces with their database counts
+func listManagedNamespaces(ctx context.Context) ([]map[string]interface{}, error) {
+	var namespaces []*corev1.Namespace
+	if resourceCache != nil {
+		cached, err := resourceCache.namespaces.List(managedNamespaceSelector())
+		if err != nil {
+			return nil, err
+		}
+		namespaces = cached
+	} else {
+		list, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+			LabelSelector: managedNamespaceSelector().String(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			namespaces = append(namespaces, &list.Items[i])
+		}
+	}
+
+	result := make([]map[string]interface{}, 0, len(namespaces))
+	for _, ns := range namespaces {
+		deployments, err := listDatabaseDeployments(ctx, ns.Name, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list databases in namespace %s: %w", ns.Name, err)
+		}
+
+		status := "Active"
+		if ns.Status.Phase != corev1.NamespaceActive && ns.Status.Phase != "" {
+			status = string(ns.Status.Phase)
+		}
+
+		result = append(result, map[string]interface{}{
+			"name":          ns.Name,
+			"createdAt":     ns.CreationTimestamp.Time,
+			"databaseCount": len(deployments),
+			"status":        status,
+		})
+	}
+
+	return result, nil
+}
+
 // Helper function to parse resource quantities
 func mustParseQuantity(str string) resource.Quantity {
 	q, err := resource.ParseQuantity(str)
@@ -979,15 +1450,38 @@ func mustParseQuantity(str string) resource.Quantity {
 	return q
 }
 
-// deployMySQL deploys MySQL database with phpMyAdmin and Traefik routing
-func deployMySQL(ctx context.Context, clientset *kubernetes.Clientset, dbRequest DatabaseRequest, namespace string) error {
+// deployMySQL deploys MySQL database with phpMyAdmin and Traefik routing;
+// the dashboard is skipped when ENABLE_ADMIN_DASHBOARDS is off
+func deployMySQL(ctx context.Context, clientset kubernetes.Interface, dbRequest DatabaseRequest, namespace string) error {
+	// Create init SQL ConfigMap before the deployment that mounts it
+	if dbRequest.InitSQL != "" {
+		if err := createInitSQLConfigMap(ctx, clientset, dbRequest, namespace); err != nil {
+			return err
+		}
+	}
+	if dbRequest.ReadOnlyUser {
+		if err := createReadOnlySecret(ctx, clientset, dbRequest, namespace); err != nil {
+			return err
+		}
+	}
+	if err := createDataPVC(ctx, clientset, dbRequest, namespace); err != nil {
+		return err
+	}
+
 	// Create MySQL deployment
 	mysqlDeployment := createMySQLDeployment(dbRequest, namespace)
 	_, err := clientset.AppsV1().Deployments(namespace).Create(ctx, mysqlDeployment, metav1.CreateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to create MySQL deployment: %w", err)
 	}
-	fmt.Printf("✅ Created MySQL deployment: %s\n", dbRequest.Name)
+	logf(ctx, "✅ Created MySQL deployment: %s\n", dbRequest.Name)
+
+	if err := createPodDisruptionBudget(ctx, clientset, dbRequest, namespace); err != nil {
+		return err
+	}
+	if err := createBackupCronJob(ctx, clientset, dbRequest, mysqlDeployment); err != nil {
+		return err
+	}
 
 	// Create MySQL service
 	mysqlService := createMySQLService(dbRequest)
@@ -995,7 +1489,13 @@ func deployMySQL(ctx context.Context, clientset *kubernetes.Clientset, dbRequest
 	if err != nil {
 		return fmt.Errorf("failed to create MySQL service: %w", err)
 	}
-	fmt.Printf("✅ Created MySQL service: %s\n", dbRequest.Name)
+	logf(ctx, "✅ Created MySQL service: %s\n", dbRequest.Name)
+
+	if !adminDashboardsEnabled() {
+		return nil
+	}
+
+	waitForDatabaseReady(ctx, clientset, namespace, dbRequest.Name)
 
 	// Create phpMyAdmin deployment
 	phpMyAdminDeployment := createPhpMyAdminDeployment(dbRequest, namespace)
@@ -1003,7 +1503,7 @@ func deployMySQL(ctx context.Context, clientset *kubernetes.Clientset, dbRequest
 	if err != nil {
 		return fmt.Errorf("failed to create phpMyAdmin deployment: %w", err)
 	}
-	fmt.Printf("✅ Created phpMyAdmin deployment: %s-phpmyadmin\n", dbRequest.Name)
+	logf(ctx, "✅ Created phpMyAdmin deployment: %s-phpmyadmin\n", dbRequest.Name)
 
 	// Create phpMyAdmin service (ClusterIP)
 	phpMyAdminService := createPhpMyAdminService(dbRequest)
@@ -1011,19 +1511,19 @@ func deployMySQL(ctx context.Context, clientset *kubernetes.Clientset, dbRequest
 	if err != nil {
 		return fmt.Errorf("failed to create phpMyAdmin service: %w", err)
 	}
-	fmt.Printf("✅ Created phpMyAdmin ClusterIP service: %s-phpmyadmin\n", dbRequest.Name)
+	logf(ctx, "✅ Created phpMyAdmin ClusterIP service: %s-phpmyadmin\n", dbRequest.Name)
 
 	// Create Traefik Middleware for path stripping
 	if err := createTraefikMiddleware(ctx, dbRequest, namespace, "phpmyadmin"); err != nil {
 		return fmt.Errorf("failed to create Traefik middleware: %w", err)
 	}
-	fmt.Printf("✅ Created Traefik middleware for phpMyAdmin\n")
+	logf(ctx, "✅ Created Traefik middleware for phpMyAdmin\n")
 
 	// Create Traefik IngressRoute (port 80 since it's ClusterIP)
 	if err := createTraefikIngressRoute(ctx, dbRequest, namespace, "phpmyadmin", 80); err != nil {
 		return fmt.Errorf("failed to create Traefik IngressRoute: %w", err)
 	}
-	fmt.Printf("✅ Created Traefik IngressRoute for phpMyAdmin\n")
+	logf(ctx, "✅ Created Traefik IngressRoute for phpMyAdmin\n")
 
 	return nil
 }
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BouchamiAhmed/TBD/config"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// useTestConfig installs the default configuration for the duration of a test
+func useTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	saved := appConfig
+	appConfig = config.Load()
+	t.Cleanup(func() { appConfig = saved })
+	return appConfig
+}
+
+func TestServicesTargetNamedContainerPorts(t *testing.T) {
+	useTestConfig(t)
+
+	postgres := DatabaseRequest{Name: "orders", Type: DatabaseTypePostgreSQL, Username: "app", Password: "secret-password", UserID: 1, UserName: "alice"}
+	mysql := DatabaseRequest{Name: "shop", Type: DatabaseTypeMySQL, Username: "app", Password: "secret-password", UserID: 1, UserName: "alice"}
+
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		service    *corev1.Service
+	}{
+		{"postgresql", createPostgreSQLDeployment(postgres, "ns"), createPostgreSQLService(postgres)},
+		{"pgadmin", createPgAdminDeployment(postgres, "ns"), createPgAdminService(postgres)},
+		{"mysql", createMySQLDeployment(mysql, "ns"), createMySQLService(mysql)},
+		{"phpmyadmin", createPhpMyAdminDeployment(mysql, "ns"), createPhpMyAdminService(mysql)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			containerPorts := map[string]int32{}
+			for _, port := range tt.deployment.Spec.Template.Spec.Containers[0].Ports {
+				if port.Name == "" {
+					t.Errorf("container port %d has no name", port.ContainerPort)
+				}
+				containerPorts[port.Name] = port.ContainerPort
+			}
+
+			if len(tt.service.Spec.Ports) == 0 {
+				t.Fatal("service has no ports")
+			}
+			for _, port := range tt.service.Spec.Ports {
+				if port.Name == "" {
+					t.Errorf("service port %d has no name", port.Port)
+				}
+				if port.TargetPort.Type != intstr.String {
+					t.Errorf("service port %q targets %s, want the container port name", port.Name, port.TargetPort.String())
+					continue
+				}
+				if _, ok := containerPorts[port.TargetPort.StrVal]; !ok {
+					t.Errorf("service port %q targets %q, which no container port is named", port.Name, port.TargetPort.StrVal)
+				}
+			}
+		})
+	}
+}
+
+// useFakeDynamicClient installs a fake dynamic client holding objects for the
+// duration of a test and returns it
+func useFakeDynamicClient(t *testing.T, objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	t.Helper()
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		ingressRoutesGVR: "IngressRouteList",
+		middlewaresGVR:   "MiddlewareList",
+	}, objects...)
+	saved := dynamicClient
+	dynamicClient = client
+	t.Cleanup(func() { dynamicClient = saved })
+	return client
+}
+
+// traefikMiddleware returns a bare Traefik Middleware object
+func traefikMiddleware(namespace, name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "traefik.io/v1alpha1",
+		"kind":       "Middleware",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+	}}
+}
+
+func TestDeleteTraefikMiddlewareContinuesPastFailures(t *testing.T) {
+	useTestConfig(t)
+
+	// The headers middleware is already gone; the replacePath one must still be deleted
+	replacePath := traefikReplacePathMiddlewareName("ns", "shop", "phpmyadmin")
+	client := useFakeDynamicClient(t, traefikMiddleware(traefikNamespace("ns"), replacePath))
+
+	err := deleteTraefikMiddleware(context.Background(), "shop", "ns", "phpmyadmin")
+	if err == nil {
+		t.Fatal("expected an error for the missing headers middleware")
+	}
+
+	_, getErr := client.Resource(middlewaresGVR).Namespace(traefikNamespace("ns")).Get(context.Background(), replacePath, metav1.GetOptions{})
+	if !errors.IsNotFound(getErr) {
+		t.Errorf("replacePath middleware %s was not deleted after the first failure (get error: %v)", replacePath, getErr)
+	}
+}
+
+func TestDeleteTraefikMiddlewareDeletesAll(t *testing.T) {
+	useTestConfig(t)
+
+	headers := traefikHeadersMiddlewareName("ns", "shop", "phpmyadmin")
+	replacePath := traefikReplacePathMiddlewareName("ns", "shop", "phpmyadmin")
+	client := useFakeDynamicClient(t,
+		traefikMiddleware(traefikNamespace("ns"), headers),
+		traefikMiddleware(traefikNamespace("ns"), replacePath),
+	)
+
+	if err := deleteTraefikMiddleware(context.Background(), "shop", "ns", "phpmyadmin"); err != nil {
+		t.Fatalf("deleteTraefikMiddleware returned error: %v", err)
+	}
+
+	list, err := client.Resource(middlewaresGVR).Namespace(traefikNamespace("ns")).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("listing middlewares: %v", err)
+	}
+	if len(list.Items) != 0 {
+		t.Errorf("%d middlewares left after delete, want 0", len(list.Items))
+	}
+}
+
+func TestAdminDashboardsCanBeDisabled(t *testing.T) {
+	cfg := useTestConfig(t)
+	cfg.WaitForDB = false
+	cfg.Features.AdminDashboards = false
+	client := useFakeClientset(t, managedNamespace("7alice"))
+	useFakeDynamicClient(t)
+
+	ctx := context.Background()
+	dbRequest := DatabaseRequest{Name: "orders", Type: DatabaseTypePostgreSQL, Username: "app", Password: "secret-password", UserID: 7, UserName: "alice"}
+	if err := deployDatabase(ctx, client, dbRequest, "7alice"); err != nil {
+		t.Fatalf("deployDatabase returned error: %v", err)
+	}
+
+	if _, err := client.AppsV1().Deployments("7alice").Get(ctx, "orders", metav1.GetOptions{}); err != nil {
+		t.Fatalf("getting database deployment: %v", err)
+	}
+	if _, err := client.AppsV1().Deployments("7alice").Get(ctx, "orders-pgadmin", metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("pgAdmin deployment lookup returned %v, want not found", err)
+	}
+
+	response := newDatabaseResponse(dbRequest, "7alice")
+	if response.AdminURL != "" || response.AdminType != "" {
+		t.Errorf("response advertises dashboard %q (%q) with ENABLE_ADMIN_DASHBOARDS off", response.AdminURL, response.AdminType)
+	}
+}
+
+func TestNetworkPolicyIsolatesUserNamespace(t *testing.T) {
+	cfg := useTestConfig(t)
+	cfg.WaitForDB = false
+	cfg.Features.NetworkPolicy = true
+	cfg.TraefikNamespace = "traefik"
+	client := useFakeClientset(t, managedNamespace("7alice"))
+	useFakeDynamicClient(t)
+
+	ctx := context.Background()
+	for _, name := range []string{"orders", "billing"} {
+		dbRequest := DatabaseRequest{Name: name, Type: DatabaseTypePostgreSQL, Username: "app", Password: "secret-password", UserID: 7, UserName: "alice"}
+		if err := deployDatabase(ctx, client, dbRequest, "7alice"); err != nil {
+			t.Fatalf("deployDatabase(%s) returned error: %v", name, err)
+		}
+	}
+
+	policy, err := client.NetworkingV1().NetworkPolicies("7alice").Get(ctx, namespaceNetworkPolicyName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("getting NetworkPolicy: %v", err)
+	}
+	from := policy.Spec.Ingress[0].From
+	if len(from) != 2 {
+		t.Fatalf("NetworkPolicy allows %d peers, want the namespace itself and Traefik's", len(from))
+	}
+	if got := from[1].NamespaceSelector.MatchLabels["kubernetes.io/metadata.name"]; got != "traefik" {
+		t.Errorf("NetworkPolicy admits namespace %q, want %q", got, "traefik")
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec is the hand-written OpenAPI 3 description of the API. Keep it in
+// step with the routes registered in main.go, auth_handlers.go, podreader.go
+// and deployer.go.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// handleOpenAPISpec serves the OpenAPI description as-is, outside the usual
+// response envelope so tools like Swagger UI can load it directly
+func handleOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
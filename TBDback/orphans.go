@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/BouchamiAhmed/TBD/shared"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// adminTypes are the admin dashboards that get Traefik routing
+var adminTypes = []string{"pgadmin", "phpmyadmin"}
+
+// managedTraefikSelector selects the Traefik objects db-saas created
+const managedTraefikSelector = "app.kubernetes.io/managed-by=db-saas"
+
+// middlewareNameSuffixes are the suffixes of the middlewares created per admin
+// dashboard, see traefikHeadersMiddlewareName and traefikReplacePathMiddlewareName
+var middlewareNameSuffixes = []string{"-headers", "-replacepath"}
+
+// OrphanCleanupResult reports what a Traefik orphan cleanup removed
+type OrphanCleanupResult struct {
+	Checked int      `json:"checked"`
+	Removed int      `json:"removed"`
+	Objects []string `json:"objects"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// cleanupOrphanedTraefikObjects deletes the db-saas IngressRoutes and
+// Middlewares whose admin dashboard service no longer exists. Older releases
+// deleted middlewares under different names than they were created with, so
+// clusters may hold many of these. Only objects labeled as db-saas's are
+// considered, except that unlabeled middlewares in user namespaces, which
+// predate the labels, are matched by name.
+func cleanupOrphanedTraefikObjects(ctx context.Context) (*OrphanCleanupResult, error) {
+	namespaceList, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+		LabelSelector: managedNamespaceSelector().String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	namespaces := make([]string, 0, len(namespaceList.Items)+1)
+	for _, ns := range namespaceList.Items {
+		namespaces = append(namespaces, ns.Name)
+	}
+	if appConfig.TraefikNamespace != "" {
+		namespaces = append(namespaces, appConfig.TraefikNamespace)
+	}
+
+	result := &OrphanCleanupResult{Objects: []string{}}
+	services := serviceExistenceCache{}
+
+	for _, namespace := range namespaces {
+		routes, err := dynamicClient.Resource(ingressRoutesGVR).Namespace(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: managedTraefikSelector,
+		})
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("list ingressroutes in %s: %v", namespace, err))
+		} else {
+			for i := range routes.Items {
+				route := &routes.Items[i]
+				result.Checked++
+				orphaned, err := services.anyMissing(ctx, ingressRouteServices(route))
+				if err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("check ingressroute %s/%s: %v", namespace, route.GetName(), err))
+					continue
+				}
+				if orphaned {
+					deleteOrphan(ctx, result, "ingressroute", route)
+				}
+			}
+		}
+
+		// TRAEFIK_NAMESPACE is shared with objects db-saas did not create
+		middlewareOptions := metav1.ListOptions{}
+		if namespace == appConfig.TraefikNamespace {
+			middlewareOptions.LabelSelector = managedTraefikSelector
+		}
+		middlewares, err := dynamicClient.Resource(middlewaresGVR).Namespace(namespace).List(ctx, middlewareOptions)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("list middlewares in %s: %v", namespace, err))
+			continue
+		}
+		for i := range middlewares.Items {
+			middleware := &middlewares.Items[i]
+			if managedBy, labeled := middleware.GetLabels()["app.kubernetes.io/managed-by"]; labeled && managedBy != "db-saas" {
+				continue
+			}
+			service, ok := middlewareService(middleware)
+			if !ok {
+				continue
+			}
+			result.Checked++
+			orphaned, err := services.anyMissing(ctx, []serviceRef{service})
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("check middleware %s/%s: %v", namespace, middleware.GetName(), err))
+				continue
+			}
+			if orphaned {
+				deleteOrphan(ctx, result, "middleware", middleware)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// deleteOrphan deletes one orphaned Traefik object and records the outcome
+func deleteOrphan(ctx context.Context, result *OrphanCleanupResult, kind string, obj *unstructured.Unstructured) {
+	gvr := ingressRoutesGVR
+	if kind == "middleware" {
+		gvr = middlewaresGVR
+	}
+
+	ref := fmt.Sprintf("%s %s/%s", kind, obj.GetNamespace(), obj.GetName())
+	err := dynamicClient.Resource(gvr).Namespace(obj.GetNamespace()).Delete(ctx, obj.GetName(), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		result.Errors = append(result.Errors, fmt.Sprintf("delete %s: %v", ref, err))
+		return
+	}
+
+	logf(ctx, "🧹 Deleted orphaned %s\n", ref)
+	result.Removed++
+	result.Objects = append(result.Objects, ref)
+}
+
+// serviceRef identifies a Service referenced by a Traefik object
+type serviceRef struct {
+	namespace string
+	name      string
+}
+
+// ingressRouteServices returns the services an IngressRoute routes to
+func ingressRouteServices(route *unstructured.Unstructured) []serviceRef {
+	var refs []serviceRef
+	routes, _, _ := unstructured.NestedSlice(route.Object, "spec", "routes")
+	for _, r := range routes {
+		routeMap, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		services, _, _ := unstructured.NestedSlice(routeMap, "services")
+		for _, s := range services {
+			serviceMap, ok := s.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(serviceMap, "name")
+			namespace, _, _ := unstructured.NestedString(serviceMap, "namespace")
+			if namespace == "" {
+				namespace = route.GetNamespace()
+			}
+			refs = append(refs, serviceRef{namespace: namespace, name: name})
+		}
+	}
+	return refs
+}
+
+// middlewareService returns the admin dashboard service a db-saas middleware
+// belongs to, derived from its name since middlewares carry no service
+// reference. Middlewares not named like ours are skipped.
+func middlewareService(middleware *unstructured.Unstructured) (serviceRef, bool) {
+	name := middleware.GetName()
+	namespace := middleware.GetNamespace()
+
+	// Middlewares kept in TRAEFIK_NAMESPACE are named after their database's namespace
+	if dbNamespace := middleware.GetLabels()[shared.TraefikNamespaceLabel]; dbNamespace != "" && dbNamespace != namespace {
+		name = strings.TrimPrefix(name, dbNamespace+"-")
+		namespace = dbNamespace
+	}
+
+	for _, suffix := range middlewareNameSuffixes {
+		serviceName, ok := strings.CutSuffix(name, suffix)
+		if !ok {
+			continue
+		}
+		for _, adminType := range adminTypes {
+			if strings.HasSuffix(serviceName, "-"+adminType) {
+				return serviceRef{namespace: namespace, name: serviceName}, true
+			}
+		}
+	}
+	return serviceRef{}, false
+}
+
+// serviceExistenceCache remembers which services exist during one cleanup
+type serviceExistenceCache map[serviceRef]bool
+
+// anyMissing reports whether any of the services is missing (or none are given)
+func (c serviceExistenceCache) anyMissing(ctx context.Context, refs []serviceRef) (bool, error) {
+	if len(refs) == 0 {
+		return true, nil
+	}
+	for _, ref := range refs {
+		exists, cached := c[ref]
+		if !cached {
+			_, err := clientset.CoreV1().Services(ref.namespace).Get(ctx, ref.name, metav1.GetOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return false, err
+			}
+			exists = err == nil
+			c[ref] = exists
+		}
+		if !exists {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BouchamiAhmed/TBD/shared"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// labeledTraefikMiddleware returns a Traefik Middleware carrying labels
+func labeledTraefikMiddleware(namespace, name string, objectLabels map[string]string) *unstructured.Unstructured {
+	middleware := traefikMiddleware(namespace, name)
+	middleware.SetLabels(objectLabels)
+	return middleware
+}
+
+func TestCleanupOrphanedTraefikObjectsOnlyTouchesOurMiddlewares(t *testing.T) {
+	cfg := useTestConfig(t)
+	cfg.TraefikNamespace = "traefik"
+	useFakeClientset(t, managedNamespace("7alice"))
+
+	ours := traefikHeadersMiddlewareName("7alice", "shop", "phpmyadmin")
+	useFakeDynamicClient(t,
+		labeledTraefikMiddleware("traefik", ours, map[string]string{
+			"app.kubernetes.io/managed-by": "db-saas",
+			shared.TraefikNamespaceLabel:   "7alice",
+		}),
+		traefikMiddleware("traefik", "other-pgadmin-headers"),
+		traefikMiddleware("7alice", "legacy-pgadmin-headers"),
+		labeledTraefikMiddleware("7alice", "chart-pgadmin-headers", map[string]string{"app.kubernetes.io/managed-by": "helm"}),
+	)
+
+	result, err := cleanupOrphanedTraefikObjects(context.Background())
+	if err != nil {
+		t.Fatalf("cleanupOrphanedTraefikObjects returned error: %v", err)
+	}
+	if len(result.Errors) != 0 {
+		t.Fatalf("cleanup reported errors: %v", result.Errors)
+	}
+
+	removed := map[string]bool{}
+	for _, ref := range result.Objects {
+		removed[ref] = true
+	}
+	want := []string{"middleware traefik/" + ours, "middleware 7alice/legacy-pgadmin-headers"}
+	for _, ref := range want {
+		if !removed[ref] {
+			t.Errorf("%s was not removed", ref)
+		}
+	}
+	if len(result.Objects) != len(want) {
+		t.Errorf("removed %v, want only %v", result.Objects, want)
+	}
+}
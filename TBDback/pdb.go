@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+// podDisruptionBudgetName returns the name of a database's PodDisruptionBudget
+func podDisruptionBudgetName(dbName string) string {
+	return dbName + "-pdb"
+}
+
+// wantsPodDisruptionBudget reports whether a database gets a PodDisruptionBudget:
+// as requested (or set by its environment), otherwise ENABLE_POD_DISRUPTION_BUDGET
+func wantsPodDisruptionBudget(dbRequest DatabaseRequest) bool {
+	if dbRequest.PodDisruptionBudget != nil {
+		return *dbRequest.PodDisruptionBudget
+	}
+	return appConfig.Features.PodDisruptionBudget
+}
+
+// createPodDisruptionBudget protects a database pod from voluntary disruptions
+// (e.g. node drains). With a single replica, minAvailable 1 makes drains wait
+// until the pod is deleted by hand.
+func createPodDisruptionBudget(ctx context.Context, clientset kubernetes.Interface, dbRequest DatabaseRequest, namespace string) error {
+	if !wantsPodDisruptionBudget(dbRequest) {
+		return nil
+	}
+
+	minAvailable := intstr.FromInt32(1)
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podDisruptionBudgetName(dbRequest.Name),
+			Namespace: namespace,
+			Labels: withDatabaseLabels(map[string]string{
+				"app": dbRequest.Name,
+			}, dbRequest),
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": dbRequest.Name},
+			},
+		},
+	}
+
+	if _, err := clientset.PolicyV1().PodDisruptionBudgets(namespace).Create(ctx, pdb, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create PodDisruptionBudget: %w", err)
+	}
+	logf(ctx, "✅ Created PodDisruptionBudget: %s\n", pdb.Name)
+	return nil
+}
+
+// deletePodDisruptionBudget removes a database's PodDisruptionBudget if it exists
+func deletePodDisruptionBudget(ctx context.Context, dbName, namespace string) {
+	err := clientset.PolicyV1().PodDisruptionBudgets(namespace).Delete(ctx, podDisruptionBudgetName(dbName), metav1.DeleteOptions{})
+	switch {
+	case err == nil:
+		logf(ctx, "✅ Deleted PodDisruptionBudget\n")
+	case !errors.IsNotFound(err):
+		logf(ctx, "Warning: Failed to delete PodDisruptionBudget: %v\n", err)
+	}
+}
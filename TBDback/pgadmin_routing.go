@@ -0,0 +1,28 @@
+package main
+
+import "github.com/BouchamiAhmed/TBD/shared"
+
+// pgAdminRouting returns the configured pgAdmin routing; see shared.PgAdminRouting
+// for the strategies. Misconfigurations are reported once at startup.
+func pgAdminRouting() shared.PgAdminRouting {
+	if appConfig == nil {
+		return shared.PgAdminRouting{}
+	}
+	return shared.PgAdminRouting{Mode: appConfig.PgAdminRouting, HostDomain: appConfig.PgAdminHostDomain}
+}
+
+// pgAdminMatchRule returns the Traefik match rule for a pgAdmin IngressRoute
+func pgAdminMatchRule(namespace, dbName string) string {
+	return pgAdminRouting().MatchRule(traefikMatcherVersion(), publicHost(), namespace, dbName)
+}
+
+// pgAdminURL returns the URL users open to reach pgAdmin
+func pgAdminURL(namespace, dbName string) string {
+	return pgAdminRouting().URL(adminURLScheme(), publicHost(), namespace, dbName)
+}
+
+// pgAdminEmail returns the pgAdmin login email: the user's own email when
+// known, otherwise the database username at PGADMIN_EMAIL_DOMAIN
+func pgAdminEmail(dbRequest DatabaseRequest) string {
+	return shared.PgAdminEmail(dbRequest.Email, dbRequest.Username, appConfig.PgAdminEmailDomain)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// phaseAnnotation records on a database deployment the last phase reported
+// for it, so every replica shares one view and a transition is reported once
+const phaseAnnotation = "db-saas/phase"
+
+// phaseDBClient is the database the observed phases are written back to, so
+// persisted records show where a deploy is. It stays nil when no database is
+// configured.
+var phaseDBClient *DBClient
+
+// failedWaitingReasons are container waiting reasons a database will not
+// recover from without intervention
+var failedWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"InvalidImageName":           true,
+	"RunContainerError":          true,
+}
+
+// pullingWaitingReasons are container waiting reasons caused by image pulls
+var pullingWaitingReasons = map[string]bool{
+	"ErrImagePull":     true,
+	"ImagePullBackOff": true,
+}
+
+// podPhase derives the deployment phase of a single database pod from its
+// container states
+func podPhase(pod *corev1.Pod) string {
+	if pod.Status.Phase == corev1.PodFailed {
+		return DatabaseStatusFailed
+	}
+
+	for _, container := range pod.Status.ContainerStatuses {
+		if container.State.Waiting == nil {
+			continue
+		}
+		switch reason := container.State.Waiting.Reason; {
+		case failedWaitingReasons[reason]:
+			return DatabaseStatusFailed
+		case pullingWaitingReasons[reason]:
+			return DatabaseStatusPulling
+		}
+	}
+
+	switch {
+	case isPodReady(pod):
+		return DatabaseStatusRunning
+	case pod.Status.Phase == corev1.PodRunning:
+		return DatabaseStatusStarting
+	default:
+		return DatabaseStatusProvisioning
+	}
+}
+
+// phasePriority orders phases when a database has several pods (e.g. during
+// a rollout): one ready pod means the database is running, otherwise the
+// phase most worth surfacing wins
+var phasePriority = map[string]int{
+	DatabaseStatusRunning:      4,
+	DatabaseStatusFailed:       3,
+	DatabaseStatusPulling:      2,
+	DatabaseStatusStarting:     1,
+	DatabaseStatusProvisioning: 0,
+}
+
+// databasePhase derives a database's phase from its pods: provisioning
+// (objects created, no pod started), pulling, starting (running, not ready),
+// running or failed. Terminating pods are ignored.
+func databasePhase(pods []*corev1.Pod) string {
+	phase := DatabaseStatusProvisioning
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if p := podPhase(pod); phasePriority[p] > phasePriority[phase] {
+			phase = p
+		}
+	}
+	return phase
+}
+
+// listDatabasePhases returns the phase of each database in a namespace,
+// keyed by database name, from a single pod listing. Databases without pods
+// are absent from the map and are provisioning.
+func listDatabasePhases(ctx context.Context, namespace string) (map[string]string, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: databaseLabel})
+	if err != nil {
+		return nil, err
+	}
+
+	podsByDatabase := map[string][]*corev1.Pod{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		// Admin dashboard and backup pods share the database label but not
+		// the database's app label
+		dbName := pod.Labels[databaseLabel]
+		if pod.Labels["app"] != dbName {
+			continue
+		}
+		podsByDatabase[dbName] = append(podsByDatabase[dbName], pod)
+	}
+
+	phases := make(map[string]string, len(podsByDatabase))
+	for dbName, dbPods := range podsByDatabase {
+		phases[dbName] = databasePhase(dbPods)
+	}
+	return phases, nil
+}
+
+// runPhaseWatcher periodically checks the phase of every managed database,
+// reporting transitions and writing them back to the records
+func runPhaseWatcher(interval time.Duration) {
+	fmt.Printf("🔁 Phase watcher started (interval %s)\n", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := watchPhases(context.Background()); err != nil {
+			fmt.Printf("⚠️ Phase check failed: %v\n", err)
+		}
+	}
+}
+
+// watchPhases runs a single phase check across all namespaces
+func watchPhases(ctx context.Context) error {
+	deployments, err := ownedDatabaseDeployments(ctx)
+	if err != nil {
+		return err
+	}
+
+	phasesByNamespace := map[string]map[string]string{}
+	for _, deployment := range deployments {
+		phases, ok := phasesByNamespace[deployment.Namespace]
+		if !ok {
+			phases, err = listDatabasePhases(ctx, deployment.Namespace)
+			if err != nil {
+				fmt.Printf("⚠️ Failed to get database phases in '%s': %v\n", deployment.Namespace, err)
+				continue
+			}
+			phasesByNamespace[deployment.Namespace] = phases
+		}
+
+		if err := trackPhase(ctx, deployment, phases); err != nil {
+			fmt.Printf("⚠️ Failed to track phase of '%s/%s': %v\n", deployment.Namespace, deployment.Name, err)
+		}
+	}
+	return nil
+}
+
+// trackPhase reports a database's transition from the phase recorded on its
+// deployment and writes the new phase back to the deployment and its record.
+// The deployment update carries its resourceVersion, so when several replicas
+// see the same transition only the one whose update lands reports it. A
+// database first seen running is reported as having become ready; other
+// first observations are only recorded.
+func trackPhase(ctx context.Context, deployment *appsv1.Deployment, phases map[string]string) error {
+	phase, ok := phases[deployment.Name]
+	if !ok {
+		phase = DatabaseStatusProvisioning
+	}
+
+	previous := deployment.Annotations[phaseAnnotation]
+	if previous == phase {
+		return nil
+	}
+
+	updated := deployment.DeepCopy()
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	updated.Annotations[phaseAnnotation] = phase
+	_, err := clientset.AppsV1().Deployments(deployment.Namespace).Update(ctx, updated, metav1.UpdateOptions{})
+	if errors.IsConflict(err) || errors.IsNotFound(err) {
+		// Another replica recorded it first, or the database is gone; the
+		// next pass sees the current state
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record phase: %w", err)
+	}
+
+	persistDatabasePhase(deployment.Name, deployment.Namespace, phase)
+	if previous == "" {
+		if phase != DatabaseStatusRunning {
+			return nil
+		}
+		previous = DatabaseStatusProvisioning
+	}
+
+	fmt.Printf("🔁 '%s/%s' phase %s -> %s\n", deployment.Namespace, deployment.Name, previous, phase)
+	userID, _ := strconv.Atoi(deployment.Labels["db-saas/user-id"])
+	notifyStatusChange(deployment.Name, deployment.Namespace, userID, previous, phase)
+	return nil
+}
+
+// persistDatabasePhase records a database's phase on its record. Records whose
+// delete failed keep that status until the delete is finished.
+func persistDatabasePhase(name, namespace, phase string) {
+	if phaseDBClient == nil {
+		return
+	}
+	if err := phaseDBClient.UpdateDatabaseRecordPhase(name, namespace, phase); err != nil {
+		fmt.Printf("⚠️ Failed to record phase of '%s/%s': %v\n", namespace, name, err)
+	}
+}
+
+// getDatabasePhase returns the phase of one database
+func getDatabasePhase(ctx context.Context, namespace, dbName string) (string, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{"app": dbName, databaseLabel: dbName}).String(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	podPtrs := make([]*corev1.Pod, 0, len(pods.Items))
+	for i := range pods.Items {
+		podPtrs = append(podPtrs, &pods.Items[i])
+	}
+	return databasePhase(podPtrs), nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// useFakeClientset installs a fake clientset holding objects for the
+// duration of a test and returns it
+func useFakeClientset(t *testing.T, objects ...runtime.Object) *fake.Clientset {
+	t.Helper()
+	client := fake.NewSimpleClientset(objects...)
+	saved := clientset
+	clientset = client
+	t.Cleanup(func() { clientset = saved })
+	return client
+}
+
+// useWebhookRecorder points WEBHOOK_URL at a test server and returns the
+// channel its deliveries arrive on
+func useWebhookRecorder(t *testing.T) <-chan StatusChangeEvent {
+	t.Helper()
+	events := make(chan StatusChangeEvent, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event StatusChangeEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("decoding webhook body: %v", err)
+		}
+		events <- event
+	}))
+	t.Cleanup(server.Close)
+	appConfig.WebhookURL = server.URL
+	return events
+}
+
+// expectNoWebhook fails if a delivery arrives shortly after an action
+func expectNoWebhook(t *testing.T, events <-chan StatusChangeEvent) {
+	t.Helper()
+	select {
+	case event := <-events:
+		t.Errorf("unexpected webhook: %+v", event)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// nextWebhook waits for the next delivery
+func nextWebhook(t *testing.T, events <-chan StatusChangeEvent) StatusChangeEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a webhook")
+		return StatusChangeEvent{}
+	}
+}
+
+func managedNamespace(name string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   name,
+		Labels: map[string]string{"app.kubernetes.io/managed-by": "db-saas"},
+	}}
+}
+
+func databaseDeployment(namespace, name string) *appsv1.Deployment {
+	return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels: map[string]string{
+			"app":                          name,
+			"app.kubernetes.io/managed-by": "db-saas",
+			"app.kubernetes.io/component":  "database",
+			databaseLabel:                  name,
+			"db-saas/user-id":              "7",
+		},
+	}}
+}
+
+// databasePod returns a running pod with the given app label, ready or not
+func databasePod(namespace, name, app, dbName string, ready bool) *corev1.Pod {
+	readiness := corev1.ConditionFalse
+	if ready {
+		readiness = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{"app": app, databaseLabel: dbName},
+		},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: readiness}},
+		},
+	}
+}
+
+// recordedPhase returns the phase annotation on a database deployment
+func recordedPhase(t *testing.T, namespace, name string) string {
+	t.Helper()
+	deployment, err := clientset.AppsV1().Deployments(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("getting deployment: %v", err)
+	}
+	return deployment.Annotations[phaseAnnotation]
+}
+
+func TestWatchPhasesReportsFirstSeenReady(t *testing.T) {
+	useTestConfig(t)
+	events := useWebhookRecorder(t)
+	useFakeClientset(t,
+		managedNamespace("7alice"),
+		databaseDeployment("7alice", "orders"),
+		databasePod("7alice", "orders-abc", "orders", "orders", true),
+		// The admin dashboard shares the database label but must not count
+		databasePod("7alice", "orders-pgadmin-abc", "orders-pgadmin", "orders", false),
+	)
+
+	if err := watchPhases(context.Background()); err != nil {
+		t.Fatalf("watchPhases returned error: %v", err)
+	}
+
+	event := nextWebhook(t, events)
+	if event.Name != "orders" || event.Status != DatabaseStatusRunning || event.PreviousStatus != DatabaseStatusProvisioning {
+		t.Errorf("webhook = %+v, want orders provisioning -> running", event)
+	}
+	if got := recordedPhase(t, "7alice", "orders"); got != DatabaseStatusRunning {
+		t.Errorf("recorded phase = %q, want %q", got, DatabaseStatusRunning)
+	}
+
+	// Another pass, as another replica would run, reports nothing new
+	if err := watchPhases(context.Background()); err != nil {
+		t.Fatalf("second watchPhases returned error: %v", err)
+	}
+	expectNoWebhook(t, events)
+}
+
+func TestWatchPhasesReportsTransitions(t *testing.T) {
+	useTestConfig(t)
+	events := useWebhookRecorder(t)
+	client := useFakeClientset(t,
+		managedNamespace("7alice"),
+		databaseDeployment("7alice", "orders"),
+		databasePod("7alice", "orders-abc", "orders", "orders", false),
+	)
+
+	// A database first seen still starting is only recorded
+	if err := watchPhases(context.Background()); err != nil {
+		t.Fatalf("watchPhases returned error: %v", err)
+	}
+	expectNoWebhook(t, events)
+	if got := recordedPhase(t, "7alice", "orders"); got != DatabaseStatusStarting {
+		t.Errorf("recorded phase = %q, want %q", got, DatabaseStatusStarting)
+	}
+
+	ready := databasePod("7alice", "orders-abc", "orders", "orders", true)
+	if _, err := client.CoreV1().Pods("7alice").UpdateStatus(context.Background(), ready, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("updating pod: %v", err)
+	}
+
+	if err := watchPhases(context.Background()); err != nil {
+		t.Fatalf("watchPhases returned error: %v", err)
+	}
+	event := nextWebhook(t, events)
+	if event.Status != DatabaseStatusRunning || event.PreviousStatus != DatabaseStatusStarting {
+		t.Errorf("webhook = %+v, want starting -> running", event)
+	}
+}
+
+func TestTrackPhaseSkipsConflictingUpdate(t *testing.T) {
+	useTestConfig(t)
+	events := useWebhookRecorder(t)
+	deployment := databaseDeployment("7alice", "orders")
+	client := useFakeClientset(t, deployment)
+
+	// Another replica updated the deployment since it was read
+	client.PrependReactor("update", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewConflict(schema.GroupResource{Group: "apps", Resource: "deployments"}, "orders", nil)
+	})
+
+	phases := map[string]string{"orders": DatabaseStatusRunning}
+	if err := trackPhase(context.Background(), deployment, phases); err != nil {
+		t.Fatalf("trackPhase returned error: %v", err)
+	}
+	expectNoWebhook(t, events)
+}
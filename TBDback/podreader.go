@@ -4,12 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/mux"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -20,72 +24,272 @@ type PodInfo struct {
 	Status     string    `json:"status"`
 	IP         string    `json:"ip"`
 	Node       string    `json:"node"`
-	Age        string    `json:"age"`
 	Containers int       `json:"containers"`
 	CreatedAt  time.Time `json:"createdAt"`
 }
 
-// RegisterPodsHandler adds the pod-related routes to the router
-func RegisterPodsHandler(r *mux.Router, clientset *kubernetes.Clientset) {
-	// Endpoint to list all pods in the cluster
-	r.HandleFunc("/api/pods", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("Getting pods list from K3s...")
+// DatabasePodInfo contains the health of a pod belonging to a database
+type DatabasePodInfo struct {
+	Name      string    `json:"name"`
+	Component string    `json:"component"` // "database" or "admin"
+	Status    string    `json:"status"`
+	Ready     bool      `json:"ready"`
+	Restarts  int32     `json:"restarts"`
+	Node      string    `json:"node"`
+	CreatedAt time.Time `json:"createdAt"`
+}
 
-		// Get pods from all namespaces
-		pods, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
-		if err != nil {
-			fmt.Printf("Error getting pods: %v\n", err)
-			http.Error(w, "Failed to get pods: "+err.Error(), http.StatusInternalServerError)
-			return
+// listDatabasePods returns the pods of a database and of its admin dashboard
+func listDatabasePods(ctx context.Context, namespace, dbName string) ([]DatabasePodInfo, error) {
+	adminApps := []string{adminDeploymentName(dbName, DatabaseTypePostgreSQL), adminDeploymentName(dbName, DatabaseTypeMySQL)}
+	requirement, err := labels.NewRequirement("app", selection.In, append([]string{dbName}, adminApps...))
+	if err != nil {
+		return nil, fmt.Errorf("invalid database name: %w", err)
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.NewSelector().Add(*requirement).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]DatabasePodInfo, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		component := "database"
+		if pod.Labels["app"] != dbName {
+			component = "admin"
 		}
 
-		var podInfoList []PodInfo
-		for _, pod := range pods.Items {
-			// Calculate pod age
-			age := calculateAge(pod.CreationTimestamp.Time)
+		var restarts int32
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			restarts += containerStatus.RestartCount
+		}
+
+		result = append(result, DatabasePodInfo{
+			Name:      pod.Name,
+			Component: component,
+			Status:    computePodStatus(&pod),
+			Ready:     isPodReady(&pod),
+			Restarts:  restarts,
+			Node:      pod.Spec.NodeName,
+			CreatedAt: pod.CreationTimestamp.Time,
+		})
+	}
+	return result, nil
+}
+
+// computePodStatus returns the status kubectl shows for a pod: waiting and
+// terminated container reasons (ContainerCreating, CrashLoopBackOff,
+// ImagePullBackOff, Init:...) take precedence over the pod phase
+func computePodStatus(pod *corev1.Pod) string {
+	reason := string(pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		reason = pod.Status.Reason
+	}
+
+	initializing := false
+	for i, container := range pod.Status.InitContainerStatuses {
+		switch {
+		case container.State.Terminated != nil && container.State.Terminated.ExitCode == 0:
+			continue
+		case container.State.Terminated != nil:
+			if container.State.Terminated.Reason != "" {
+				reason = "Init:" + container.State.Terminated.Reason
+			} else if container.State.Terminated.Signal != 0 {
+				reason = fmt.Sprintf("Init:Signal:%d", container.State.Terminated.Signal)
+			} else {
+				reason = fmt.Sprintf("Init:ExitCode:%d", container.State.Terminated.ExitCode)
+			}
+		case container.State.Waiting != nil && container.State.Waiting.Reason != "" && container.State.Waiting.Reason != "PodInitializing":
+			reason = "Init:" + container.State.Waiting.Reason
+		default:
+			reason = fmt.Sprintf("Init:%d/%d", i, len(pod.Spec.InitContainers))
+		}
+		initializing = true
+		break
+	}
 
-			// Determine pod status
-			status := string(pod.Status.Phase)
-			if pod.DeletionTimestamp != nil {
-				status = "Terminating"
+	if !initializing {
+		hasRunning := false
+		for i := len(pod.Status.ContainerStatuses) - 1; i >= 0; i-- {
+			container := pod.Status.ContainerStatuses[i]
+			switch {
+			case container.State.Waiting != nil && container.State.Waiting.Reason != "":
+				reason = container.State.Waiting.Reason
+			case container.State.Terminated != nil && container.State.Terminated.Reason != "":
+				reason = container.State.Terminated.Reason
+			case container.State.Terminated != nil && container.State.Terminated.Signal != 0:
+				reason = fmt.Sprintf("Signal:%d", container.State.Terminated.Signal)
+			case container.State.Terminated != nil:
+				reason = fmt.Sprintf("ExitCode:%d", container.State.Terminated.ExitCode)
+			case container.Ready && container.State.Running != nil:
+				hasRunning = true
 			}
+		}
 
-			podInfo := PodInfo{
-				Name:       pod.Name,
-				Namespace:  pod.Namespace,
-				Status:     status,
-				IP:         pod.Status.PodIP,
-				Node:       pod.Spec.NodeName,
-				Age:        age,
-				Containers: len(pod.Spec.Containers),
-				CreatedAt:  pod.CreationTimestamp.Time,
+		// A completed container next to running ones means the pod is still running
+		if reason == "Completed" && hasRunning {
+			if isPodReady(pod) {
+				reason = "Running"
+			} else {
+				reason = "NotReady"
 			}
+		}
+	}
 
-			podInfoList = append(podInfoList, podInfo)
+	if pod.DeletionTimestamp != nil {
+		if pod.Status.Reason == "NodeLost" {
+			return "Unknown"
 		}
+		return "Terminating"
+	}
+	return reason
+}
 
-		// Send JSON response
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"pods":  podInfoList,
-			"count": len(podInfoList),
-		})
+// isPodReady reports whether a pod's Ready condition is true
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podListPageSize is how many pods are fetched from the API server at a time
+// while streaming a pod list
+const podListPageSize = 500
 
-		fmt.Printf("Returned %d pods\n", len(podInfoList))
-	}).Methods("GET")
+// serverTime returns the current time for pod responses. Responses carry
+// createdAt timestamps rather than relative ages, which go stale as soon as a
+// response is cached; clients compute ages against this, so clock skew
+// between client and server does not matter.
+func serverTime() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// podInfoFromPod summarizes a pod for the pod list
+func podInfoFromPod(pod *corev1.Pod) PodInfo {
+	return PodInfo{
+		Name:       pod.Name,
+		Namespace:  pod.Namespace,
+		Status:     computePodStatus(pod),
+		IP:         pod.Status.PodIP,
+		Node:       pod.Spec.NodeName,
+		Containers: len(pod.Spec.Containers),
+		CreatedAt:  pod.CreationTimestamp.Time,
+	}
+}
+
+// streamPodList writes the pods of a namespace (all namespaces when empty)
+// as the usual envelope, {"data":{"serverTime":t,"pods":[...],"count":n},"success":true},
+// encoding each pod as soon as its page arrives from the API server so memory
+// stays flat however large the cluster. An error before anything is written
+// gets a normal error response; once streaming has begun, the envelope is
+// closed with success false and the error instead.
+func streamPodList(ctx context.Context, w http.ResponseWriter, clientset kubernetes.Interface, namespace string) (int, error) {
+	options := metav1.ListOptions{Limit: podListPageSize}
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, options)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get pods: "+err.Error())
+		return 0, err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, `{"data":{"serverTime":%q,"pods":[`, serverTime())
+
+	count := 0
+	for {
+		for i := range pods.Items {
+			podJSON, err := json.Marshal(podInfoFromPod(&pods.Items[i]))
+			if err != nil {
+				return count, closePodStream(w, count, err)
+			}
+			if count > 0 {
+				io.WriteString(w, ",")
+			}
+			if _, err := w.Write(podJSON); err != nil {
+				// The client went away; nothing more can be written
+				return count, err
+			}
+			count++
+		}
+
+		if pods.Continue == "" {
+			break
+		}
+		options.Continue = pods.Continue
+		if pods, err = clientset.CoreV1().Pods(namespace).List(ctx, options); err != nil {
+			return count, closePodStream(w, count, err)
+		}
+	}
+
+	return count, closePodStream(w, count, nil)
+}
+
+// closePodStream ends a streamed pod list, reporting err in the envelope
+func closePodStream(w http.ResponseWriter, count int, err error) error {
+	tail := struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error,omitempty"`
+	}{Success: err == nil}
+	if err != nil {
+		tail.Error = "Failed to get pods: " + err.Error()
+	}
+	tailJSON, _ := json.Marshal(tail)
+
+	// Splice the success/error fields into the already open envelope
+	fmt.Fprintf(w, `],"count":%d},%s`+"\n", count, tailJSON[1:])
+	return err
+}
+
+// podNamespaceScope returns the namespace the authenticated user may read
+// pods from: their own, or every namespace ("") for admins
+func podNamespaceScope(r *http.Request) string {
+	claims := authFromContext(r.Context())
+	if isAdmin(claims) {
+		return metav1.NamespaceAll
+	}
+	return GetUserNamespace(claims.UserID, claims.Username)
+}
+
+// RegisterPodsHandler adds the pod-related routes to the router. Pods are
+// only visible to authenticated users, each scoped to their own namespace;
+// admins see every namespace.
+func RegisterPodsHandler(r *mux.Router, clientset kubernetes.Interface) {
+	// Endpoint to list the pods the caller may see, streamed page by page so a
+	// large cluster is not cut off by the server's WriteTimeout
+	r.HandleFunc("/api/pods", requireAuth(streaming(func(w http.ResponseWriter, r *http.Request) {
+		namespace := podNamespaceScope(r)
+		fmt.Printf("Getting pods list from K3s (namespace %q)...\n", namespace)
+
+		count, err := streamPodList(r.Context(), w, clientset, namespace)
+		if err != nil {
+			fmt.Printf("Error getting pods: %v\n", err)
+			return
+		}
+		fmt.Printf("Returned %d pods\n", count)
+	}))).Methods("GET")
 
 	// Endpoint to get details of a specific pod
-	r.HandleFunc("/api/pods/{namespace}/{name}", func(w http.ResponseWriter, r *http.Request) {
+	r.HandleFunc("/api/pods/{namespace}/{name}", requireAuth(func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		namespace := vars["namespace"]
 		name := vars["name"]
 
+		if scope := podNamespaceScope(r); scope != metav1.NamespaceAll && scope != namespace {
+			respondError(w, http.StatusForbidden, "Cannot read pods outside your namespace")
+			return
+		}
+
 		fmt.Printf("Getting details for pod %s in namespace %s\n", name, namespace)
 
 		pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
 		if err != nil {
 			fmt.Printf("Error getting pod details: %v\n", err)
-			http.Error(w, "Pod not found", http.StatusNotFound)
+			respondError(w, http.StatusNotFound, "Pod not found")
 			return
 		}
 
@@ -103,32 +307,16 @@ func RegisterPodsHandler(r *mux.Router, clientset *kubernetes.Clientset) {
 		podDetails := map[string]interface{}{
 			"name":       pod.Name,
 			"namespace":  pod.Namespace,
-			"status":     pod.Status.Phase,
+			"status":     computePodStatus(pod),
 			"ip":         pod.Status.PodIP,
 			"node":       pod.Spec.NodeName,
 			"createdAt":  pod.CreationTimestamp.Time,
+			"serverTime": serverTime(),
 			"containers": containers,
 			"labels":     pod.Labels,
 		}
 
 		// Send JSON response
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(podDetails)
-	}).Methods("GET")
-}
-
-// calculateAge returns a human-readable string representing time since the given time
-func calculateAge(t time.Time) string {
-	duration := time.Since(t)
-
-	if duration.Hours() > 48 {
-		days := int(duration.Hours() / 24)
-		return fmt.Sprintf("%dd", days)
-	} else if duration.Hours() > 1 {
-		return fmt.Sprintf("%dh", int(duration.Hours()))
-	} else if duration.Minutes() > 1 {
-		return fmt.Sprintf("%dm", int(duration.Minutes()))
-	} else {
-		return fmt.Sprintf("%ds", int(duration.Seconds()))
-	}
+		respondSuccess(w, http.StatusOK, podDetails)
+	})).Methods("GET")
 }
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/pprof"
+)
+
+// startPprofServer serves the net/http/pprof handlers on their own listener so
+// profiling is never exposed through the public API port. Fetch profiles with
+// e.g. kubectl port-forward and `go tool pprof http://localhost:6060/debug/pprof/heap`.
+func startPprofServer(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	fmt.Printf("🔬 pprof endpoints listening on http://%s/debug/pprof/\n", addr)
+	go func() {
+		// No WriteTimeout: CPU profiles and traces stream for their requested duration
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("Warning: pprof server stopped: %v", err)
+		}
+	}()
+}
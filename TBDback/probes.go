@@ -0,0 +1,63 @@
+package main
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Probe types selectable with PROBE_TYPE
+const (
+	ProbeTypeExec = "exec" // run the database's own readiness client
+	ProbeTypeTCP  = "tcp"  // only check that the port accepts connections
+	ProbeTypeOff  = "off"  // no probes
+)
+
+// probeCommands are the exec probes per database type. They connect over
+// TCP to localhost, so they only pass once the server accepts queries rather
+// than while the entrypoint is still running its init scripts on the socket.
+// Add new database types here.
+var probeCommands = map[string][]string{
+	DatabaseTypePostgreSQL: {"sh", "-c", `pg_isready -h 127.0.0.1 -U "$POSTGRES_USER" -d "$POSTGRES_DB"`},
+	DatabaseTypeMySQL:      {"sh", "-c", `MYSQL_PWD="$MYSQL_ROOT_PASSWORD" mysqladmin ping -h 127.0.0.1 -u root --silent`},
+}
+
+// applyDatabaseProbes adds readiness and liveness probes to a database
+// container, tuned by the PROBE_* settings. Liveness only starts after its
+// own, longer delay so slow volumes do not get a cold-starting database killed.
+func applyDatabaseProbes(deployment *appsv1.Deployment, dbType string) {
+	if appConfig == nil || appConfig.Probes.Type == ProbeTypeOff {
+		return
+	}
+
+	// The database is always the first container; sidecars keep their defaults
+	container := &deployment.Spec.Template.Spec.Containers[0]
+	handler := probeHandler(dbType)
+
+	settings := appConfig.Probes
+	container.ReadinessProbe = &corev1.Probe{
+		ProbeHandler:        handler,
+		InitialDelaySeconds: int32(settings.ReadinessInitialDelay.Seconds()),
+		PeriodSeconds:       int32(settings.Period.Seconds()),
+		TimeoutSeconds:      int32(settings.Timeout.Seconds()),
+		FailureThreshold:    int32(settings.FailureThreshold),
+	}
+	container.LivenessProbe = &corev1.Probe{
+		ProbeHandler:        handler,
+		InitialDelaySeconds: int32(settings.LivenessInitialDelay.Seconds()),
+		PeriodSeconds:       int32(settings.Period.Seconds()),
+		TimeoutSeconds:      int32(settings.Timeout.Seconds()),
+		FailureThreshold:    int32(settings.FailureThreshold),
+	}
+}
+
+// probeHandler returns the configured check for a database type, falling
+// back to a TCP check for types without an exec command
+func probeHandler(dbType string) corev1.ProbeHandler {
+	if command, ok := probeCommands[dbType]; ok && appConfig.Probes.Type == ProbeTypeExec {
+		return corev1.ProbeHandler{Exec: &corev1.ExecAction{Command: command}}
+	}
+	return corev1.ProbeHandler{
+		TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt32(mustDefaultPortNumber(dbType))},
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/BouchamiAhmed/TBD/apperrors"
+	"github.com/BouchamiAhmed/TBD/shared"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// defaultResourceProfile is used when a request names no profile
+const defaultResourceProfile = "small"
+
+// DatabaseResources overrides individual quantities of the resource profile
+type DatabaseResources = shared.DatabaseResources
+
+// resourceProfiles maps each t-shirt size to the database container's
+// requests and limits. Add or tune sizes here.
+var resourceProfiles = map[string]DatabaseResources{
+	"small":  {CPURequest: "100m", CPULimit: "500m", MemoryRequest: "256Mi", MemoryLimit: "512Mi"},
+	"medium": {CPURequest: "250m", CPULimit: "1", MemoryRequest: "512Mi", MemoryLimit: "1Gi"},
+	"large":  {CPURequest: "500m", CPULimit: "2", MemoryRequest: "1Gi", MemoryLimit: "2Gi"},
+}
+
+// resolveResources returns the profile's quantities with any explicit
+// overrides applied
+func resolveResources(profile string, overrides *DatabaseResources) (DatabaseResources, error) {
+	if profile == "" {
+		profile = defaultResourceProfile
+	}
+	resolved, ok := resourceProfiles[strings.ToLower(profile)]
+	if !ok {
+		return DatabaseResources{}, apperrors.New(apperrors.ErrInvalidInput, "unknown resource profile '%s' (supported: small, medium, large)", profile)
+	}
+
+	if overrides != nil {
+		for _, field := range []struct{ value, target *string }{
+			{&overrides.CPURequest, &resolved.CPURequest},
+			{&overrides.CPULimit, &resolved.CPULimit},
+			{&overrides.MemoryRequest, &resolved.MemoryRequest},
+			{&overrides.MemoryLimit, &resolved.MemoryLimit},
+		} {
+			if *field.value != "" {
+				*field.target = *field.value
+			}
+		}
+	}
+	return resolved, nil
+}
+
+// validateResources checks that a request's profile exists and that the
+// resolved quantities parse, with each request no greater than its limit
+func validateResources(profile string, overrides *DatabaseResources) error {
+	resolved, err := resolveResources(profile, overrides)
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range []struct{ name, request, limit string }{
+		{"cpu", resolved.CPURequest, resolved.CPULimit},
+		{"memory", resolved.MemoryRequest, resolved.MemoryLimit},
+	} {
+		request, err := resource.ParseQuantity(pair.request)
+		if err != nil {
+			return apperrors.New(apperrors.ErrInvalidInput, "invalid %s request '%s'", pair.name, pair.request)
+		}
+		limit, err := resource.ParseQuantity(pair.limit)
+		if err != nil {
+			return apperrors.New(apperrors.ErrInvalidInput, "invalid %s limit '%s'", pair.name, pair.limit)
+		}
+		if request.Cmp(limit) > 0 {
+			return apperrors.New(apperrors.ErrInvalidInput, "%s request %s exceeds limit %s", pair.name, pair.request, pair.limit)
+		}
+	}
+	return nil
+}
+
+// databaseResources returns the database container's resource requirements;
+// the request must have passed validateResources
+func databaseResources(dbRequest DatabaseRequest) corev1.ResourceRequirements {
+	resolved, _ := resolveResources(dbRequest.Profile, dbRequest.Resources)
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceMemory: mustParseQuantity(resolved.MemoryRequest),
+			corev1.ResourceCPU:    mustParseQuantity(resolved.CPURequest),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: mustParseQuantity(resolved.MemoryLimit),
+			corev1.ResourceCPU:    mustParseQuantity(resolved.CPULimit),
+		},
+	}
+}
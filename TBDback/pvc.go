@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const dataVolumeName = "data"
+
+// dataMounts gives, per database type, where the data volume is mounted and
+// the subdirectory of the volume mounted there.
+//
+// The subPath is required: a freshly formatted volume is not empty (ext4 puts
+// lost+found at its root), and both initdb and mysqld refuse to initialize a
+// non-empty data directory. Mounting a subdirectory gives them an empty one.
+var dataMounts = map[string]struct {
+	mountPath string
+	subPath   string
+}{
+	DatabaseTypePostgreSQL: {mountPath: "/var/lib/postgresql/data", subPath: "pgdata"},
+	DatabaseTypeMySQL:      {mountPath: "/var/lib/mysql", subPath: "mysql"},
+}
+
+// dataPVCName returns the name of a database's data PersistentVolumeClaim
+func dataPVCName(dbName string) string {
+	return dbName + "-data"
+}
+
+// createDataPVC creates the PersistentVolumeClaim holding a database's data
+// when ENABLE_PVC is set
+func createDataPVC(ctx context.Context, clientset kubernetes.Interface, dbRequest DatabaseRequest, namespace string) error {
+	if !appConfig.Features.PVC {
+		return nil
+	}
+
+	size, err := resource.ParseQuantity(appConfig.PVCStorageSize)
+	if err != nil {
+		return fmt.Errorf("invalid PVC_STORAGE_SIZE %q: %w", appConfig.PVCStorageSize, err)
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dataPVCName(dbRequest.Name),
+			Namespace: namespace,
+			Labels: withDatabaseLabels(map[string]string{
+				"app": dbRequest.Name,
+			}, dbRequest),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+		},
+	}
+	if appConfig.PVCStorageClass != "" {
+		pvc.Spec.StorageClassName = &appConfig.PVCStorageClass
+	}
+
+	if _, err := clientset.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, pvc, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create data PersistentVolumeClaim: %w", err)
+	}
+	logf(ctx, "✅ Created data PersistentVolumeClaim: %s\n", pvc.Name)
+	return nil
+}
+
+// addDataVolume mounts the data PersistentVolumeClaim into the database
+// container through a subPath (see dataMounts). For PostgreSQL, PGDATA is set
+// to the mount so the image's default data directory does not matter.
+// The deployment switches to the Recreate strategy, since a ReadWriteOnce
+// volume cannot be attached to the old and new pod at once.
+func addDataVolume(deployment *appsv1.Deployment, dbRequest DatabaseRequest) {
+	if !appConfig.Features.PVC {
+		return
+	}
+	mount := dataMounts[dbRequest.Type]
+
+	deployment.Spec.Strategy = appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+
+	podSpec := &deployment.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: dataVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: dataPVCName(dbRequest.Name)},
+		},
+	})
+
+	container := &podSpec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      dataVolumeName,
+		MountPath: mount.mountPath,
+		SubPath:   mount.subPath,
+	})
+	if dbRequest.Type == DatabaseTypePostgreSQL {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "PGDATA", Value: mount.mountPath})
+	}
+}
+
+// deleteDataPVC removes a database's data PersistentVolumeClaim if it exists
+func deleteDataPVC(ctx context.Context, dbName, namespace string) {
+	err := clientset.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, dataPVCName(dbName), metav1.DeleteOptions{})
+	switch {
+	case err == nil:
+		logf(ctx, "✅ Deleted data PersistentVolumeClaim\n")
+	case !errors.IsNotFound(err):
+		logf(ctx, "Warning: Failed to delete data PersistentVolumeClaim: %v\n", err)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// databaseReadyPollInterval is how often a new database deployment is checked for readiness
+const databaseReadyPollInterval = 2 * time.Second
+
+// waitForDatabaseReady blocks, when WAIT_FOR_DB is on, until the database
+// deployment reports a ready replica, so its admin dashboard does not start
+// against a database that is still initializing. On timeout it logs a warning
+// and lets the deploy continue; the dashboard reconnects once the database is up.
+func waitForDatabaseReady(ctx context.Context, clientset kubernetes.Interface, namespace, name string) {
+	if appConfig == nil || !appConfig.WaitForDB {
+		return
+	}
+
+	logf(ctx, "⏳ Waiting up to %s for database '%s' to become ready\n", appConfig.WaitForDBTimeout, name)
+	start := time.Now()
+
+	err := wait.PollUntilContextTimeout(ctx, databaseReadyPollInterval, appConfig.WaitForDBTimeout, true, func(ctx context.Context) (bool, error) {
+		deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return deployment.Status.ReadyReplicas > 0, nil
+	})
+
+	waited := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		logf(ctx, "⚠️  Database '%s' not ready after %s, creating its dashboard anyway: %v\n", name, waited, err)
+		return
+	}
+	logf(ctx, "✅ Database '%s' ready after %s\n", name, waited)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/BouchamiAhmed/TBD/shared"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	readOnlySQLVolumeName = "readonly-sql"
+	readOnlySQLFileName   = "readonly.sql"
+)
+
+// ReadOnlyCredentials are the credentials of a database's read-only role
+type ReadOnlyCredentials = shared.ReadOnlyCredentials
+
+// readOnlySecretName returns the name of the Secret holding a database's read-only role script
+func readOnlySecretName(dbName string) string {
+	return dbName + "-readonly-sql"
+}
+
+// readOnlyUsername returns the name of a database's read-only role
+func readOnlyUsername(dbRequest DatabaseRequest) string {
+	return dbRequest.Username + "_ro"
+}
+
+// generatePassword returns a random 32-character hex password
+func generatePassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// readOnlySQL returns the script creating a login role limited to SELECT on
+// the database, including tables created after it runs
+func readOnlySQL(dbRequest DatabaseRequest) (string, error) {
+	role := readOnlyUsername(dbRequest)
+	password := strings.ReplaceAll(dbRequest.ReadOnlyPassword, "'", "''")
+
+	switch dbRequest.Type {
+	case DatabaseTypePostgreSQL:
+		quote := func(ident string) string { return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"` }
+		return fmt.Sprintf(`CREATE ROLE %[1]s LOGIN PASSWORD '%[2]s' NOSUPERUSER NOCREATEDB NOCREATEROLE;
+GRANT CONNECT ON DATABASE %[3]s TO %[1]s;
+GRANT USAGE ON SCHEMA public TO %[1]s;
+GRANT SELECT ON ALL TABLES IN SCHEMA public TO %[1]s;
+ALTER DEFAULT PRIVILEGES FOR ROLE %[4]s IN SCHEMA public GRANT SELECT ON TABLES TO %[1]s;
+`, quote(role), password, quote(dbRequest.LogicalDatabaseName()), quote(dbRequest.Username)), nil
+	case DatabaseTypeMySQL:
+		quote := func(ident string) string { return "`" + strings.ReplaceAll(ident, "`", "``") + "`" }
+		return fmt.Sprintf(`CREATE USER '%[1]s'@'%%' IDENTIFIED BY '%[2]s';
+GRANT SELECT ON %[3]s.* TO '%[1]s'@'%%';
+FLUSH PRIVILEGES;
+`, strings.ReplaceAll(role, "'", "''"), password, quote(dbRequest.LogicalDatabaseName())), nil
+	}
+	return "", fmt.Errorf("read-only users are not supported for database type %s", dbRequest.Type)
+}
+
+// createReadOnlySecret stores the read-only role script in a Secret, since it
+// carries the role's password
+func createReadOnlySecret(ctx context.Context, clientset kubernetes.Interface, dbRequest DatabaseRequest, namespace string) error {
+	script, err := readOnlySQL(dbRequest)
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      readOnlySecretName(dbRequest.Name),
+			Namespace: namespace,
+			Labels: withDatabaseLabels(map[string]string{
+				"app": dbRequest.Name,
+			}, dbRequest),
+		},
+		StringData: map[string]string{
+			readOnlySQLFileName: script,
+		},
+	}
+
+	if _, err := clientset.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create read-only role Secret: %w", err)
+	}
+	logf(ctx, "✅ Created read-only role Secret: %s\n", secret.Name)
+	return nil
+}
+
+// addReadOnlySQLVolume mounts the read-only role script next to the init SQL
+// script, so the database image runs it on first boot
+func addReadOnlySQLVolume(deployment *appsv1.Deployment, dbRequest DatabaseRequest) {
+	if !dbRequest.ReadOnlyUser {
+		return
+	}
+
+	podSpec := &deployment.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: readOnlySQLVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: readOnlySecretName(dbRequest.Name)},
+		},
+	})
+	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      readOnlySQLVolumeName,
+		MountPath: initSQLMountPath + "/" + readOnlySQLFileName,
+		SubPath:   readOnlySQLFileName,
+		ReadOnly:  true,
+	})
+}
+
+// deleteReadOnlySecret removes a database's read-only role Secret if it exists
+func deleteReadOnlySecret(ctx context.Context, dbName, namespace string) {
+	err := clientset.CoreV1().Secrets(namespace).Delete(ctx, readOnlySecretName(dbName), metav1.DeleteOptions{})
+	switch {
+	case err == nil:
+		logf(ctx, "✅ Deleted read-only role Secret\n")
+	case !errors.IsNotFound(err):
+		logf(ctx, "Warning: Failed to delete read-only role Secret: %v\n", err)
+	}
+}
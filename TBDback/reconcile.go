@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// runReconcileLoop periodically checks every managed database and recreates
+// any Service, IngressRoute or Middleware that went missing
+func runReconcileLoop(interval time.Duration) {
+	fmt.Printf("🔁 Reconcile loop started (interval %s)\n", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := reconcileDatabases(context.Background()); err != nil {
+			fmt.Printf("⚠️ Reconcile pass failed: %v\n", err)
+		}
+	}
+}
+
+// reconcileDatabases runs a single reconcile pass across all namespaces
+func reconcileDatabases(ctx context.Context) error {
+	deployments, err := ownedDatabaseDeployments(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, deployment := range deployments {
+		if err := reconcileDatabaseLocked(ctx, deployment); err != nil {
+			fmt.Printf("⚠️ Reconcile failed for '%s/%s': %v\n", deployment.Namespace, deployment.Name, err)
+		}
+	}
+	return nil
+}
+
+// ownedDatabaseDeployments lists the database deployments in this instance's
+// namespaces, leaving out those being deleted
+func ownedDatabaseDeployments(ctx context.Context) ([]*appsv1.Deployment, error) {
+	deployments, err := listDatabaseDeployments(ctx, metav1.NamespaceAll, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list database deployments: %w", err)
+	}
+
+	owned := map[string]bool{}
+	result := make([]*appsv1.Deployment, 0, len(deployments))
+	for _, deployment := range deployments {
+		if deployment.DeletionTimestamp != nil {
+			continue
+		}
+
+		ours, checked := owned[deployment.Namespace]
+		if !checked {
+			ours, err = ownsNamespace(ctx, deployment.Namespace)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check namespace '%s': %w", deployment.Namespace, err)
+			}
+			owned[deployment.Namespace] = ours
+		}
+		if ours {
+			result = append(result, deployment)
+		}
+	}
+	return result, nil
+}
+
+// reconcileDatabaseLocked reconciles a database under its namespace lock, so
+// objects a concurrent delete just removed are not recreated behind it. The
+// deployment is re-read under the lock and skipped once it is gone.
+func reconcileDatabaseLocked(ctx context.Context, deployment *appsv1.Deployment) error {
+	unlock, err := lockNamespace(ctx, deployment.Namespace)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := clientset.AppsV1().Deployments(deployment.Namespace).Get(ctx, deployment.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get database deployment: %w", err)
+	}
+	if current.DeletionTimestamp != nil {
+		return nil
+	}
+	return reconcileDatabase(ctx, current)
+}
+
+// reconcileDatabase ensures one database has all of its supporting resources.
+// The admin dashboard's routing is only repaired while its deployment exists.
+func reconcileDatabase(ctx context.Context, deployment *appsv1.Deployment) error {
+	namespace := deployment.Namespace
+	dbType := labeledDatabaseType(deployment.Labels)
+	userID, _ := strconv.Atoi(deployment.Labels["db-saas/user-id"])
+
+	dbRequest := DatabaseRequest{
+		Name:     deployment.Name,
+		Type:     dbType,
+		UserID:   userID,
+		UserName: deployment.Labels["db-saas/username"],
+	}
+
+	var createService, createAdminService func(DatabaseRequest) *corev1.Service
+	var adminType string
+	switch dbType {
+	case DatabaseTypeMySQL:
+		dbRequest.Username = containerEnvValue(deployment, "MYSQL_USER")
+		createService, createAdminService, adminType = createMySQLService, createPhpMyAdminService, "phpmyadmin"
+	case DatabaseTypePostgreSQL:
+		dbRequest.Username = containerEnvValue(deployment, "POSTGRES_USER")
+		createService, createAdminService, adminType = createPostgreSQLService, createPgAdminService, "pgadmin"
+	default:
+		return fmt.Errorf("unknown database type '%s'", dbType)
+	}
+
+	// Database service
+	if err := ensureService(ctx, namespace, createService(dbRequest)); err != nil {
+		return err
+	}
+
+	// Admin dashboard service and Traefik routing
+	adminName := adminDeploymentName(dbRequest.Name, dbType)
+	if _, err := clientset.AppsV1().Deployments(namespace).Get(ctx, adminName, metav1.GetOptions{}); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get admin deployment: %w", err)
+	}
+
+	if err := ensureService(ctx, namespace, createAdminService(dbRequest)); err != nil {
+		return err
+	}
+
+	middlewareNames := []string{traefikHeadersMiddlewareName(namespace, dbRequest.Name, adminType)}
+	if adminType == "phpmyadmin" {
+		middlewareNames = append(middlewareNames, traefikReplacePathMiddlewareName(namespace, dbRequest.Name, adminType))
+	}
+	missing, err := traefikObjectsMissing(ctx, middlewaresGVR, traefikNamespace(namespace), middlewareNames...)
+	if err != nil {
+		return err
+	}
+	if missing {
+		fmt.Printf("🔧 Reconcile: recreating Traefik middlewares for '%s/%s'\n", namespace, dbRequest.Name)
+		// The create path makes all middlewares at once, so clear any survivors first
+		for _, name := range middlewareNames {
+			err := dynamicClient.Resource(middlewaresGVR).Namespace(traefikNamespace(namespace)).Delete(ctx, name, metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("failed to clear middleware %s before recreating: %w", name, err)
+			}
+		}
+		if adminType == "pgadmin" {
+			err = createPgAdminMiddleware(ctx, dbRequest, namespace)
+		} else {
+			err = createTraefikMiddleware(ctx, dbRequest, namespace, adminType)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to recreate middlewares: %w", err)
+		}
+	}
+
+	missing, err = traefikObjectsMissing(ctx, ingressRoutesGVR, traefikNamespace(namespace), traefikIngressRouteName(namespace, dbRequest.Name, adminType))
+	if err != nil {
+		return err
+	}
+	if missing {
+		fmt.Printf("🔧 Reconcile: recreating IngressRoute for '%s/%s'\n", namespace, dbRequest.Name)
+		if adminType == "pgadmin" {
+			err = createPgAdminIngressRoute(ctx, dbRequest, namespace, 80)
+		} else {
+			err = createTraefikIngressRoute(ctx, dbRequest, namespace, adminType, 80)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to recreate IngressRoute: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ensureService creates a service if it does not exist
+func ensureService(ctx context.Context, namespace string, service *corev1.Service) error {
+	_, err := clientset.CoreV1().Services(namespace).Get(ctx, service.Name, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get service %s: %w", service.Name, err)
+	}
+
+	fmt.Printf("🔧 Reconcile: recreating missing service '%s/%s'\n", namespace, service.Name)
+	if _, err := clientset.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to recreate service %s: %w", service.Name, err)
+	}
+	return nil
+}
+
+// traefikObjectsMissing reports whether any of the named Traefik objects is missing
+func traefikObjectsMissing(ctx context.Context, gvr schema.GroupVersionResource, namespace string, names ...string) (bool, error) {
+	for _, name := range names {
+		_, err := dynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to get %s %s: %w", gvr.Resource, name, err)
+		}
+	}
+	return false, nil
+}
+
+// containerEnvValue returns a literal env var from the deployment's first container
+func containerEnvValue(deployment *appsv1.Deployment, name string) string {
+	containers := deployment.Spec.Template.Spec.Containers
+	if len(containers) == 0 {
+		return ""
+	}
+	for _, env := range containers[0].Env {
+		if env.Name == name {
+			return env.Value
+		}
+	}
+	return ""
+}
+
+// StatusChange describes a database record whose status was corrected
+type StatusChange struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+// reconcileRecordStatuses cross-references the persisted database records
+// against their deployments and updates any status that has drifted.
+// External records have no deployment and are left alone.
+func reconcileRecordStatuses(ctx context.Context, dbClient *DBClient) (int, []StatusChange, error) {
+	records, err := dbClient.ListAllDatabaseRecords()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	checked := 0
+	changes := []StatusChange{}
+	for _, record := range records {
+		if record.Status == DatabaseStatusExternal {
+			continue
+		}
+		checked++
+
+		status := DatabaseStatusMissing
+		_, err := clientset.AppsV1().Deployments(record.Namespace).Get(ctx, record.Name, metav1.GetOptions{})
+		if err == nil {
+			status, err = getDatabasePhase(ctx, record.Namespace, record.Name)
+			if err != nil {
+				return checked, changes, fmt.Errorf("failed to get phase of '%s/%s': %w", record.Namespace, record.Name, err)
+			}
+		} else if !errors.IsNotFound(err) {
+			return checked, changes, fmt.Errorf("failed to get deployment '%s/%s': %w", record.Namespace, record.Name, err)
+		}
+
+		// Finish deletes that removed the resources but not the record; ones
+		// that still have resources wait for the delete to be retried
+		if record.Status == DatabaseStatusDeleteFailed {
+			if status != DatabaseStatusMissing {
+				continue
+			}
+			if err := dbClient.DeleteDatabaseRecord(record.Name, record.Namespace); err != nil {
+				return checked, changes, err
+			}
+			fmt.Printf("🔧 Reconcile: removed record of deleted database '%s/%s'\n", record.Namespace, record.Name)
+			continue
+		}
+
+		if status == record.Status {
+			continue
+		}
+
+		if err := dbClient.UpdateDatabaseRecordStatus(record.Name, record.Namespace, status); err != nil {
+			return checked, changes, err
+		}
+		fmt.Printf("🔧 Reconcile: '%s/%s' status %s -> %s\n", record.Namespace, record.Name, record.Status, status)
+		notifyStatusChange(record.Name, record.Namespace, record.UserID, record.Status, status)
+		changes = append(changes, StatusChange{
+			Name:      record.Name,
+			Namespace: record.Namespace,
+			From:      record.Status,
+			To:        status,
+		})
+	}
+
+	return checked, changes, nil
+}
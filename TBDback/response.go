@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/BouchamiAhmed/TBD/apperrors"
+)
+
+// apiResponse is the envelope every JSON endpoint responds with
+type apiResponse struct {
+	Success bool         `json:"success"`
+	Data    interface{}  `json:"data,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Code    string       `json:"code,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+// writeJSON writes a JSON body with the given status code
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		fmt.Printf("Error encoding JSON response: %v\n", err)
+	}
+}
+
+// respondSuccess writes a successful response with data in the envelope
+func respondSuccess(w http.ResponseWriter, status int, data interface{}) {
+	writeJSON(w, status, apiResponse{Success: true, Data: data})
+}
+
+// respondError writes a failed response with an error message in the envelope
+func respondError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, apiResponse{Success: false, Error: message})
+}
+
+// respondErrorCode writes a failed response with a machine-readable code
+// (e.g. NOT_FOUND) alongside the error message
+func respondErrorCode(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, apiResponse{Success: false, Error: message, Code: code})
+}
+
+// respondRequestError writes a failed response for an error returned while
+// checking a request, listing every field problem when it carries several
+func respondRequestError(w http.ResponseWriter, err error) {
+	if problems := validationProblems(err); len(problems) > 0 {
+		writeJSON(w, http.StatusBadRequest, apiResponse{
+			Success: false,
+			Error:   "Invalid request: " + err.Error(),
+			Code:    "VALIDATION_FAILED",
+			Errors:  problems,
+		})
+		return
+	}
+	respondError(w, apperrors.HTTPStatus(err), err.Error())
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Non-root user IDs baked into the upstream images
+const (
+	postgresUID   int64 = 999  // postgres user in the official postgres image
+	mysqlUID      int64 = 999  // mysql user in the official mysql image
+	pgAdminUID    int64 = 5050 // pgadmin user in the dpage/pgadmin4 image
+	phpMyAdminUID int64 = 33   // www-data user in the phpmyadmin image
+)
+
+// phpMyAdminPort is the unprivileged port Apache listens on in phpMyAdmin
+// pods, so the image can run as www-data without binding port 80
+const phpMyAdminPort int32 = 8080
+
+// applyPodSecurity hardens a deployment's pods to satisfy the Pod Security
+// Admission "restricted" profile: non-root user, no privilege escalation,
+// all capabilities dropped (plus any explicitly re-added) and the RuntimeDefault
+// seccomp profile. Root filesystems stay writable because none of the images
+// we deploy can run read-only without a data volume. No-op unless
+// ENABLE_POD_SECURITY is set.
+func applyPodSecurity(deployment *appsv1.Deployment, uid int64, addCapabilities ...corev1.Capability) {
+	if appConfig == nil || !appConfig.Features.PodSecurity {
+		return
+	}
+
+	runAsNonRoot := true
+	allowPrivilegeEscalation := false
+
+	podSpec := &deployment.Spec.Template.Spec
+	podSpec.SecurityContext = &corev1.PodSecurityContext{
+		RunAsNonRoot: &runAsNonRoot,
+		RunAsUser:    &uid,
+		RunAsGroup:   &uid,
+		FSGroup:      &uid,
+		SeccompProfile: &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		},
+	}
+
+	for i := range podSpec.Containers {
+		podSpec.Containers[i].SecurityContext = &corev1.SecurityContext{
+			RunAsNonRoot:             &runAsNonRoot,
+			AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{"ALL"},
+				Add:  addCapabilities,
+			},
+		}
+	}
+}
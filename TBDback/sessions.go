@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// sessionSweepInterval is how often expired sessions are deleted
+const sessionSweepInterval = 15 * time.Minute
+
+// sessionTouchInterval is how stale a session's last-seen time may get before
+// an authenticated request refreshes it, so not every request writes a row
+const sessionTouchInterval = time.Minute
+
+// sessionDBClient records issued tokens so they can be listed and revoked.
+// When nil, tokens are validated by signature and expiry alone.
+var sessionDBClient *DBClient
+
+const sessionIDKey contextKey = "sessionID"
+
+// Session is an issued token tracked server-side; the token itself is never stored
+type Session struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"userId"`
+	IssuedAt   time.Time `json:"issuedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	Current    bool      `json:"current"`
+}
+
+// hashToken returns the SHA-256 hex digest under which a token is stored
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// CreateSession records a newly issued token
+func (c *DBClient) CreateSession(token string, claims *TokenClaims) error {
+	query := `
+	INSERT INTO sessions (user_id, token_hash, issued_at, expires_at, last_seen_at)
+	VALUES ($1, $2, $3, $4, $3)
+	ON CONFLICT (token_hash) DO NOTHING`
+
+	_, err := c.db.Exec(
+		query,
+		claims.UserID,
+		hashToken(token),
+		time.Unix(claims.IssuedAt, 0),
+		time.Unix(claims.ExpiresAt, 0),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating session: %w", err)
+	}
+	return nil
+}
+
+// TouchSession returns the ID of the token's unexpired session, or
+// sql.ErrNoRows when the token has no live session (revoked). The last-seen
+// time is only written once it is older than sessionTouchInterval.
+func (c *DBClient) TouchSession(ctx context.Context, token string) (int, error) {
+	query := `
+	WITH live AS (
+		SELECT id FROM sessions
+		WHERE token_hash = $1 AND expires_at > CURRENT_TIMESTAMP
+	), touched AS (
+		UPDATE sessions SET last_seen_at = CURRENT_TIMESTAMP
+		WHERE id IN (SELECT id FROM live)
+		AND last_seen_at < CURRENT_TIMESTAMP - make_interval(secs => $2)
+	)
+	SELECT id FROM live`
+
+	var id int
+	if err := c.db.QueryRowContext(ctx, query, hashToken(token), sessionTouchInterval.Seconds()).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// ListActiveSessions returns a user's unexpired sessions, most recent first
+func (c *DBClient) ListActiveSessions(userID int) ([]Session, error) {
+	query := `
+	SELECT id, user_id, issued_at, expires_at, last_seen_at
+	FROM sessions
+	WHERE user_id = $1 AND expires_at > CURRENT_TIMESTAMP
+	ORDER BY last_seen_at DESC`
+
+	rows, err := c.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var session Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.IssuedAt, &session.ExpiresAt, &session.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("error scanning session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession deletes one of a user's sessions, reporting whether it existed
+func (c *DBClient) RevokeSession(id, userID int) (bool, error) {
+	result, err := c.db.Exec(`DELETE FROM sessions WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("error revoking session: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// DeleteExpiredSessions removes sessions whose token has expired
+func (c *DBClient) DeleteExpiredSessions() (int64, error) {
+	result, err := c.db.Exec(`DELETE FROM sessions WHERE expires_at <= CURRENT_TIMESTAMP`)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired sessions: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected, nil
+}
+
+// runSessionSweeper periodically deletes expired sessions
+func runSessionSweeper(dbClient *DBClient, interval time.Duration) {
+	fmt.Printf("🧹 Session sweeper started (interval %s)\n", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := dbClient.DeleteExpiredSessions()
+		if err != nil {
+			fmt.Printf("⚠️  Session sweep failed: %v\n", err)
+			continue
+		}
+		if deleted > 0 {
+			fmt.Printf("🧹 Deleted %d expired sessions\n", deleted)
+		}
+	}
+}
+
+// issueToken generates a token for the user and records it as a session
+func issueToken(dbClient *DBClient, user *AuthUser) (string, error) {
+	token := GenerateToken(user.ID, user.Username)
+
+	claims, err := ParseToken(token)
+	if err != nil {
+		return "", fmt.Errorf("error parsing issued token: %w", err)
+	}
+
+	if err := dbClient.CreateSession(token, claims); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// sessionIDFromContext returns the ID of the session authenticating the request, or 0
+func sessionIDFromContext(ctx context.Context) int {
+	id, _ := ctx.Value(sessionIDKey).(int)
+	return id
+}
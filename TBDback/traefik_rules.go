@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/BouchamiAhmed/TBD/shared"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// traefikNamespace returns the namespace holding the IngressRoutes and
+// Middlewares of a database in namespace: TRAEFIK_NAMESPACE when set, otherwise
+// the database's own namespace. Routing to a service in another namespace
+// requires Traefik's kubernetesCRD provider to run with allowCrossNamespace.
+func traefikNamespace(namespace string) string {
+	if appConfig == nil || appConfig.TraefikNamespace == "" {
+		return namespace
+	}
+	return appConfig.TraefikNamespace
+}
+
+// traefikObjectName qualifies a Traefik object name with the database
+// namespace when objects from several namespaces share TRAEFIK_NAMESPACE
+func traefikObjectName(namespace, name string) string {
+	if traefikNamespace(namespace) == namespace {
+		return name
+	}
+	return namespace + "-" + name
+}
+
+// traefikMatcherVersion returns the configured matcher syntax; unknown values
+// are reported once at startup
+func traefikMatcherVersion() string {
+	if appConfig == nil {
+		return shared.TraefikMatcherV2
+	}
+	return appConfig.TraefikMatcherVersion
+}
+
+// traefikHostPathRule returns a rule matching a host and path prefix
+func traefikHostPathRule(host, pathPrefix string) string {
+	return shared.TraefikHostPathRule(traefikMatcherVersion(), host, pathPrefix)
+}
+
+// traefikTLSEnabled reports whether IngressRoutes are served over TLS
+// (ENABLE_TLS, on by default once TRAEFIK_TLS_SECRET or TRAEFIK_CERT_RESOLVER
+// is set)
+func traefikTLSEnabled() bool {
+	return appConfig != nil && appConfig.Features.TLS
+}
+
+// applyTraefikTLS moves an IngressRoute to the TLS entry point and adds its
+// tls block when TLS is enabled. A secret (e.g. a *.db.example.com wildcard
+// cert) takes precedence over the cert resolver when both are set; with
+// neither, Traefik serves its default certificate.
+func applyTraefikTLS(ingressRoute *unstructured.Unstructured) error {
+	if !traefikTLSEnabled() {
+		return nil
+	}
+
+	tls := map[string]interface{}{}
+	if appConfig.TraefikTLSSecret != "" {
+		tls["secretName"] = appConfig.TraefikTLSSecret
+	} else if appConfig.TraefikCertResolver != "" {
+		tls["certResolver"] = appConfig.TraefikCertResolver
+	}
+
+	if err := unstructured.SetNestedSlice(ingressRoute.Object, []interface{}{appConfig.TraefikTLSEntryPoint}, "spec", "entryPoints"); err != nil {
+		return err
+	}
+	return unstructured.SetNestedMap(ingressRoute.Object, tls, "spec", "tls")
+}
+
+// adminURLScheme returns the scheme admin dashboards are served on
+func adminURLScheme() string {
+	if traefikTLSEnabled() {
+		return "https"
+	}
+	return "http"
+}
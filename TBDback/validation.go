@@ -0,0 +1,409 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BouchamiAhmed/TBD/apperrors"
+	"github.com/BouchamiAhmed/TBD/shared"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Canonical database types, shared with the admin service
+const (
+	DatabaseTypePostgreSQL = shared.DatabaseTypePostgreSQL
+	DatabaseTypeMySQL      = shared.DatabaseTypeMySQL
+)
+
+// normalizeDatabaseType returns the canonical type for a requested database
+// type, or an error if the type is not supported. Every entry point goes
+// through here, so only canonical types reach labels and branching.
+func normalizeDatabaseType(dbType string) (string, error) {
+	canonical, ok := shared.NormalizeDatabaseType(dbType)
+	if !ok {
+		return "", apperrors.New(apperrors.ErrInvalidInput, "unsupported database type '%s' (supported: %s, %s)", dbType, DatabaseTypePostgreSQL, DatabaseTypeMySQL)
+	}
+	return canonical, nil
+}
+
+// labeledDatabaseType returns the canonical type recorded in a deployment's
+// db-saas/type label. Labels written before types were normalized may hold an
+// alias (e.g. "postgres"); labels of other kinds are returned unchanged.
+func labeledDatabaseType(labels map[string]string) string {
+	label := labels["db-saas/type"]
+	if canonical, ok := shared.NormalizeDatabaseType(label); ok {
+		return canonical
+	}
+	return label
+}
+
+// defaultPorts maps each canonical database type to the port it listens on.
+// Add new database types here (e.g. redis: 6379, mongodb: 27017).
+var defaultPorts = map[string]int32{
+	DatabaseTypePostgreSQL: 5432,
+	DatabaseTypeMySQL:      3306,
+}
+
+// defaultPortNumber returns the port a database type listens on, or an
+// error for a type with no known port
+func defaultPortNumber(dbType string) (int32, error) {
+	port, ok := defaultPorts[dbType]
+	if !ok {
+		return 0, apperrors.New(apperrors.ErrInvalidInput, "no default port for database type '%s'", dbType)
+	}
+	return port, nil
+}
+
+// defaultPort returns the port a database type listens on as a string
+func defaultPort(dbType string) (string, error) {
+	port, err := defaultPortNumber(dbType)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(int(port)), nil
+}
+
+// mustDefaultPortNumber is defaultPortNumber for the canonical type constants
+// used when building manifests, where an unknown type is a programming error
+func mustDefaultPortNumber(dbType string) int32 {
+	port, err := defaultPortNumber(dbType)
+	if err != nil {
+		panic(err)
+	}
+	return port
+}
+
+// mustDefaultPort is mustDefaultPortNumber as a string
+func mustDefaultPort(dbType string) string {
+	return strconv.Itoa(int(mustDefaultPortNumber(dbType)))
+}
+
+// advertisedPort returns the port reported to clients for a database: the
+// DB_PORT override for PostgreSQL when set, otherwise the type's default
+// port. The type must already have passed prepareDatabaseRequest.
+func advertisedPort(dbType string) string {
+	if dbType == DatabaseTypePostgreSQL && appConfig != nil && appConfig.DBPort != "" {
+		return appConfig.DBPort
+	}
+	return mustDefaultPort(dbType)
+}
+
+// envVarNamePattern matches valid environment variable names
+var envVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// managedEnvVars are set by the platform and cannot be overridden by custom env
+var managedEnvVars = map[string]bool{
+	"POSTGRES_DB":         true,
+	"POSTGRES_USER":       true,
+	"POSTGRES_PASSWORD":   true,
+	"MYSQL_ROOT_PASSWORD": true,
+	"MYSQL_DATABASE":      true,
+	"MYSQL_USER":          true,
+	"MYSQL_PASSWORD":      true,
+}
+
+// validateCustomEnv checks that custom environment variables have valid names
+// and do not override the managed credential variables
+func validateCustomEnv(env map[string]string) error {
+	for name := range env {
+		if !envVarNamePattern.MatchString(name) {
+			return apperrors.New(apperrors.ErrInvalidInput, "invalid environment variable name '%s'", name)
+		}
+		if managedEnvVars[strings.ToUpper(name)] {
+			return apperrors.New(apperrors.ErrInvalidInput, "environment variable '%s' is managed by the platform and cannot be overridden", name)
+		}
+	}
+	return nil
+}
+
+// sqlIdentifierPattern matches an unquoted SQL identifier, at most 63
+// characters (PostgreSQL's limit, below MySQL's 64)
+var sqlIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}$`)
+
+// validateDatabaseName checks that a logical database name is a valid SQL
+// identifier; empty means the resource name is used
+func validateDatabaseName(name string) error {
+	if name != "" && !sqlIdentifierPattern.MatchString(name) {
+		return apperrors.New(apperrors.ErrInvalidInput, "invalid databaseName '%s': use letters, digits and underscores, starting with a letter or underscore (max 63 characters)", name)
+	}
+	return nil
+}
+
+// maxResourceNameLength leaves room in the 63 character DNS label limit for
+// the longest suffix of a database's objects ("-phpmyadmin")
+const maxResourceNameLength = 63 - len("-phpmyadmin")
+
+// validateResourceName checks that a database name can name its Kubernetes
+// objects: a DNS-1035 label short enough for the admin dashboard's suffix
+func validateResourceName(name string) error {
+	if name == "" {
+		return apperrors.New(apperrors.ErrInvalidInput, "name is required")
+	}
+	if problems := validation.IsDNS1035Label(name); len(problems) > 0 {
+		return apperrors.New(apperrors.ErrInvalidInput, "invalid name '%s': %s", name, strings.Join(problems, "; "))
+	}
+	if len(name) > maxResourceNameLength {
+		return apperrors.New(apperrors.ErrInvalidInput, "invalid name '%s': must be no more than %d characters", name, maxResourceNameLength)
+	}
+	return nil
+}
+
+// reservedUsernames maps each database type to the usernames its image or
+// server refuses, with the reason reported to the client. Add new database
+// types here.
+var reservedUsernames = map[string]map[string]string{
+	DatabaseTypeMySQL: {
+		"root":             "it is the superuser created from MYSQL_ROOT_PASSWORD; MYSQL_USER must be a different account",
+		"mysql.sys":        "it is a MySQL system account",
+		"mysql.session":    "it is a MySQL system account",
+		"mysql.infoschema": "it is a MySQL system account",
+	},
+	DatabaseTypePostgreSQL: {
+		"public":       "PostgreSQL reserves it for the implicit all-roles group",
+		"none":         "PostgreSQL reserves it as a role keyword",
+		"current_user": "PostgreSQL reserves it as a role keyword",
+		"current_role": "PostgreSQL reserves it as a role keyword",
+		"session_user": "PostgreSQL reserves it as a role keyword",
+	},
+}
+
+// minDatabasePasswordLength is the shortest database password accepted
+const minDatabasePasswordLength = 8
+
+// validateDatabasePassword checks that a password has at least
+// minDatabasePasswordLength characters. A blank password would otherwise only
+// fail at runtime, as a crashing pod.
+func validateDatabasePassword(password string) error {
+	if len(password) < minDatabasePasswordLength {
+		return apperrors.New(apperrors.ErrInvalidInput, "password must be at least %d characters", minDatabasePasswordLength)
+	}
+	return nil
+}
+
+// validateDatabaseUsername rejects empty usernames and those the database
+// type reserves, which would otherwise crash the container on first boot
+func validateDatabaseUsername(dbType, username string) error {
+	name := strings.ToLower(strings.TrimSpace(username))
+	if name == "" {
+		return apperrors.New(apperrors.ErrInvalidInput, "username is required")
+	}
+	if reason, reserved := reservedUsernames[dbType][name]; reserved {
+		return apperrors.New(apperrors.ErrInvalidInput, "username '%s' cannot be used for %s: %s", username, dbType, reason)
+	}
+	if dbType == DatabaseTypePostgreSQL && strings.HasPrefix(name, "pg_") {
+		return apperrors.New(apperrors.ErrInvalidInput, "username '%s' cannot be used for %s: the pg_ prefix is reserved for system roles", username, dbType)
+	}
+	return nil
+}
+
+// reservedAnnotationDomains are the annotation prefix domains (and their
+// subdomains) owned by Kubernetes, which users may not set on their pods
+var reservedAnnotationDomains = []string{"kubernetes.io", "k8s.io"}
+
+// maxPodAnnotationsSize bounds the combined size of custom pod annotations
+const maxPodAnnotationsSize = 64 * 1024
+
+// validatePodAnnotations checks that custom pod annotations have valid keys
+// outside the reserved Kubernetes domains and a reasonable total size
+func validatePodAnnotations(annotations map[string]string) error {
+	size := 0
+	for key, value := range annotations {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return apperrors.New(apperrors.ErrInvalidInput, "invalid annotation key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if prefix, _, hasPrefix := strings.Cut(key, "/"); hasPrefix {
+			for _, domain := range reservedAnnotationDomains {
+				if prefix == domain || strings.HasSuffix(prefix, "."+domain) {
+					return apperrors.New(apperrors.ErrInvalidInput, "annotation %q uses a reserved prefix", key)
+				}
+			}
+		}
+		size += len(key) + len(value)
+	}
+	if size > maxPodAnnotationsSize {
+		return apperrors.New(apperrors.ErrInvalidInput, "pod annotations exceed %d bytes", maxPodAnnotationsSize)
+	}
+	return nil
+}
+
+// validateNamespaceMetadata checks the operator-supplied NAMESPACE_LABELS and
+// NAMESPACE_ANNOTATIONS, so a bad entry stops startup instead of failing
+// every namespace creation later
+func validateNamespaceMetadata(namespaceLabels, namespaceAnnotations map[string]string) error {
+	for key, value := range namespaceLabels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid NAMESPACE_LABELS key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("invalid NAMESPACE_LABELS value %q for %q: %s", value, key, strings.Join(errs, "; "))
+		}
+	}
+	for key := range namespaceAnnotations {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid NAMESPACE_ANNOTATIONS key %q: %s", key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// validateProfileUpdate trims the supplied names in place and rejects empty
+// ones, or a request that changes nothing
+func validateProfileUpdate(req *UpdateProfileRequest) error {
+	if req.FirstName == nil && req.LastName == nil {
+		return apperrors.New(apperrors.ErrInvalidInput, "at least one of firstName or lastName is required")
+	}
+	if req.FirstName != nil {
+		*req.FirstName = strings.TrimSpace(*req.FirstName)
+		if *req.FirstName == "" {
+			return apperrors.New(apperrors.ErrInvalidInput, "firstName must not be empty")
+		}
+	}
+	if req.LastName != nil {
+		*req.LastName = strings.TrimSpace(*req.LastName)
+		if *req.LastName == "" {
+			return apperrors.New(apperrors.ErrInvalidInput, "lastName must not be empty")
+		}
+	}
+	return nil
+}
+
+// parseLabelFilters parses user-supplied "key=value" label filters (e.g. from
+// repeated ?label= query parameters), validating each key and value so they
+// can be safely turned into a label selector
+func parseLabelFilters(filters []string) (labels.Set, error) {
+	set := labels.Set{}
+	for _, filter := range filters {
+		key, value, ok := strings.Cut(filter, "=")
+		if !ok {
+			return nil, apperrors.New(apperrors.ErrInvalidInput, "invalid label filter %q (expected key=value)", filter)
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, apperrors.New(apperrors.ErrInvalidInput, "invalid label key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return nil, apperrors.New(apperrors.ErrInvalidInput, "invalid label value %q: %s", value, strings.Join(errs, "; "))
+		}
+		if existing, dup := set[key]; dup && existing != value {
+			return nil, apperrors.New(apperrors.ErrInvalidInput, "conflicting values for label %q", key)
+		}
+		set[key] = value
+	}
+	return set, nil
+}
+
+// allowedServerSettings lists, per database type, the server settings users
+// may tune through container args. MySQL names use dashes, PostgreSQL names
+// underscores, matching each server's own spelling.
+var allowedServerSettings = map[string]map[string]bool{
+	DatabaseTypePostgreSQL: {
+		"max_connections":                     true,
+		"shared_buffers":                      true,
+		"work_mem":                            true,
+		"maintenance_work_mem":                true,
+		"effective_cache_size":                true,
+		"statement_timeout":                   true,
+		"idle_in_transaction_session_timeout": true,
+		"log_min_duration_statement":          true,
+		"log_statement":                       true,
+		"timezone":                            true,
+	},
+	DatabaseTypeMySQL: {
+		"max-connections":         true,
+		"innodb-buffer-pool-size": true,
+		"max-allowed-packet":      true,
+		"wait-timeout":            true,
+		"long-query-time":         true,
+		"slow-query-log":          true,
+		"character-set-server":    true,
+		"collation-server":        true,
+		"default-time-zone":       true,
+	},
+}
+
+// serverSettingValuePattern matches setting values without whitespace or
+// shell metacharacters (e.g. "200", "128MB", "utf8mb4", "+00:00")
+var serverSettingValuePattern = regexp.MustCompile(`^[A-Za-z0-9._:+-]+$`)
+
+// validateDatabaseArgs checks that container args only set allowlisted server
+// settings. PostgreSQL accepts "-c name=value" pairs or "--name=value";
+// MySQL accepts "--name=value".
+func validateDatabaseArgs(dbType string, args []string) error {
+	allowed := allowedServerSettings[dbType]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var setting string
+		switch {
+		case dbType == DatabaseTypePostgreSQL && arg == "-c":
+			if i+1 == len(args) {
+				return apperrors.New(apperrors.ErrInvalidInput, "missing setting after '-c'")
+			}
+			i++
+			setting = args[i]
+		case strings.HasPrefix(arg, "--"):
+			setting = strings.TrimPrefix(arg, "--")
+		default:
+			return apperrors.New(apperrors.ErrInvalidInput, "unsupported argument '%s'", arg)
+		}
+
+		name, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			return apperrors.New(apperrors.ErrInvalidInput, "invalid setting '%s' (expected name=value)", setting)
+		}
+		if !allowed[strings.ToLower(name)] {
+			return apperrors.New(apperrors.ErrInvalidInput, "setting '%s' is not allowed for %s", name, dbType)
+		}
+		if !serverSettingValuePattern.MatchString(value) {
+			return apperrors.New(apperrors.ErrInvalidInput, "invalid value for setting '%s'", name)
+		}
+	}
+	return nil
+}
+
+// FieldError is one problem with a field of a request
+type FieldError struct {
+	Field string `json:"field"`
+	Msg   string `json:"msg"`
+}
+
+// ValidationErrors collects every problem found in a request, so clients can
+// flag all bad fields at once. It is an ErrInvalidInput error.
+type ValidationErrors []FieldError
+
+// add records err, if any, against a field
+func (v *ValidationErrors) add(field string, err error) {
+	if err != nil {
+		*v = append(*v, FieldError{Field: field, Msg: err.Error()})
+	}
+}
+
+// err returns the collected problems as an error, or nil when there are none
+func (v ValidationErrors) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, len(v))
+	for i, problem := range v {
+		messages[i] = problem.Field + ": " + problem.Msg
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap lets HTTPStatus map validation failures to 400
+func (v ValidationErrors) Unwrap() error {
+	return apperrors.ErrInvalidInput
+}
+
+// validationProblems returns the field problems carried by err, if any
+func validationProblems(err error) ValidationErrors {
+	var problems ValidationErrors
+	errors.As(err, &problems)
+	return problems
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/BouchamiAhmed/TBD/apperrors"
+	"github.com/BouchamiAhmed/TBD/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDefaultPort(t *testing.T) {
+	tests := []struct {
+		dbType string
+		want   string
+	}{
+		{DatabaseTypePostgreSQL, "5432"},
+		{DatabaseTypeMySQL, "3306"},
+	}
+	for _, tt := range tests {
+		got, err := defaultPort(tt.dbType)
+		if err != nil {
+			t.Errorf("defaultPort(%q) returned error: %v", tt.dbType, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("defaultPort(%q) = %q, want %q", tt.dbType, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultPortUnknownType(t *testing.T) {
+	for _, dbType := range []string{"", "redis", "postgres"} {
+		port, err := defaultPort(dbType)
+		if err == nil {
+			t.Errorf("defaultPort(%q) = %q, want an error", dbType, port)
+			continue
+		}
+		if apperrors.HTTPStatus(err) != http.StatusBadRequest {
+			t.Errorf("defaultPort(%q) error maps to %d, want 400", dbType, apperrors.HTTPStatus(err))
+		}
+	}
+}
+
+func TestAdvertisedPortHonorsDBPort(t *testing.T) {
+	saved := appConfig
+	defer func() { appConfig = saved }()
+
+	appConfig = &config.Config{DBPort: "6543"}
+	if got := advertisedPort(DatabaseTypePostgreSQL); got != "6543" {
+		t.Errorf("advertisedPort(postgresql) = %q, want the DB_PORT override 6543", got)
+	}
+	if got := advertisedPort(DatabaseTypeMySQL); got != "3306" {
+		t.Errorf("advertisedPort(mysql) = %q, want 3306", got)
+	}
+
+	appConfig = &config.Config{}
+	if got := advertisedPort(DatabaseTypePostgreSQL); got != "5432" {
+		t.Errorf("advertisedPort(postgresql) without DB_PORT = %q, want 5432", got)
+	}
+}
+
+func TestDatabaseTypeAliasRoundTrips(t *testing.T) {
+	cfg := useTestConfig(t)
+	cfg.WaitForDB = false
+	client := useFakeClientset(t, managedNamespace("7alice"))
+	useFakeDynamicClient(t)
+
+	dbRequest := DatabaseRequest{Name: "orders", Type: "postgres", Username: "app", Password: "secret-password", UserID: 7, UserName: "alice"}
+	if err := prepareDatabaseRequest(&dbRequest); err != nil {
+		t.Fatalf("prepareDatabaseRequest returned error: %v", err)
+	}
+	if dbRequest.Type != DatabaseTypePostgreSQL {
+		t.Fatalf("type normalized to %q, want %q", dbRequest.Type, DatabaseTypePostgreSQL)
+	}
+
+	ctx := context.Background()
+	if err := deployDatabase(ctx, client, dbRequest, "7alice"); err != nil {
+		t.Fatalf("deployDatabase returned error: %v", err)
+	}
+
+	deployment, err := client.AppsV1().Deployments("7alice").Get(ctx, "orders", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("getting deployment: %v", err)
+	}
+	if got := deployment.Labels["db-saas/type"]; got != DatabaseTypePostgreSQL {
+		t.Errorf("db-saas/type label = %q, want %q", got, DatabaseTypePostgreSQL)
+	}
+	if dbType, err := getDatabaseType(ctx, "orders", "7alice"); err != nil || dbType != DatabaseTypePostgreSQL {
+		t.Errorf("getDatabaseType = %q, %v, want %q", dbType, err, DatabaseTypePostgreSQL)
+	}
+
+	if err := deleteDatabaseDeployment(ctx, "orders", "7alice", nil); err != nil {
+		t.Fatalf("deleteDatabaseDeployment returned error: %v", err)
+	}
+	deployments, err := client.AppsV1().Deployments("7alice").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("listing deployments: %v", err)
+	}
+	if len(deployments.Items) != 0 {
+		t.Errorf("%d deployments left after delete, want 0", len(deployments.Items))
+	}
+}
+
+func TestMetricsRejectedWhenDisabled(t *testing.T) {
+	cfg := useTestConfig(t)
+	cfg.Features.Metrics = false
+
+	dbRequest := DatabaseRequest{Name: "orders", Type: DatabaseTypePostgreSQL, Username: "app", Password: "secret-password", EnableMetrics: true}
+	err := prepareDatabaseRequest(&dbRequest)
+	var problems ValidationErrors
+	if !errors.As(err, &problems) || len(problems) != 1 || problems[0].Field != "enableMetrics" {
+		t.Fatalf("prepareDatabaseRequest returned %v, want an enableMetrics validation error", err)
+	}
+}
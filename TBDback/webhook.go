@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// webhookMaxAttempts bounds the deliveries of one event
+	webhookMaxAttempts = 5
+	// webhookInitialBackoff doubles after each failed attempt
+	webhookInitialBackoff = time.Second
+	// webhookSignatureHeader carries "sha256=<hex HMAC of the body>" when
+	// WEBHOOK_SECRET is set
+	webhookSignatureHeader = "X-DBSaaS-Signature"
+)
+
+// webhookClient sends webhook deliveries
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// StatusChangeEvent is the JSON payload POSTed to WEBHOOK_URL when a
+// database's status changes
+type StatusChangeEvent struct {
+	Event          string    `json:"event"`
+	Name           string    `json:"name"`
+	Namespace      string    `json:"namespace"`
+	UserID         int       `json:"userId"`
+	Status         string    `json:"status"`
+	PreviousStatus string    `json:"previousStatus"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// notifyStatusChange delivers a status change to WEBHOOK_URL in the
+// background; it is a no-op when no webhook is configured
+func notifyStatusChange(name, namespace string, userID int, from, to string) {
+	if appConfig.WebhookURL == "" {
+		return
+	}
+
+	event := StatusChangeEvent{
+		Event:          "database.status_changed",
+		Name:           name,
+		Namespace:      namespace,
+		UserID:         userID,
+		Status:         to,
+		PreviousStatus: from,
+		Timestamp:      time.Now().UTC(),
+	}
+	go func() {
+		if err := deliverWebhook(event); err != nil {
+			fmt.Printf("⚠️ Webhook for '%s/%s' (%s -> %s) failed: %v\n", namespace, name, from, to, err)
+		}
+	}()
+}
+
+// deliverWebhook POSTs an event, retrying with exponential backoff on network
+// errors, 429 and 5xx responses
+func deliverWebhook(event StatusChangeEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	backoff := webhookInitialBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := postWebhook(body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == webhookMaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// postWebhook makes one delivery attempt, reporting whether a failure is worth retrying
+func postWebhook(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, appConfig.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if appConfig.WebhookSecret != "" {
+		req.Header.Set(webhookSignatureHeader, "sha256="+signWebhook(body))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook responded %s", resp.Status)
+	default:
+		return false, fmt.Errorf("webhook responded %s", resp.Status)
+	}
+}
+
+// signWebhook returns the hex HMAC-SHA256 of a payload under WEBHOOK_SECRET
+func signWebhook(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(appConfig.WebhookSecret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
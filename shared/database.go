@@ -0,0 +1,89 @@
+// Package shared holds the request and response types exchanged by the API
+// server (TBDback) and the admin service, and the helpers both rely on, so
+// the two cannot drift apart.
+package shared
+
+// DatabaseRequest represents a request to create a new database
+type DatabaseRequest struct {
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Type     string `json:"type"`               // mysql or postgresql (alias: postgres)
+	UserID   int    `json:"userId,omitempty"`   // User ID for namespace targeting
+	UserName string `json:"userName,omitempty"` // Username for namespace targeting
+	// DatabaseName is the logical database created inside the server
+	// (POSTGRES_DB/MYSQL_DATABASE); defaults to Name, the resource name
+	DatabaseName string `json:"databaseName,omitempty"`
+	// Env holds extra environment variables for the database container
+	// (e.g. POSTGRES_INITDB_ARGS); managed credential variables cannot be overridden
+	Env map[string]string `json:"env,omitempty"`
+	// Args holds extra server flags (e.g. "-c", "max_connections=200" for
+	// PostgreSQL or "--max-connections=200" for MySQL), limited to an allowlist
+	Args []string `json:"args,omitempty"`
+	// PodAnnotations are added to the database pod (e.g. prometheus.io/scrape)
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+	// EnableMetrics adds a Prometheus exporter sidecar (SQL databases only)
+	EnableMetrics bool `json:"enableMetrics,omitempty"`
+	// Profile sizes the database container (small, medium or large; default small)
+	Profile string `json:"profile,omitempty"`
+	// Resources overrides individual quantities of the profile
+	Resources *DatabaseResources `json:"resources,omitempty"`
+	// Environment is the tier the database serves (dev, staging or prod); it
+	// supplies Profile, PodDisruptionBudget and BackupSchedule when those are left unset
+	Environment string `json:"environment,omitempty"`
+	// PodDisruptionBudget overrides whether the database pod gets a PodDisruptionBudget
+	PodDisruptionBudget *bool `json:"podDisruptionBudget,omitempty"`
+	// BackupSchedule is a cron expression for scheduled backups ("none" disables them)
+	BackupSchedule string `json:"backupSchedule,omitempty"`
+	// InitSQL is an optional script run on first boot (only when the data volume is empty)
+	InitSQL string `json:"initSql,omitempty"`
+	// ReadOnlyUser additionally creates a SELECT-only role on first boot
+	ReadOnlyUser bool `json:"readOnlyUser,omitempty"`
+	// ReadOnlyPassword is generated server-side for the read-only role
+	ReadOnlyPassword string `json:"-"`
+	// Email is the requesting user's email, used as the pgAdmin login
+	Email string `json:"-"`
+}
+
+// LogicalDatabaseName returns the database created inside the server:
+// DatabaseName when set, otherwise the resource Name
+func (r DatabaseRequest) LogicalDatabaseName() string {
+	if r.DatabaseName != "" {
+		return r.DatabaseName
+	}
+	return r.Name
+}
+
+// DatabaseResources overrides individual quantities of the resource profile
+// (e.g. {"memoryLimit": "768Mi"})
+type DatabaseResources struct {
+	CPURequest    string `json:"cpuRequest,omitempty"`
+	CPULimit      string `json:"cpuLimit,omitempty"`
+	MemoryRequest string `json:"memoryRequest,omitempty"`
+	MemoryLimit   string `json:"memoryLimit,omitempty"`
+}
+
+// DatabaseResponse contains the result of a database creation operation
+type DatabaseResponse struct {
+	Name     string `json:"name"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Username string `json:"username"`
+	Type     string `json:"type"`
+	Status   string `json:"status"`
+	// DatabaseName is the logical database to connect to
+	DatabaseName string `json:"databaseName,omitempty"`
+	Environment  string `json:"environment,omitempty"`
+	Message      string `json:"message"`
+	Namespace    string `json:"namespace,omitempty"` // Include namespace in response
+	AdminURL     string `json:"adminUrl,omitempty"`  // Admin dashboard URL
+	AdminType    string `json:"adminType,omitempty"` // Type of admin dashboard (pgadmin/phpmyadmin)
+	// ReadOnly holds the read-only role's credentials when one was requested
+	ReadOnly *ReadOnlyCredentials `json:"readOnly,omitempty"`
+}
+
+// ReadOnlyCredentials are the credentials of a database's read-only role
+type ReadOnlyCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
@@ -0,0 +1,25 @@
+package shared
+
+import "strings"
+
+// Canonical database types, used in labels, branching and responses
+const (
+	DatabaseTypePostgreSQL = "postgresql"
+	DatabaseTypeMySQL      = "mysql"
+)
+
+// databaseTypeAliases maps accepted type names to their canonical value.
+// Add new database types here.
+var databaseTypeAliases = map[string]string{
+	"postgresql": DatabaseTypePostgreSQL,
+	"postgres":   DatabaseTypePostgreSQL,
+	"pg":         DatabaseTypePostgreSQL,
+	"mysql":      DatabaseTypeMySQL,
+}
+
+// NormalizeDatabaseType returns the canonical type for a requested database
+// type (e.g. "postgres" becomes "postgresql"), or false if it is not supported
+func NormalizeDatabaseType(dbType string) (string, bool) {
+	canonical, ok := databaseTypeAliases[strings.ToLower(strings.TrimSpace(dbType))]
+	return canonical, ok
+}
@@ -0,0 +1,67 @@
+package shared
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// dsnParam is a single key/value pair of a libpq connection string
+type dsnParam struct {
+	key   string
+	value string
+}
+
+// quoteDSNValue quotes a connection string value per libpq rules: the value is
+// wrapped in single quotes and any backslash or single quote is backslash-escaped
+func quoteDSNValue(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+	return "'" + escaped + "'"
+}
+
+// buildDSN builds a libpq key/value connection string with every value quoted,
+// so credentials containing spaces, quotes or equals signs survive intact
+func buildDSN(params ...dsnParam) (string, error) {
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		if p.key == "" || strings.ContainsAny(p.key, " ='\\") {
+			return "", fmt.Errorf("invalid connection parameter name %q", p.key)
+		}
+		parts = append(parts, p.key+"="+quoteDSNValue(p.value))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// PostgresDSN builds the connection string for the platform PostgreSQL database
+func PostgresDSN(host string, port int, user, password, dbname string) (string, error) {
+	if host == "" {
+		return "", fmt.Errorf("database host is required")
+	}
+	return buildDSN(
+		dsnParam{"host", host},
+		dsnParam{"port", fmt.Sprintf("%d", port)},
+		dsnParam{"user", user},
+		dsnParam{"password", password},
+		dsnParam{"dbname", dbname},
+		dsnParam{"sslmode", "disable"},
+	)
+}
+
+// ResolvePassword returns the database password, preferring the contents of
+// passwordFile (e.g. a mounted Secret) over the plain password. The file is
+// re-read on every call so a rotated Secret is picked up on reconnect; the
+// trailing newline most secret files end with is dropped.
+func ResolvePassword(password, passwordFile string) (string, error) {
+	if passwordFile == "" {
+		return password, nil
+	}
+	data, err := os.ReadFile(passwordFile)
+	if err != nil {
+		return "", fmt.Errorf("error reading database password file: %w", err)
+	}
+	filePassword := strings.TrimRight(string(data), "\r\n")
+	if filePassword == "" {
+		return "", fmt.Errorf("database password file %s is empty", passwordFile)
+	}
+	return filePassword, nil
+}
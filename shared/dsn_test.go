@@ -0,0 +1,47 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestPostgresDSNQuotesPasswords(t *testing.T) {
+	tests := []struct {
+		password string
+		want     string
+	}{
+		{"p@ss", `password='p@ss'`},
+		{"a/b/c", `password='a/b/c'`},
+		{"what?now", `password='what?now'`},
+		{"with space", `password='with space'`},
+		{"it's", `password='it\'s'`},
+		{`say "hi"`, `password='say "hi"'`},
+		{`back\slash`, `password='back\\slash'`},
+		{"a=b", `password='a=b'`},
+		{"", `password=''`},
+	}
+
+	for _, tt := range tests {
+		dsn, err := PostgresDSN("db.example", 5432, "postgres", tt.password, "testdb")
+		if err != nil {
+			t.Fatalf("PostgresDSN(%q) returned error: %v", tt.password, err)
+		}
+
+		want := `host='db.example' port='5432' user='postgres' ` + tt.want + ` dbname='testdb' sslmode='disable'`
+		if dsn != want {
+			t.Errorf("PostgresDSN(%q) =\n  %s\nwant\n  %s", tt.password, dsn, want)
+		}
+
+		// The driver must accept the string as a well-formed key/value DSN
+		if _, err := pq.NewConnector(dsn); err != nil {
+			t.Errorf("lib/pq rejected the DSN for password %q: %v", tt.password, err)
+		}
+	}
+}
+
+func TestPostgresDSNRequiresHost(t *testing.T) {
+	if _, err := PostgresDSN("", 5432, "postgres", "secret", "testdb"); err == nil {
+		t.Error("PostgresDSN with an empty host should return an error")
+	}
+}
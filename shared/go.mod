@@ -0,0 +1,5 @@
+module github.com/BouchamiAhmed/TBD/shared
+
+go 1.23.0
+
+require github.com/lib/pq v1.10.9
@@ -0,0 +1,225 @@
+package shared
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migrationLockKey is the advisory lock key that serializes migrations across
+// API replicas starting at the same time
+const migrationLockKey = 0x7462645f6d6967 // "tbd_mig"
+
+// migration is one versioned schema change, applied in its own transaction
+type migration struct {
+	version     int
+	description string
+	statements  []string
+}
+
+// migrations lists every schema change in order. Versions are never reused or
+// edited once released; append a new migration to change the schema. The
+// early versions use IF NOT EXISTS so databases created before the runner
+// existed adopt them without errors.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create users and databases tables",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS users (
+				id SERIAL PRIMARY KEY,
+				last_name VARCHAR(100) NOT NULL,
+				first_name VARCHAR(100) NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE TABLE IF NOT EXISTS databases (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(100) NOT NULL,
+				type VARCHAR(50) NOT NULL,
+				host VARCHAR(255) NOT NULL,
+				port VARCHAR(10) NOT NULL,
+				username VARCHAR(100) NOT NULL,
+				namespace VARCHAR(100) NOT NULL,
+				user_id INTEGER NOT NULL,
+				admin_url VARCHAR(500) NOT NULL DEFAULT '',
+				admin_type VARCHAR(50) NOT NULL DEFAULT '',
+				status VARCHAR(50) NOT NULL DEFAULT 'creating',
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE (namespace, name)
+			)`,
+		},
+	},
+	{
+		version:     2,
+		description: "create auth_users table",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS auth_users (
+				id SERIAL PRIMARY KEY,
+				username VARCHAR(50) NOT NULL UNIQUE,
+				email VARCHAR(100) NOT NULL UNIQUE,
+				first_name VARCHAR(100) NOT NULL,
+				last_name VARCHAR(100) NOT NULL,
+				password_hash VARCHAR(64) NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			// Tables created before profile updates existed lack updated_at
+			`ALTER TABLE auth_users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP`,
+		},
+	},
+	{
+		version:     3,
+		description: "create sessions table",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS sessions (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL,
+				token_hash VARCHAR(64) NOT NULL UNIQUE,
+				issued_at TIMESTAMPTZ NOT NULL,
+				expires_at TIMESTAMPTZ NOT NULL,
+				last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id)`,
+		},
+	},
+	{
+		version:     4,
+		description: "create idempotency_keys table",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS idempotency_keys (
+				user_id INTEGER NOT NULL,
+				key VARCHAR(255) NOT NULL,
+				request_hash VARCHAR(64) NOT NULL,
+				status_code INTEGER NOT NULL DEFAULT 0,
+				response BYTEA,
+				completed BOOLEAN NOT NULL DEFAULT FALSE,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (user_id, key)
+			)`,
+		},
+	},
+	{
+		version:     5,
+		description: "widen auth_users.password_hash for salted hash formats",
+		statements: []string{
+			`ALTER TABLE auth_users ALTER COLUMN password_hash TYPE VARCHAR(255)`,
+		},
+	},
+	{
+		version:     6,
+		description: "add databases.status_message",
+		statements: []string{
+			`ALTER TABLE databases ADD COLUMN IF NOT EXISTS status_message TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version:     7,
+		description: "align databases tables created by the admin service",
+		statements: []string{
+			// The admin service used to create databases itself, referencing its
+			// own users table, with nullable admin columns and no unique
+			// constraint; version 1 then skipped the table
+			`ALTER TABLE databases DROP CONSTRAINT IF EXISTS databases_user_id_fkey`,
+			`UPDATE databases SET admin_url = '' WHERE admin_url IS NULL`,
+			`UPDATE databases SET admin_type = '' WHERE admin_type IS NULL`,
+			`UPDATE databases SET status = 'creating' WHERE status IS NULL`,
+			`ALTER TABLE databases
+				ALTER COLUMN admin_url SET DEFAULT '',
+				ALTER COLUMN admin_url SET NOT NULL,
+				ALTER COLUMN admin_type SET DEFAULT '',
+				ALTER COLUMN admin_type SET NOT NULL,
+				ALTER COLUMN status SET NOT NULL,
+				ALTER COLUMN user_id SET NOT NULL`,
+			`DO $$
+			BEGIN
+				IF NOT EXISTS (
+					SELECT 1 FROM pg_constraint
+					WHERE conrelid = 'databases'::regclass AND conname = 'databases_namespace_name_key'
+				) THEN
+					ALTER TABLE databases ADD CONSTRAINT databases_namespace_name_key UNIQUE (namespace, name);
+				END IF;
+			END $$`,
+		},
+	},
+}
+
+// Migrate brings the control database schema up to date, applying each
+// pending migration in order and recording it in schema_migrations. The API
+// server and the admin service share the database, so both run it at startup.
+func Migrate(db *sql.DB) error {
+	fmt.Println("╔════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                Schema Migrations                           ║")
+	fmt.Println("╚════════════════════════════════════════════════════════════╝")
+
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		description VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations table: %w", err)
+	}
+
+	applied := 0
+	for _, m := range migrations {
+		ran, err := applyMigration(db, m)
+		if err != nil {
+			fmt.Printf("❌ Migration %d (%s) failed\n", m.version, m.description)
+			return err
+		}
+		if ran {
+			fmt.Printf("✅ Applied migration %d: %s\n", m.version, m.description)
+			applied++
+		}
+	}
+
+	fmt.Printf("✅ Database schema up to date (version %d, %d migration(s) applied)\n", migrations[len(migrations)-1].version, applied)
+	return nil
+}
+
+// applyMigration runs one migration unless it was already recorded. The
+// advisory lock makes a concurrently starting replica wait and then see the
+// migration as applied.
+func applyMigration(db *sql.DB, m migration) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("error starting migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationLockKey); err != nil {
+		return false, fmt.Errorf("error locking migration %d: %w", m.version, err)
+	}
+
+	var exists bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.version).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking migration %d: %w", m.version, err)
+	}
+	if exists {
+		return false, nil
+	}
+
+	for _, statement := range m.statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return false, fmt.Errorf("error applying migration %d (%s): %w", m.version, m.description, err)
+		}
+	}
+
+	if err := recordMigration(tx, m); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing migration %d: %w", m.version, err)
+	}
+	return true, nil
+}
+
+// recordMigration marks a migration as applied
+func recordMigration(tx *sql.Tx, m migration) error {
+	_, err := tx.Exec(`INSERT INTO schema_migrations (version, description) VALUES ($1, $2)`, m.version, m.description)
+	if err != nil {
+		return fmt.Errorf("error recording migration %d: %w", m.version, err)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package shared
+
+import "testing"
+
+func TestMigrationVersionsIncrease(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("migration %d has version %d, want %d", i, m.version, i+1)
+		}
+		if m.description == "" || len(m.statements) == 0 {
+			t.Errorf("migration %d has no description or statements", m.version)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package shared
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+)
+
+// MaxNamespaceLength is the Kubernetes limit for namespace names
+const MaxNamespaceLength = 63
+
+// ShortenNamespaceName returns name unchanged if it fits, otherwise a truncated
+// prefix plus a short hash of the full name, so distinct long names stay
+// distinct and the same name always maps to the same namespace
+func ShortenNamespaceName(name string) string {
+	if len(name) <= MaxNamespaceLength {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(name))
+	suffix := hex.EncodeToString(sum[:])[:8]
+
+	prefix := strings.TrimRight(name[:MaxNamespaceLength-len(suffix)-1], "-.")
+	return prefix + "-" + suffix
+}
+
+// LegacyNamespaceName returns the name earlier releases gave an over-long
+// namespace name (a plain cut at MaxNamespaceLength), or "" when name fits
+// and both schemes agree
+func LegacyNamespaceName(name string) string {
+	if len(name) <= MaxNamespaceLength {
+		return ""
+	}
+	return name[:MaxNamespaceLength]
+}
+
+// legacyNamespaceOwned caches ownedBy answers per full namespace name; legacy
+// namespaces are never created any more, so an answer cannot go stale
+var legacyNamespaceOwned sync.Map
+
+// ResolveNamespaceName returns the namespace for name, the full unshortened
+// user namespace name. If an earlier release created the namespace under its
+// truncated legacy name and ownedBy confirms it belongs to the same user, the
+// legacy name is kept so its databases are not orphaned; otherwise the
+// hash-suffixed name is used. Lookup errors are not cached.
+func ResolveNamespaceName(name string, ownedBy func(legacy string) (bool, error)) string {
+	legacy := LegacyNamespaceName(name)
+	if legacy == "" || ownedBy == nil {
+		return ShortenNamespaceName(name)
+	}
+
+	owned, cached := legacyNamespaceOwned.Load(name)
+	if !cached {
+		found, err := ownedBy(legacy)
+		if err != nil {
+			return ShortenNamespaceName(name)
+		}
+		legacyNamespaceOwned.Store(name, found)
+		owned = found
+	}
+
+	if owned.(bool) {
+		return legacy
+	}
+	return ShortenNamespaceName(name)
+}
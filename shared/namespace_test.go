@@ -0,0 +1,54 @@
+package shared
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestShortenNamespaceName(t *testing.T) {
+	if got := ShortenNamespaceName("12alice"); got != "12alice" {
+		t.Errorf("ShortenNamespaceName(short) = %q, want it unchanged", got)
+	}
+
+	long := "7" + strings.Repeat("a", 70)
+	other := "7" + strings.Repeat("a", 69) + "b"
+	got := ShortenNamespaceName(long)
+	if len(got) > MaxNamespaceLength {
+		t.Errorf("ShortenNamespaceName(long) has %d characters, want at most %d", len(got), MaxNamespaceLength)
+	}
+	if got == ShortenNamespaceName(other) {
+		t.Error("two long names sharing a prefix were shortened to the same namespace")
+	}
+}
+
+func TestResolveNamespaceNameKeepsOwnedLegacyName(t *testing.T) {
+	long := "3" + strings.Repeat("b", 70)
+	legacy := long[:MaxNamespaceLength]
+
+	got := ResolveNamespaceName(long, func(name string) (bool, error) {
+		if name != legacy {
+			t.Errorf("looked up %q, want the legacy name %q", name, legacy)
+		}
+		return true, nil
+	})
+	if got != legacy {
+		t.Errorf("ResolveNamespaceName = %q, want the existing legacy namespace %q", got, legacy)
+	}
+}
+
+func TestResolveNamespaceNameFallsBackToShortened(t *testing.T) {
+	missing := "4" + strings.Repeat("c", 70)
+	if got := ResolveNamespaceName(missing, func(string) (bool, error) { return false, nil }); got != ShortenNamespaceName(missing) {
+		t.Errorf("ResolveNamespaceName without a legacy namespace = %q, want %q", got, ShortenNamespaceName(missing))
+	}
+
+	// A failed lookup is not cached, so a later success still finds the legacy namespace
+	flaky := "5" + strings.Repeat("d", 70)
+	if got := ResolveNamespaceName(flaky, func(string) (bool, error) { return false, errors.New("unavailable") }); got != ShortenNamespaceName(flaky) {
+		t.Errorf("ResolveNamespaceName on lookup error = %q, want %q", got, ShortenNamespaceName(flaky))
+	}
+	if got := ResolveNamespaceName(flaky, func(string) (bool, error) { return true, nil }); got != flaky[:MaxNamespaceLength] {
+		t.Errorf("ResolveNamespaceName after a failed lookup = %q, want the legacy name", got)
+	}
+}
@@ -0,0 +1,88 @@
+package shared
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pgAdmin routing strategies (PGADMIN_ROUTING).
+//
+//   - path-prefix (default): pgAdmin is served under /{namespace}/{db}-pgadmin on the
+//     shared host. SCRIPT_NAME tells pgAdmin its subpath, so Traefik must forward the
+//     full path: only the headers middleware is attached, never stripPrefix.
+//   - host: pgAdmin is served at the root of its own host,
+//     {db}-pgadmin.{namespace}.{PGADMIN_HOST_DOMAIN}. SCRIPT_NAME is not set and no
+//     path rewriting is needed; only the headers middleware is attached.
+const (
+	PgAdminRoutingPathPrefix = "path-prefix"
+	PgAdminRoutingHost       = "host"
+)
+
+// PgAdminRouting holds the pgAdmin routing settings of a service
+type PgAdminRouting struct {
+	Mode       string // PGADMIN_ROUTING
+	HostDomain string // PGADMIN_HOST_DOMAIN
+}
+
+// Strategy returns the effective routing strategy, falling back to
+// path-prefix when the mode is unknown or host routing has no domain
+func (r PgAdminRouting) Strategy() string {
+	if r.Mode == PgAdminRoutingHost && r.HostDomain != "" {
+		return PgAdminRoutingHost
+	}
+	return PgAdminRoutingPathPrefix
+}
+
+// Validate reports a setting Strategy falls back from, so services can warn
+// about it once at startup
+func (r PgAdminRouting) Validate() error {
+	switch r.Mode {
+	case "", PgAdminRoutingPathPrefix:
+		return nil
+	case PgAdminRoutingHost:
+		if r.HostDomain == "" {
+			return fmt.Errorf("PGADMIN_ROUTING=host requires PGADMIN_HOST_DOMAIN, using %s", PgAdminRoutingPathPrefix)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown PGADMIN_ROUTING %q, using %s", r.Mode, PgAdminRoutingPathPrefix)
+	}
+}
+
+// PgAdminPathPrefix returns the subpath pgAdmin is served under in path-prefix mode
+func PgAdminPathPrefix(namespace, dbName string) string {
+	return fmt.Sprintf("/%s/%s-pgadmin", namespace, dbName)
+}
+
+// Host returns the dedicated hostname pgAdmin is served on in host mode
+func (r PgAdminRouting) Host(namespace, dbName string) string {
+	return fmt.Sprintf("%s-pgadmin.%s.%s", dbName, namespace, r.HostDomain)
+}
+
+// MatchRule returns the Traefik match rule for a pgAdmin IngressRoute;
+// publicHost is the shared host path-prefix routes are served on
+func (r PgAdminRouting) MatchRule(matcherVersion, publicHost, namespace, dbName string) string {
+	if r.Strategy() == PgAdminRoutingHost {
+		return TraefikHostRule(matcherVersion, r.Host(namespace, dbName))
+	}
+	return TraefikHostPathRule(matcherVersion, publicHost, PgAdminPathPrefix(namespace, dbName))
+}
+
+// URL returns the URL users open to reach pgAdmin. In path-prefix mode it
+// points at the login page, which pgAdmin does not redirect to by itself
+// under a subpath.
+func (r PgAdminRouting) URL(scheme, publicHost, namespace, dbName string) string {
+	if r.Strategy() == PgAdminRoutingHost {
+		return fmt.Sprintf("%s://%s/", scheme, r.Host(namespace, dbName))
+	}
+	return fmt.Sprintf("%s://%s%s/login?next=", scheme, publicHost, PgAdminPathPrefix(namespace, dbName))
+}
+
+// PgAdminEmail returns the pgAdmin login email: the user's own email when
+// known, otherwise the database username at emailDomain (PGADMIN_EMAIL_DOMAIN)
+func PgAdminEmail(email, username, emailDomain string) string {
+	if strings.Contains(email, "@") {
+		return email
+	}
+	return fmt.Sprintf("%s@%s", username, emailDomain)
+}
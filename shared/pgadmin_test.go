@@ -0,0 +1,53 @@
+package shared
+
+import "testing"
+
+func TestPgAdminRoutingStrategy(t *testing.T) {
+	tests := []struct {
+		routing PgAdminRouting
+		want    string
+		invalid bool
+	}{
+		{PgAdminRouting{}, PgAdminRoutingPathPrefix, false},
+		{PgAdminRouting{Mode: PgAdminRoutingPathPrefix}, PgAdminRoutingPathPrefix, false},
+		{PgAdminRouting{Mode: PgAdminRoutingHost, HostDomain: "db.example.com"}, PgAdminRoutingHost, false},
+		{PgAdminRouting{Mode: PgAdminRoutingHost}, PgAdminRoutingPathPrefix, true},
+		{PgAdminRouting{Mode: "subdomain"}, PgAdminRoutingPathPrefix, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.routing.Strategy(); got != tt.want {
+			t.Errorf("%+v: Strategy() = %q, want %q", tt.routing, got, tt.want)
+		}
+		if err := tt.routing.Validate(); (err != nil) != tt.invalid {
+			t.Errorf("%+v: Validate() = %v, want invalid %v", tt.routing, err, tt.invalid)
+		}
+	}
+}
+
+func TestPgAdminRoutingURLsAndRules(t *testing.T) {
+	pathPrefix := PgAdminRouting{Mode: PgAdminRoutingPathPrefix}
+	if got, want := pathPrefix.URL("https", "db.example.com", "7alice", "orders"), "https://db.example.com/7alice/orders-pgadmin/login?next="; got != want {
+		t.Errorf("path-prefix URL = %s, want %s", got, want)
+	}
+	if got, want := pathPrefix.MatchRule(TraefikMatcherV2, "db.example.com", "7alice", "orders"), `Host("db.example.com") && PathPrefix("/7alice/orders-pgadmin")`; got != want {
+		t.Errorf("path-prefix rule = %s, want %s", got, want)
+	}
+
+	host := PgAdminRouting{Mode: PgAdminRoutingHost, HostDomain: "pg.example.com"}
+	if got, want := host.URL("http", "db.example.com", "7alice", "orders"), "http://orders-pgadmin.7alice.pg.example.com/"; got != want {
+		t.Errorf("host URL = %s, want %s", got, want)
+	}
+	if got, want := host.MatchRule(TraefikMatcherV3, "db.example.com", "7alice", "orders"), "Host(`orders-pgadmin.7alice.pg.example.com`)"; got != want {
+		t.Errorf("host rule = %s, want %s", got, want)
+	}
+}
+
+func TestPgAdminEmail(t *testing.T) {
+	if got := PgAdminEmail("alice@example.org", "app", "example.com"); got != "alice@example.org" {
+		t.Errorf("PgAdminEmail with an email = %s, want the user's email", got)
+	}
+	if got := PgAdminEmail("", "app", "example.com"); got != "app@example.com" {
+		t.Errorf("PgAdminEmail without an email = %s, want app@example.com", got)
+	}
+}
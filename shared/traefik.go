@@ -0,0 +1,43 @@
+package shared
+
+import "fmt"
+
+// Traefik matcher syntaxes (TRAEFIK_MATCHER_VERSION). v2 rules quote values
+// with double quotes; v3 rules use backticks, the only form documented for v3.
+const (
+	TraefikMatcherV2 = "v2"
+	TraefikMatcherV3 = "v3"
+)
+
+// TraefikNamespaceLabel records the database namespace on Traefik objects, so
+// those kept in a central TRAEFIK_NAMESPACE can be found per user namespace
+const TraefikNamespaceLabel = "db-saas/namespace"
+
+// ValidTraefikMatcherVersion reports whether version names a known matcher
+// syntax; empty is accepted and means v2. Services check this once at startup.
+func ValidTraefikMatcherVersion(version string) bool {
+	switch version {
+	case "", TraefikMatcherV2, TraefikMatcherV3:
+		return true
+	}
+	return false
+}
+
+// TraefikQuote quotes a matcher argument for a matcher version; anything but
+// v3 uses the v2 syntax
+func TraefikQuote(version, value string) string {
+	if version == TraefikMatcherV3 {
+		return "`" + value + "`"
+	}
+	return `"` + value + `"`
+}
+
+// TraefikHostRule returns a rule matching a host
+func TraefikHostRule(version, host string) string {
+	return fmt.Sprintf("Host(%s)", TraefikQuote(version, host))
+}
+
+// TraefikHostPathRule returns a rule matching a host and path prefix
+func TraefikHostPathRule(version, host, pathPrefix string) string {
+	return fmt.Sprintf("Host(%s) && PathPrefix(%s)", TraefikQuote(version, host), TraefikQuote(version, pathPrefix))
+}
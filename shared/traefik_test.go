@@ -0,0 +1,35 @@
+package shared
+
+import "testing"
+
+func TestTraefikRulesPerMatcherVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		host     string
+		hostPath string
+	}{
+		{TraefikMatcherV2, `Host("db.example.com")`, `Host("db.example.com") && PathPrefix("/ns/shop")`},
+		{TraefikMatcherV3, "Host(`db.example.com`)", "Host(`db.example.com`) && PathPrefix(`/ns/shop`)"},
+		{"", `Host("db.example.com")`, `Host("db.example.com") && PathPrefix("/ns/shop")`},
+	}
+
+	for _, tt := range tests {
+		if got := TraefikHostRule(tt.version, "db.example.com"); got != tt.host {
+			t.Errorf("version %q: TraefikHostRule = %s, want %s", tt.version, got, tt.host)
+		}
+		if got := TraefikHostPathRule(tt.version, "db.example.com", "/ns/shop"); got != tt.hostPath {
+			t.Errorf("version %q: TraefikHostPathRule = %s, want %s", tt.version, got, tt.hostPath)
+		}
+	}
+}
+
+func TestValidTraefikMatcherVersion(t *testing.T) {
+	for _, version := range []string{"", TraefikMatcherV2, TraefikMatcherV3} {
+		if !ValidTraefikMatcherVersion(version) {
+			t.Errorf("ValidTraefikMatcherVersion(%q) = false, want true", version)
+		}
+	}
+	if ValidTraefikMatcherVersion("v4") {
+		t.Error("ValidTraefikMatcherVersion(\"v4\") = true, want false")
+	}
+}
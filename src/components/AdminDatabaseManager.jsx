@@ -120,7 +120,11 @@ const AdminDatabaseManager = () => {
     const getStatusBadgeClass = (status) => {
         switch (status) {
             case 'running': return 'badge bg-success';
-            case 'creating': return 'badge bg-warning';
+            case 'creating':
+            case 'provisioning':
+            case 'pulling':
+            case 'starting': return 'badge bg-warning';
+            case 'failed':
             case 'error': return 'badge bg-danger';
             case 'Active': return 'badge bg-success';
             case 'Terminating': return 'badge bg-warning';
@@ -11,7 +11,11 @@ const DatabaseList = ({
     const getStatusBadgeClass = (status) => {
         switch (status) {
             case 'running': return 'badge bg-success';
-            case 'creating': return 'badge bg-warning';
+            case 'creating':
+            case 'provisioning':
+            case 'pulling':
+            case 'starting': return 'badge bg-warning';
+            case 'failed':
             case 'error': return 'badge bg-danger';
             default: return 'badge bg-secondary';
         }
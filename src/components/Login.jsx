@@ -33,11 +33,11 @@ const Login = () => {
       });
 
       if (!response.ok) {
-        const errorData = await response.text();
-        throw new Error(errorData || 'Login failed');
+        const errorData = await response.json().catch(() => ({}));
+        throw new Error(errorData.error || 'Login failed');
       }
 
-      const data = await response.json();
+      const { data } = await response.json();
       
       // Save user data and token to localStorage
       localStorage.setItem('user', JSON.stringify(data.user));
@@ -52,11 +52,11 @@ const Register = () => {
       });
 
       if (!response.ok) {
-        const errorData = await response.text();
-        throw new Error(errorData || 'Registration failed');
+        const errorData = await response.json().catch(() => ({}));
+        throw new Error(errorData.error || 'Registration failed');
       }
 
-      const data = await response.json();
+      const { data } = await response.json();
       
       // Save user data and token to localStorage
       localStorage.setItem('user', JSON.stringify(data.user));
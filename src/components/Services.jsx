@@ -10,6 +10,7 @@ const Services = (props) => {
     });
 
     const [currentUser, setCurrentUser] = useState(null);
+    const [userNamespace, setUserNamespace] = useState('');
     const [showDatabaseForm, setShowDatabaseForm] = useState(false);
     const [selectedDbType, setSelectedDbType] = useState('');
     const [databaseForm, setDatabaseForm] = useState({
@@ -38,6 +39,30 @@ const Services = (props) => {
         }
     }, []);
 
+    // Resolve the user's namespace from the server instead of deriving it here
+    const fetchUserNamespace = async () => {
+        if (userNamespace) return userNamespace;
+
+        const response = await fetch(`http://localhost:8080/api/users/${currentUser.id}/namespace`, {
+            headers: {
+                'Authorization': `Bearer ${localStorage.getItem('token')}` || ''
+            }
+        });
+        if (!response.ok) {
+            throw new Error('Failed to resolve namespace');
+        }
+
+        const { data } = await response.json();
+        setUserNamespace(data.namespace);
+        return data.namespace;
+    };
+
+    useEffect(() => {
+        if (currentUser) {
+            fetchUserNamespace().catch(error => console.error('Error resolving namespace:', error));
+        }
+    }, [currentUser]);
+
     // NEW: Load databases when user changes
     useEffect(() => {
         if (currentUser && showDatabasesList) {
@@ -51,7 +76,7 @@ const Services = (props) => {
 
         setLoadingDatabases(true);
         try {
-            const namespace = `${currentUser.id}${currentUser.username}`;
+            const namespace = await fetchUserNamespace();
             const response = await fetch(`http://localhost:8080/api/databases/${namespace}`, {
                 headers: {
                     'Authorization': `Bearer ${localStorage.getItem('token')}` || ''
@@ -59,7 +84,7 @@ const Services = (props) => {
             });
 
             if (response.ok) {
-                const data = await response.json();
+                const { data } = await response.json();
                 setDatabases(data.databases || []);
             } else {
                 console.error('Failed to load databases');
@@ -103,7 +128,7 @@ const Services = (props) => {
 
         try {
             const response = await fetch(
-                `http://localhost:8080/api/databases/${database.namespace}/${database.name}`,
+                `http://localhost:8080/api/databases/${database.namespace}/${database.name}?confirm=${encodeURIComponent(database.name)}`,
                 {
                     method: 'DELETE',
                     headers: {
@@ -125,8 +150,8 @@ const Services = (props) => {
 
                 console.log(`Database ${database.name} deleted successfully`);
             } else {
-                const errorData = await response.text();
-                throw new Error(errorData || 'Failed to delete database');
+                const errorData = await response.json().catch(() => ({}));
+                throw new Error(errorData.error || 'Failed to delete database');
             }
         } catch (error) {
             console.error('Error deleting database:', error);
@@ -193,7 +218,7 @@ const Services = (props) => {
                     isLoading: false,
                     success: true,
                     message: `${dbType.toUpperCase()} database created successfully!`,
-                    deployment: result
+                    deployment: result.data
                 });
 
                 // Reset form
@@ -212,7 +237,7 @@ const Services = (props) => {
                 }
 
             } else {
-                throw new Error(result.message || 'Database creation failed');
+                throw new Error(result.error || 'Database creation failed');
             }
         } catch (error) {
             console.error('Error creating database:', error);
@@ -283,8 +308,8 @@ const Services = (props) => {
                 success: response.ok,
                 message: response.ok ? 
                     `${dbType.toUpperCase()} database deployed successfully!` : 
-                    `Error: ${result.message}`,
-                deployment: response.ok ? result : null
+                    `Error: ${result.error}`,
+                deployment: response.ok ? result.data : null
             });
 
             // Refresh databases list if it's open
@@ -305,7 +330,11 @@ const Services = (props) => {
     const getStatusBadgeClass = (status) => {
         switch (status) {
             case 'running': return 'badge bg-success';
-            case 'creating': return 'badge bg-warning';
+            case 'creating':
+            case 'provisioning':
+            case 'pulling':
+            case 'starting': return 'badge bg-warning';
+            case 'failed':
             case 'error': return 'badge bg-danger';
             default: return 'badge bg-secondary';
         }
@@ -603,7 +632,7 @@ const Services = (props) => {
                                             <li><strong>Host:</strong> {`{db-name}.{namespace}.svc.cluster.local`}</li>
                                             <li><strong>MySQL Port:</strong> 3306</li>
                                             <li><strong>PostgreSQL Port:</strong> 5432</li>
-                                            <li><strong>Your Namespace:</strong> {currentUser ? `${currentUser.id}${currentUser.username}` : 'Please log in'}</li>
+                                            <li><strong>Your Namespace:</strong> {currentUser ? (userNamespace || 'Loading...') : 'Please log in'}</li>
                                             <li><strong>Admin Access:</strong> http://10.9.21.201/{`{namespace}/{service}`}</li>
                                         </ul>
                                     </div>
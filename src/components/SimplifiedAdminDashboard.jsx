@@ -130,6 +130,9 @@ const SimplifiedAdminDashboard = () => {
       case 'active':
         return 'badge-success';
       case 'creating':
+      case 'provisioning':
+      case 'pulling':
+      case 'starting':
       case 'pending':
         return 'badge-warning';
       case 'failed':